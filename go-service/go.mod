@@ -1,6 +1,6 @@
 module github.com/forest-guardian/forest-guardian-api-poc
 
-go 1.24.0
+go 1.24.9
 
 require (
 	github.com/airbusgeo/godal v0.0.13
@@ -12,6 +12,7 @@ require (
 	github.com/icza/mjpeg v0.0.0-20230330134156-38318e5ab8f4
 	github.com/joho/godotenv v1.5.1
 	github.com/paulmach/orb v0.11.1
+	github.com/parquet-go/parquet-go v0.32.0
 	github.com/schollz/progressbar/v3 v3.18.0
 	golang.org/x/oauth2 v0.28.0
 	google.golang.org/grpc v1.72.0
@@ -23,17 +24,24 @@ replace github.com/forest-guardian/forest-guardian-api-poc/internal/ml/protobufs
 replace github.com/forest-guardian/forest-guardian-api-poc/internal/delta/protobufs => ./internal/dataset/protobufs
 
 require (
+	github.com/andybalholm/brotli v1.1.1 // indirect
 	github.com/gammazero/deque v0.2.0 // indirect
 	github.com/golang/freetype v0.0.0-20170609003504-e2365dfdc4a0 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/klauspost/compress v1.17.9 // indirect
 	github.com/mattn/go-colorable v0.1.13 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
 	github.com/mitchellh/colorstring v0.0.0-20190213212951-d06e56a500db // indirect
+	github.com/parquet-go/bitpack v1.0.0 // indirect
+	github.com/parquet-go/jsonlite v1.0.0 // indirect
+	github.com/pierrec/lz4/v4 v4.1.21 // indirect
 	github.com/rivo/uniseg v0.4.7 // indirect
 	github.com/stretchr/testify v1.10.0 // indirect
+	github.com/twpayne/go-geom v1.6.1 // indirect
 	go.mongodb.org/mongo-driver v1.11.4 // indirect
 	golang.org/x/image v0.25.0 // indirect
 	golang.org/x/net v0.35.0 // indirect
-	golang.org/x/sys v0.30.0 // indirect
+	golang.org/x/sys v0.38.0 // indirect
 	golang.org/x/term v0.29.0 // indirect
 	golang.org/x/text v0.23.0 // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20250218202821-56aae31c358a // indirect
@@ -0,0 +1,164 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/forest-guardian/forest-guardian-api-poc/internal/properties"
+)
+
+// Config holds the research settings read from the JSON file at CONFIG_PATH
+// (default: {ROOT_PATH}/config.json). A missing file yields the zero Config, which
+// reproduces the original pipeline.
+type Config struct {
+	Acquisition AcquisitionConfig `json:"acquisition"`
+	Indexes     IndexesConfig     `json:"indexes"`
+	Sentinel1   Sentinel1Config   `json:"sentinel1"`
+	Coverage    CoverageConfig    `json:"coverage"`
+	Quality     QualityConfig     `json:"quality"`
+	Smoothing   SmoothingConfig   `json:"smoothing"`
+	GapFilling  GapFillingConfig  `json:"gap_filling"`
+	Features    FeaturesConfig    `json:"features"`
+	Compositing CompositingConfig `json:"compositing"`
+}
+
+// AcquisitionConfig selects the optical missions images are acquired from.
+type AcquisitionConfig struct {
+	// Sensors lists "sentinel2" and/or "landsat". Defaults to Sentinel-2 only.
+	Sensors []string `json:"sensors"`
+	// Mode is "plot" (default) to request every plot on its own, or "forest" to request
+	// the merged extent of the forest once and clip the plots from it.
+	Mode string `json:"mode"`
+}
+
+// IndexesConfig selects the spectral indices computed for every pixel.
+type IndexesConfig struct {
+	// Enabled lists registry indices computed in addition to ndre, ndmi, psri and ndvi.
+	Enabled []string `json:"enabled"`
+	// Custom defines additional indices from band expressions; they are always computed.
+	Custom []CustomIndex `json:"custom"`
+}
+
+// CustomIndex is a user-defined index such as {"name": "ndwi", "expression": "(B08-B11)/(B08+B11)"}.
+type CustomIndex struct {
+	Name       string `json:"name"`
+	Expression string `json:"expression"`
+}
+
+// Sentinel1Config merges Sentinel-1 GRD backscatter into the optical images, so the pixel
+// time series keeps radar features on cloudy dates.
+type Sentinel1Config struct {
+	Enabled bool `json:"enabled"`
+	// MaxDaysApart is the largest gap in days between an optical image and the radar
+	// acquisition merged into it. Defaults to 6, the Sentinel-1 revisit time.
+	MaxDaysApart int `json:"max_days_apart"`
+}
+
+// CoverageConfig controls how pixels on the border of a plot polygon are treated.
+type CoverageConfig struct {
+	// BufferMeters shrinks the plot polygon inward before computing pixel coverage, so pixels
+	// close to the border count as edge pixels. Defaults to 0.
+	BufferMeters float64 `json:"buffer_meters"`
+	// MinFraction is the smallest share of a pixel inside the polygon for the pixel to be kept
+	// in the dataset. Pixels outside the polygon are always dropped. Defaults to 0, keeping
+	// every edge pixel.
+	MinFraction float64 `json:"min_fraction"`
+}
+
+// QualityConfig sets the pixel quality mask. Unset fields keep the original rules.
+type QualityConfig struct {
+	// CloudProbability is the CLD value (0-100) above which a pixel is cloudy. Defaults to 0.
+	CloudProbability float64 `json:"cloud_probability"`
+	// MaskedSCLClasses lists the scene classes treated as unknown. Defaults to 2, 3, 8, 9 and
+	// 10; an empty list masks none.
+	MaskedSCLClasses []int `json:"masked_scl_classes"`
+	// Brightness is the mean of B02 and B04 above which a pixel is invalid. Defaults to 0.9.
+	Brightness float64 `json:"brightness"`
+	// DilationRadius also masks the pixels within this many pixels of a cloud or shadow.
+	// Defaults to 0.
+	DilationRadius int `json:"dilation_radius"`
+}
+
+// SmoothingConfig selects how the pixel time series are cleared of outliers and smoothed.
+type SmoothingConfig struct {
+	// Backend is "go" to smooth in process, "python" to call the Python service, or "auto"
+	// (default) to call the Python service when it is reachable and smooth in process otherwise.
+	Backend string `json:"backend"`
+	// Lambda is the penalty of the smoothing spline. Defaults to 0.1.
+	Lambda float64 `json:"lambda"`
+	// Window is the number of dates on each side an outlier is compared with. Defaults to 10.
+	Window int `json:"window"`
+	// Threshold is the share of the window standard deviation a value may deviate from the
+	// window mean before it is replaced as an outlier. Defaults to 0.02.
+	Threshold float64 `json:"threshold"`
+}
+
+// GapFillingConfig selects how the pixels masked as unknown are estimated before smoothing.
+type GapFillingConfig struct {
+	// Strategy is "neighbors" (default) for the original neighbor heuristic, "linear" for
+	// interpolation in time, "harmonic" for a per-pixel harmonic regression, or "idw" for
+	// inverse-distance weighting of the valid pixels around on the same date.
+	Strategy string `json:"strategy"`
+}
+
+// FeaturesConfig adds multi-scale temporal features of every index to the delta dataset. The
+// zero value adds none.
+type FeaturesConfig struct {
+	// DerivativeWindows lists the spans in days of the derivative and acceleration features,
+	// such as [5, 15, 30].
+	DerivativeWindows []int `json:"derivative_windows"`
+	// RollingWindow is the span in days of the rolling mean and variance features; 0 adds none.
+	RollingWindow int `json:"rolling_window"`
+	// SeasonalDrop adds the drop of every index from its maximum over the previous year.
+	SeasonalDrop bool `json:"seasonal_drop"`
+}
+
+// CompositingConfig resamples the clean pixel series onto a regular calendar grid before the
+// delta dataset is built. The zero value keeps the acquisition dates.
+type CompositingConfig struct {
+	// PeriodDays is the length in days of the composite bins, such as 5 or 10; 0 disables
+	// compositing.
+	PeriodDays int `json:"period_days"`
+	// Method is "median" (default) for the median of the valid values of every index in a bin,
+	// or "max_ndvi" for the values of the date of highest NDVI in the bin.
+	Method string `json:"method"`
+}
+
+var (
+	loaded     Config
+	loadErr    error
+	loadedOnce sync.Once
+)
+
+// Get returns the configuration, reading it from disk on first use.
+func Get() (Config, error) {
+	loadedOnce.Do(func() {
+		loaded, loadErr = load(Path())
+	})
+	return loaded, loadErr
+}
+
+// Path returns the location of the configuration file.
+func Path() string {
+	if path := os.Getenv("CONFIG_PATH"); path != "" {
+		return path
+	}
+	return fmt.Sprintf("%s/config.json", properties.RootPath())
+}
+
+func load(path string) (Config, error) {
+	var cfg Config
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("invalid JSON in %s: %v", path, err)
+	}
+	return cfg, nil
+}
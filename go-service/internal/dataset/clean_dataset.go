@@ -1,144 +1,115 @@
 package dataset
 
 import (
-	"context"
 	"fmt"
-	"sync"
-	"time"
 
-	pb "github.com/forest-guardian/forest-guardian-api-poc/internal/dataset/protobufs"
-	"github.com/forest-guardian/forest-guardian-api-poc/internal/properties"
 	"github.com/forest-guardian/forest-guardian-api-poc/internal/sentinel"
-	"github.com/forest-guardian/forest-guardian-api-poc/internal/utils"
-	"github.com/gammazero/workerpool"
 	"github.com/schollz/progressbar/v3"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 )
 
-func convertToProtobufList(data map[string][]float64) map[string]*pb.DoubleList {
-	result := make(map[string]*pb.DoubleList)
-	for key, values := range data {
-		result[key] = &pb.DoubleList{
-			Values: values,
+// cleanDataset smooths the series of every pixel of the cube on its valid dates with smoother.
+// Pixels the smoother fails on are left out of the dataset and reported, rather than failing
+// the plot.
+func cleanDataset(cube *Cube, smoother Smoother) error {
+	series, dates := plotSeries(cube)
+	var (
+		validCount  int
+		failures    []string
+		progressBar = progressbar.Default(int64(len(series)), "Cleaning dataset")
+	)
+
+	err := smoother.ClearAndSmooth(series, func(result SmoothedSeries) {
+		defer progressBar.Add(1)
+		if result.X < 0 || result.Y < 0 || result.X >= cube.Width || result.Y >= cube.Height {
+			failures = append(failures, fmt.Sprintf("pixel (%d, %d): outside the plot", result.X, result.Y))
+			return
+		}
+		pixel := result.Y*cube.Width + result.X
+		if result.Err == nil {
+			result.Err = checkSmoothed(cube, result, dates[pixel])
+		}
+		if result.Err != nil {
+			for _, date := range dates[pixel] {
+				cube.setState(date, pixel, stateInvalid)
+			}
+			failures = append(failures, fmt.Sprintf("pixel (%d, %d): %v", result.X, result.Y, result.Err))
+			return
 		}
-	}
-	return result
-}
 
-func convertFromProtobufList(data map[string]*pb.DoubleList) map[string][]float64 {
-	result := make(map[string][]float64)
-	for key, doubleList := range data {
-		result[key] = doubleList.Values
+		smoothed := result.Values
+		for j, date := range dates[pixel] {
+			if zeroCoreIndex(smoothed, j) {
+				cube.setState(date, pixel, stateInvalid)
+				continue
+			}
+			for i, name := range cube.Indexes {
+				cube.setValue(date, i, pixel, smoothed[name][j])
+			}
+			validCount++
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("error during dataset cleaning: %v", err)
 	}
-	return result
-}
-
-func clearAndSmooth(conn *grpc.ClientConn, values map[string][]float64) (map[string][]float64, error) {
 
-		client := pb.NewClearAndSmoothServiceClient(conn)
-
-	// Create the request
-				req := &pb.ClearAndSmoothRequest{
-		Data: convertToProtobufList(values),
+	if len(failures) > 0 {
+		fmt.Printf("%d of %d pixels could not be smoothed and were left out, first %s\n", len(failures), len(series), failures[0])
 	}
-
-	resp, err := client.ClearAndSmooth(context.Background(), req)
-	if err != nil {
-		return nil, err
+	if validCount == 0 {
+		return fmt.Errorf("no valid data found after cleaning")
 	}
-
-	// Return the smoothed data
-	return convertFromProtobufList(resp.SmoothedData), nil
+	return nil
 }
-func cleanDataset(pixelDataset map[[2]int]map[time.Time]PixelData) (map[[2]int]map[time.Time]PixelData, error) {
-	var (
-		mu          sync.Mutex
-		cleanData   = make(map[[2]int]map[time.Time]PixelData)
-		progressBar = progressbar.Default(int64(len(pixelDataset)), "Cleaning dataset")
-	)
 
-	wp := workerpool.New(100)
-	conn, err := grpc.Dial(fmt.Sprintf("localhost:%d", properties.GrpcPort), grpc.WithTransportCredentials(insecure.NewCredentials()))
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect to gRPC server: %v", err)
+// zeroCoreIndex reports whether a core index was smoothed to 0 on the j-th date, which is taken
+// for a failed fit. Core indices the cube dropped, as the red-edge ones of Landsat-only plots,
+// are not checked.
+func zeroCoreIndex(smoothed map[string][]float64, j int) bool {
+	for _, name := range sentinel.CoreIndexes {
+		if values, ok := smoothed[name]; ok && values[j] == 0 {
+			return true
+		}
 	}
-	defer conn.Close()
-
-	errChan := make(chan error, 1)
-	var stopProcessing sync.Once
-
-	for _, data := range pixelDataset {
-		d := data // capture range variable
-		wp.Submit(func() {
-			var ndre, ndmi, psri, ndvi []float64
-			ascDates := utils.GetSortedKeys(d, true)
-			for _, date := range ascDates {
-				pixel := d[date]
-				if pixel.Status == sentinel.PixelStatusInvalid {
-					panic("invalid pixel found during cleaning")
-				}
-				if pixel.Status == sentinel.PixelStatusTreatable {
-					panic("treatable pixel found during cleaning")
-				}
-				ndre = append(ndre, pixel.NDRE)
-				ndmi = append(ndmi, pixel.NDMI)
-				psri = append(psri, pixel.PSRI)
-				ndvi = append(ndvi, pixel.NDVI)
-			}
-			if len(ndre) != len(d) {
-				return
-			}
-			values := map[string][]float64{
-				"ndre": ndre, "ndmi": ndmi, "psri": psri, "ndvi": ndvi,
-			}
-
-			smoothed, err := clearAndSmooth(conn, values)
-			if err != nil {
-				stopProcessing.Do(func() { errChan <- err })
-				return
-			}
-
-			var validData = make(map[time.Time]PixelData)
-			for i, date := range ascDates {
-				pixel := d[date]
-				if smoothed["ndmi"][i] == 0 || smoothed["psri"][i] == 0 || smoothed["ndre"][i] == 0 || smoothed["ndvi"][i] == 0 {
-					continue
-				}
-				pixel.NDMI = smoothed["ndmi"][i]
-				pixel.PSRI = smoothed["psri"][i]
-				pixel.NDRE = smoothed["ndre"][i]
-				pixel.NDVI = smoothed["ndvi"][i]
-
-				validData[date] = pixel
-			}
+	return false
+}
 
-			mu.Lock()
-			for date := range validData {
-				key := [2]int{d[date].X, d[date].Y}
-				if _, ok := cleanData[key]; !ok {
-					cleanData[key] = make(map[time.Time]PixelData)
-				}
-				cleanData[key][date] = validData[date]
-			}
-			progressBar.Add(1)
-			mu.Unlock()
-		})
+// plotSeries returns the series of every pixel with valid dates, and the valid dates of
+// every pixel.
+func plotSeries(cube *Cube) ([]PixelSeries, [][]int) {
+	var series []PixelSeries
+	dates := make([][]int, cube.plane())
+	for pixel := range cube.plane() {
+		var values map[string][]float64
+		dates[pixel], values = pixelSeries(cube, pixel)
+		if len(dates[pixel]) > 0 {
+			series = append(series, PixelSeries{X: pixel % cube.Width, Y: pixel / cube.Width, Values: values})
+		}
 	}
+	return series, dates
+}
 
-	// Wait for all tasks
-	go func() {
-		wp.StopWait()
-		close(errChan)
-	}()
-
-	// Return the first error if any
-	if err := <-errChan; err != nil {
-		return nil, fmt.Errorf("error during dataset cleaning: %v", err)
+// checkSmoothed reports a smoothed series that does not belong to the plot or lacks values.
+func checkSmoothed(cube *Cube, result SmoothedSeries, dates []int) error {
+	if len(dates) == 0 {
+		return fmt.Errorf("no series was sent for this pixel")
+	}
+	for _, name := range cube.Indexes {
+		if len(result.Values[name]) != len(dates) {
+			return fmt.Errorf("got %d smoothed %s values for %d dates", len(result.Values[name]), name, len(dates))
+		}
 	}
+	return nil
+}
 
-	if len(cleanData) == 0 {
-		return nil, fmt.Errorf("no valid data found after cleaning")
+// pixelSeries returns the dates on which the pixel is valid and its index series on them,
+// keyed by index name.
+func pixelSeries(cube *Cube, pixel int) ([]int, map[string][]float64) {
+	dates := cube.validDates(pixel)
+	values := make(map[string][]float64, len(cube.Indexes))
+	for i, name := range cube.Indexes {
+		for _, date := range dates {
+			values[name] = append(values[name], cube.value(date, i, pixel))
+		}
 	}
-	return cleanData, nil
+	return dates, values
 }
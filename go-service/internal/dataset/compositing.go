@@ -0,0 +1,176 @@
+package dataset
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/forest-guardian/forest-guardian-api-poc/internal/config"
+)
+
+const (
+	compositeMedian  = "median"
+	compositeMaxNDVI = "max_ndvi"
+)
+
+// compositing resamples the series of every pixel onto bins of period days, counted from the
+// Unix epoch so every plot and season shares the same calendar grid. A composite is dated by
+// the first day of its bin and holds, for every pixel valid on a date of the bin, either the
+// median of each index or the values of the date of highest NDVI. Pixels valid on no date of
+// a bin are invalid in its composite. The zero value keeps the acquisition dates.
+type compositing struct {
+	period int
+	method string
+}
+
+func configuredCompositing() (compositing, error) {
+	cfg, err := config.Get()
+	if err != nil {
+		return compositing{}, err
+	}
+	settings := cfg.Compositing
+	if settings.PeriodDays < 0 {
+		return compositing{}, fmt.Errorf("compositing period must not be negative in %s", config.Path())
+	}
+	method := settings.Method
+	switch method {
+	case "":
+		method = compositeMedian
+	case compositeMedian, compositeMaxNDVI:
+	default:
+		return compositing{}, fmt.Errorf("unknown compositing method %q in %s: use \"median\" or \"max_ndvi\"", settings.Method, config.Path())
+	}
+	return compositing{period: settings.PeriodDays, method: method}, nil
+}
+
+// Name identifies the compositing in the datasets it builds, such as "median_10d", or "" when
+// it is disabled.
+func (c compositing) Name() string {
+	if c.period == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s_%dd", c.method, c.period)
+}
+
+// binStart returns the first day of the bin holding t.
+func (c compositing) binStart(t time.Time) time.Time {
+	days := int64(math.Floor(float64(t.Unix()) / 86400))
+	bin := days / int64(c.period)
+	if days < 0 && days%int64(c.period) != 0 {
+		bin--
+	}
+	return time.Unix(bin*int64(c.period)*86400, 0).UTC()
+}
+
+// apply returns the composites of a clean cube, or the cube itself when compositing is
+// disabled.
+func (c compositing) apply(cube *Cube) *Cube {
+	if c.period == 0 {
+		return cube
+	}
+
+	// bins[j] lists the dates of cube composited into the j-th composite
+	var starts []time.Time
+	var bins [][]int
+	for date, t := range cube.Dates {
+		start := c.binStart(t)
+		if len(starts) == 0 || !starts[len(starts)-1].Equal(start) {
+			starts = append(starts, start)
+			bins = append(bins, nil)
+		}
+		bins[len(bins)-1] = append(bins[len(bins)-1], date)
+	}
+
+	composite := newCube(starts, slices.Clone(cube.Indexes), cube.Width, cube.Height, cube.GeoTransform)
+	copy(composite.Latitude, cube.Latitude)
+	copy(composite.Longitude, cube.Longitude)
+	copy(composite.Coverage, cube.Coverage)
+	composite.GapFilling = cube.GapFilling
+	composite.Compositing = c.Name()
+
+	ndvi := cube.IndexPosition("ndvi")
+	for j, dates := range bins {
+		// The latest acquisition of a bin names its sensor
+		composite.Sensors[j] = cube.Sensors[dates[len(dates)-1]]
+		for pixel := range cube.plane() {
+			var valid []int
+			for _, date := range dates {
+				if cube.state(date, pixel) == stateValid {
+					valid = append(valid, date)
+				}
+			}
+			if len(valid) == 0 {
+				continue
+			}
+
+			// The contributing date closest to an observation lends its provenance and gap days
+			source := valid[0]
+			for _, date := range valid[1:] {
+				if gapRank(cube.gapDays[date*cube.plane()+pixel]) < gapRank(cube.gapDays[source*cube.plane()+pixel]) {
+					source = date
+				}
+			}
+
+			if c.method == compositeMaxNDVI {
+				source = maxIndexDate(cube, valid, ndvi, pixel, source)
+				for i := range cube.Indexes {
+					composite.setValue(j, i, pixel, cube.value(source, i, pixel))
+				}
+			} else {
+				for i := range cube.Indexes {
+					composite.setValue(j, i, pixel, medianValue(cube, valid, i, pixel))
+				}
+			}
+			composite.setEstimated(j, pixel, cube.provenanceOf(source, pixel))
+			composite.gapDays[j*composite.plane()+pixel] = cube.gapDays[source*cube.plane()+pixel]
+		}
+	}
+	removeInvalidDates(composite)
+
+	fmt.Printf("Composited %d dates into %d bins of %d days\n", len(cube.Dates), len(composite.Dates), c.period)
+	return composite
+}
+
+// gapRank orders gap days from the closest to an observation, -1 (never observed) last.
+func gapRank(days int32) int64 {
+	if days < 0 {
+		return math.MaxInt64
+	}
+	return int64(days)
+}
+
+// maxIndexDate returns the date among dates on which an index of the pixel is highest, or
+// fallback when the index is missing on all of them.
+func maxIndexDate(cube *Cube, dates []int, index, pixel, fallback int) int {
+	if index < 0 {
+		return fallback
+	}
+	best, highest := fallback, math.Inf(-1)
+	for _, date := range dates {
+		if value := cube.value(date, index, pixel); value > highest {
+			best, highest = date, value
+		}
+	}
+	return best
+}
+
+// medianValue returns the median of an index of the pixel over dates, skipping NaN, or NaN when
+// it is missing on all of them.
+func medianValue(cube *Cube, dates []int, index, pixel int) float64 {
+	var values []float64
+	for _, date := range dates {
+		if value := cube.value(date, index, pixel); !math.IsNaN(value) {
+			values = append(values, value)
+		}
+	}
+	if len(values) == 0 {
+		return math.NaN()
+	}
+	slices.Sort(values)
+	middle := len(values) / 2
+	if len(values)%2 == 1 {
+		return values[middle]
+	}
+	return (values[middle-1] + values[middle]) / 2
+}
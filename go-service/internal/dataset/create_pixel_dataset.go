@@ -4,11 +4,16 @@ import (
 	"errors"
 	"fmt"
 	"image/color"
+	"slices"
+	"sync"
 	"time"
 
 	"github.com/airbusgeo/godal"
+	"github.com/forest-guardian/forest-guardian-api-poc/internal/config"
+	"github.com/forest-guardian/forest-guardian-api-poc/internal/properties"
 	"github.com/forest-guardian/forest-guardian-api-poc/internal/sentinel"
 	"github.com/forest-guardian/forest-guardian-api-poc/internal/utils"
+	"github.com/gammazero/workerpool"
 	"github.com/schollz/progressbar/v3"
 )
 
@@ -23,6 +28,11 @@ type Indexes struct {
 	NDVI  []float64
 }
 
+// PixelData holds the core indices of a pixel on one date. Indexes carries the other
+// enabled indices, keyed by name, Sensor the mission the image was acquired by and Coverage
+// the share of the pixel inside the plot polygon, below 1 on edge pixels. Provenance tells
+// observed values from gap-filled estimates, and GapDays is the number of days to the
+// nearest observation of the pixel, -1 if it was never observed.
 type PixelData struct {
 	X         int                  `csv:"x"`
 	Y         int                  `csv:"y"`
@@ -32,141 +42,227 @@ type PixelData struct {
 	NDMI      float64              `csv:"ndmi"`
 	PSRI      float64              `csv:"psri"`
 	NDVI      float64              `csv:"ndvi"`
+	Indexes   map[string]float64   `csv:"-"`
 	Status    sentinel.PixelStatus `csv:"-"`
+	Sensor    sentinel.Sensor      `csv:"-"`
+	Coverage  float64              `csv:"coverage"`
 	Color     *color.RGBA          `csv:"-"`
+
+	Provenance Provenance `csv:"provenance"`
+	GapDays    int        `csv:"gap_days"`
+}
+
+// IndexValues returns every index of the pixel keyed by name, core indices included.
+func (p PixelData) IndexValues() map[string]float64 {
+	values := map[string]float64{
+		"ndre": p.NDRE,
+		"ndmi": p.NDMI,
+		"psri": p.PSRI,
+		"ndvi": p.NDVI,
+	}
+	for name, value := range p.Indexes {
+		values[name] = value
+	}
+	return values
 }
 
-func xyToLatLon(dataset *godal.Dataset, x, y int) (float64, float64, error) {
+// SetIndexValues replaces the pixel indices with values. Indexes gets a fresh map, as pixel
+// copies would otherwise share it.
+func (p *PixelData) SetIndexValues(values map[string]float64) {
+	p.NDRE = values["ndre"]
+	p.NDMI = values["ndmi"]
+	p.PSRI = values["psri"]
+	p.NDVI = values["ndvi"]
+	p.Indexes = nil
+	for name, value := range values {
+		if slices.Contains(sentinel.CoreIndexes, name) {
+			continue
+		}
+		if p.Indexes == nil {
+			p.Indexes = make(map[string]float64)
+		}
+		p.Indexes[name] = value
+	}
+}
+
+// latLonPlanes returns the WGS84 latitude and longitude of every pixel center of the image,
+// flattened as [y][x] and transformed in a single batch.
+func latLonPlanes(dataset *godal.Dataset) ([]float64, []float64, error) {
 	geoTransform, err := dataset.GeoTransform()
 	if err != nil {
-		return 0, 0, fmt.Errorf("failed to get GeoTransform: %w", err)
+		return nil, nil, fmt.Errorf("failed to get GeoTransform: %w", err)
 	}
 
-	xCoord := geoTransform[0] + geoTransform[1]*(float64(x)+0.5) + geoTransform[2]*(float64(y)+0.5)
-	yCoord := geoTransform[3] + geoTransform[4]*(float64(x)+0.5) + geoTransform[5]*(float64(y)+0.5)
+	width := dataset.Structure().SizeX
+	height := dataset.Structure().SizeY
+	xs := make([]float64, width*height)
+	ys := make([]float64, width*height)
+	for y := range height {
+		for x := range width {
+			xs[y*width+x] = geoTransform[0] + geoTransform[1]*(float64(x)+0.5) + geoTransform[2]*(float64(y)+0.5)
+			ys[y*width+x] = geoTransform[3] + geoTransform[4]*(float64(x)+0.5) + geoTransform[5]*(float64(y)+0.5)
+		}
+	}
 
 	// Transform to WGS84
 	srcSR := dataset.SpatialRef()
 	defer srcSR.Close()
-	dstSR, _ := godal.NewSpatialRefFromEPSG(4326) // WGS84
+	dstSR, err := godal.NewSpatialRefFromEPSG(4326) // WGS84
+	if err != nil {
+		return nil, nil, err
+	}
 	defer dstSR.Close()
-	tr, _ := godal.NewTransform(srcSR, dstSR)
+	tr, err := godal.NewTransform(srcSR, dstSR)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create transform: %w", err)
+	}
 	defer tr.Close()
 
-	xs := []float64{xCoord}
-	ys := []float64{yCoord}
 	if err := tr.TransformEx(xs, ys, nil, nil); err != nil {
-		return 0, 0, fmt.Errorf("transform error: %w", err)
+		return nil, nil, fmt.Errorf("transform error: %w", err)
 	}
 
-	return ys[0], xs[0], nil
+	return ys, xs, nil
 }
 
-func CreatePixelDataset(forest, plot string, images map[time.Time]*godal.Dataset) (map[[2]int]map[time.Time]PixelData, error) {
-	var width, height, totalPixels int
-
-	for _, imageData := range images {
-		width = imageData.Structure().SizeX
-		height = imageData.Structure().SizeY
-		totalPixels = width * height
-		break
-	}
-
-	historicalPixelDataset := make(map[[2]int]map[time.Time]PixelData)
-	sortedImageDates := utils.GetSortedKeys(images, false)
-	target := len(sortedImageDates) * width * height
-	progressBar := progressbar.Default(int64(target), "Creating pixel dataset")
-	validImagesCount := 0
-
-	var errGlobal error
-	for date, image := range images {
-		treatablePixelsCount, invalidPixelsCount, validPixelsCount := 0, 0, 0
-		for y := range height {
-			for x := range width {
-				result, err := getData(image, totalPixels, width, height, x, y, date)
-				if err != nil {
-					errGlobal = err
-					break
-				}
+// CreatePixelDataset reads the index values and status of every pixel of the plot images into
+// a Cube. Each image is read and its indices computed once, by a pool of
+// properties.IndexExtractionWorkers workers; a GDAL dataset is never shared between workers.
+// The cube is stored as the pixel stage of the images and reused while they are unchanged.
+func CreatePixelDataset(forest, plot string, images map[time.Time]*godal.Dataset) (*Cube, error) {
+	sortedImageDates := utils.GetSortedKeys(images, true)
+	if len(sortedImageDates) == 0 {
+		return nil, fmt.Errorf("no data available to create the dataset for forest: %s, plot: %s using %d images from dates %v", forest, plot, len(images), sortedImageDates)
+	}
 
-				if result != nil {
-					result.Latitude, result.Longitude, err = xyToLatLon(image, x, y)
-					if err != nil {
-						errGlobal = err
-						break
-					}
-
-					key := [2]int{x, y}
-					if _, ok := historicalPixelDataset[key]; !ok {
-						historicalPixelDataset[key] = make(map[time.Time]PixelData)
-					}
-					historicalPixelDataset[key][date] = *result
-
-					switch result.Status {
-					case sentinel.PixelStatusTreatable:
-						treatablePixelsCount++
-					case sentinel.PixelStatusInvalid:
-						invalidPixelsCount++
-					case sentinel.PixelStatusValid:
-						validPixelsCount++
-					}
-
-					progressBar.Add(1)
-				}
-				if errGlobal != nil {
-					break
-				}
-			}
-			if errGlobal != nil {
-				break
-			}
-		}
-		if errGlobal != nil {
-			continue
+	// Every image of the plot is warped onto its sentinel.PlotGrid, so the first one gives the
+	// size, geotransform, coordinates and coverage of the whole dataset
+	first := images[sortedImageDates[0]]
+	width := first.Structure().SizeX
+	height := first.Structure().SizeY
+	for _, image := range images {
+		if image.Structure().SizeX != width || image.Structure().SizeY != height {
+			return nil, errors.New("error while creating pixel dataset: different image size")
 		}
+	}
 
-		if validPixelsCount == 0 {
-			continue
+	cfg, err := config.Get()
+	if err != nil {
+		return nil, err
+	}
+	geometry, err := sentinel.GetGeometryFromGeoJSON(forest, plot)
+	if err != nil {
+		return nil, err
+	}
+	defer geometry.Close()
+	stage, keyed, err := pixelStage(forest, plot, images, sortedImageDates, geometry, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if keyed {
+		if cube, ok := loadCube(stage); ok {
+			return cube, nil
 		}
-		validImagesCount++
 	}
 
+	enabledIndexes, err := sentinel.EnabledIndexes()
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, index := range enabledIndexes {
+		names = append(names, index.Name)
+	}
+	geoTransform, err := first.GeoTransform()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get GeoTransform: %w", err)
+	}
+	cube := newCube(sortedImageDates, cubeIndexes(names), width, height, geoTransform)
+
+	cube.Latitude, cube.Longitude, err = latLonPlanes(first)
+	if err != nil {
+		return nil, fmt.Errorf("error while creating pixel dataset: %w", err)
+	}
+	coverage, err := sentinel.PlotCoverage(first, geometry)
+	if err != nil {
+		return nil, fmt.Errorf("error while creating pixel dataset: %w", err)
+	}
+	for y := range height {
+		copy(cube.Coverage[y*width:(y+1)*width], coverage[y])
+	}
+
+	progressBar := progressbar.Default(int64(len(sortedImageDates)), "Creating pixel dataset")
+	var (
+		mu               sync.Mutex
+		errGlobal        error
+		validImagesCount int
+	)
+	wp := workerpool.New(properties.IndexExtractionWorkers())
+	for date, imageDate := range sortedImageDates {
+		wp.Submit(func() {
+			defer progressBar.Add(1)
+			validPixelsCount, err := extractImage(cube, date, images[imageDate], cfg.Coverage.MinFraction)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if errGlobal == nil {
+					errGlobal = fmt.Errorf("image %s: %w", imageDate.Format("2006-01-02"), err)
+				}
+				return
+			}
+			if validPixelsCount > 0 {
+				validImagesCount++
+			}
+		})
+	}
+	wp.StopWait()
 	progressBar.Finish()
 
 	if errGlobal != nil {
 		return nil, fmt.Errorf("error while creating pixel dataset: %w", errGlobal)
 	}
-
-	if len(historicalPixelDataset) == 0 {
-		return nil, fmt.Errorf("no data available to create the dataset for forest: %s, plot: %s using %d images from dates %v", forest, plot, len(images), sortedImageDates)
-	}
 	fmt.Printf("Got %d valid images\n", validImagesCount)
-	return historicalPixelDataset, nil
+	if keyed {
+		saveCube(stage, cube)
+	}
+	return cube, nil
 }
 
-func getData(image *godal.Dataset, totalPixels, width, height, x, y int, date time.Time) (*PixelData, error) {
-	if totalPixels != 0 && totalPixels != width*height {
-		return nil, errors.New("different image size")
-	}
-	var indexes map[string][][]float64
-	var err error
-	utils.ExecuteWithMutex(func() {
-		indexes, err = sentinel.GetIndexesFromImage(image)
-	})
+// extractImage computes the indices of the image and writes them, with the status of every
+// pixel, into date of the cube. It returns the number of valid pixels.
+func extractImage(cube *Cube, date int, image *godal.Dataset, minCoverage float64) (int, error) {
+	indexes, err := sentinel.GetIndexesFromImage(image)
 	if err != nil {
-		return nil, err
+		return 0, err
+	}
+	planes := make([][][]float64, len(cube.Indexes))
+	for i, name := range cube.Indexes {
+		plane, ok := indexes[name]
+		if !ok {
+			return 0, fmt.Errorf("missing index %s", name)
+		}
+		planes[i] = plane
 	}
-	bands := sentinel.GetBands(indexes, x, y)
+	cube.Sensors[date] = sentinel.ImageSensor(image)
 
-	pixelStatus := bands.Valid()
-	return &PixelData{
-		X:      x,
-		Y:      y,
-		NDRE:   bands.NDRE,
-		NDMI:   bands.NDMI,
-		PSRI:   bands.PSRI,
-		NDVI:   bands.NDVI,
-		Status: pixelStatus,
-	}, nil
+	validPixelsCount := 0
+	for y := range cube.Height {
+		for x := range cube.Width {
+			pixel := y*cube.Width + x
+			for i, plane := range planes {
+				cube.setValue(date, i, pixel, plane[y][x])
+			}
 
+			// Pixels outside the plot polygon, or covering too little of it, are dropped
+			status := sentinel.GetBands(indexes, x, y).Valid()
+			if cube.Coverage[pixel] <= 0 || cube.Coverage[pixel] < minCoverage {
+				status = sentinel.PixelStatusInvalid
+			}
+			cube.setState(date, pixel, stateOf(status))
+			if status == sentinel.PixelStatusValid {
+				validPixelsCount++
+			}
+		}
+	}
+	return validPixelsCount, nil
 }
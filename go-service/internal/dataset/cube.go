@@ -0,0 +1,283 @@
+package dataset
+
+import (
+	"math"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/forest-guardian/forest-guardian-api-poc/internal/sentinel"
+)
+
+// pixelState is the status of a pixel on one date, stored in a byte rather than a
+// sentinel.PixelStatus string to keep the status plane small on forest-wide extents.
+type pixelState uint8
+
+const (
+	stateInvalid pixelState = iota
+	stateUnknown
+	stateTreatable
+	stateValid
+)
+
+func stateOf(status sentinel.PixelStatus) pixelState {
+	switch status {
+	case sentinel.PixelStatusValid:
+		return stateValid
+	case sentinel.PixelStatusTreatable:
+		return stateTreatable
+	case sentinel.PixelStatusUnknown:
+		return stateUnknown
+	default:
+		return stateInvalid
+	}
+}
+
+func (s pixelState) status() sentinel.PixelStatus {
+	switch s {
+	case stateValid:
+		return sentinel.PixelStatusValid
+	case stateTreatable:
+		return sentinel.PixelStatusTreatable
+	case stateUnknown:
+		return sentinel.PixelStatusUnknown
+	default:
+		return sentinel.PixelStatusInvalid
+	}
+}
+
+// Cube is the pixel dataset of a plot: the value of every index on every pixel of the plot
+// grid and every date, with the status of each pixel on each date. Images of a plot share
+// one grid, so the coordinates and coverage of a pixel are the same on every date.
+type Cube struct {
+	// Dates are sorted ascending
+	Dates []time.Time
+	// Indexes are the index names, the core indices first
+	Indexes      []string
+	Width        int
+	Height       int
+	GeoTransform [6]float64
+	// Values holds the index values flattened as [date][index][y][x]
+	Values []float32
+	// Sensors holds the mission that acquired each date
+	Sensors []sentinel.Sensor
+	// Latitude, Longitude and Coverage are planes flattened as [y][x]
+	Latitude  []float64
+	Longitude []float64
+	Coverage  []float64
+	// GapFilling names the GapFiller that estimated the unknown pixels, once cleaned
+	GapFilling string
+	// Compositing names the compositing of the cube's dates, "" when they are acquisition dates
+	Compositing string
+
+	// status is flattened as [date][y][x]
+	status []pixelState
+	// provenance and gapDays are flattened as [date][y][x]; gapDays is recorded once gaps are
+	// filled
+	provenance []Provenance
+	gapDays    []int32
+	// stageKey is the key of the stored stage the cube is the output of, "" if it has none
+	stageKey string
+}
+
+// newCube allocates a cube with every pixel invalid and every value NaN.
+func newCube(dates []time.Time, indexes []string, width, height int, geoTransform [6]float64) *Cube {
+	plane := width * height
+	cube := &Cube{
+		Dates:        dates,
+		Indexes:      indexes,
+		Width:        width,
+		Height:       height,
+		GeoTransform: geoTransform,
+		Values:       make([]float32, len(dates)*len(indexes)*plane),
+		Sensors:      make([]sentinel.Sensor, len(dates)),
+		Latitude:     make([]float64, plane),
+		Longitude:    make([]float64, plane),
+		Coverage:     make([]float64, plane),
+		status:       make([]pixelState, len(dates)*plane),
+		provenance:   make([]Provenance, len(dates)*plane),
+		gapDays:      make([]int32, len(dates)*plane),
+	}
+	nan := float32(math.NaN())
+	for i := range cube.Values {
+		cube.Values[i] = nan
+	}
+	return cube
+}
+
+// cubeIndexes orders the index names of an image as the cube stores them.
+func cubeIndexes(names []string) []string {
+	indexes := slices.Clone(sentinel.CoreIndexes)
+	var extra []string
+	for _, name := range names {
+		if !slices.Contains(indexes, name) {
+			extra = append(extra, name)
+		}
+	}
+	sort.Strings(extra)
+	return append(indexes, extra...)
+}
+
+func (c *Cube) plane() int {
+	return c.Width * c.Height
+}
+
+// offset returns the position of a value in Values.
+func (c *Cube) offset(date, index, pixel int) int {
+	return (date*len(c.Indexes)+index)*c.plane() + pixel
+}
+
+// Value returns the value of an index on a pixel and date, NaN if it is missing.
+func (c *Cube) Value(date, index, x, y int) float64 {
+	return float64(c.Values[c.offset(date, index, y*c.Width+x)])
+}
+
+// Status returns the status of a pixel on a date.
+func (c *Cube) Status(date, x, y int) sentinel.PixelStatus {
+	return c.state(date, y*c.Width+x).status()
+}
+
+// IndexPosition returns the position of an index in Indexes, or -1.
+func (c *Cube) IndexPosition(name string) int {
+	return slices.Index(c.Indexes, name)
+}
+
+func (c *Cube) value(date, index, pixel int) float64 {
+	return float64(c.Values[c.offset(date, index, pixel)])
+}
+
+func (c *Cube) setValue(date, index, pixel int, value float64) {
+	c.Values[c.offset(date, index, pixel)] = float32(value)
+}
+
+func (c *Cube) state(date, pixel int) pixelState {
+	return c.status[date*c.plane()+pixel]
+}
+
+func (c *Cube) setState(date, pixel int, state pixelState) {
+	c.status[date*c.plane()+pixel] = state
+}
+
+// pixelsWithState lists the pixels in state on a date.
+func (c *Cube) pixelsWithState(date int, state pixelState) []int {
+	var pixels []int
+	for pixel, s := range c.status[date*c.plane() : (date+1)*c.plane()] {
+		if s == state {
+			pixels = append(pixels, pixel)
+		}
+	}
+	return pixels
+}
+
+// countStates returns the number of pixels in each state on a date.
+func (c *Cube) countStates(date int) map[pixelState]int {
+	counts := make(map[pixelState]int)
+	for _, s := range c.status[date*c.plane() : (date+1)*c.plane()] {
+		counts[s]++
+	}
+	return counts
+}
+
+// validDates lists the dates on which the pixel is valid.
+func (c *Cube) validDates(pixel int) []int {
+	var dates []int
+	for date := range c.Dates {
+		if c.state(date, pixel) == stateValid {
+			dates = append(dates, date)
+		}
+	}
+	return dates
+}
+
+// neighbors lists the pixels around pixel, diagonals included, in one of states on a date.
+func (c *Cube) neighbors(date, pixel int, states ...pixelState) []int {
+	x, y := pixel%c.Width, pixel/c.Width
+	var neighbors []int
+	for dy := -1; dy <= 1; dy++ {
+		for dx := -1; dx <= 1; dx++ {
+			nx, ny := x+dx, y+dy
+			if (dx == 0 && dy == 0) || nx < 0 || ny < 0 || nx >= c.Width || ny >= c.Height {
+				continue
+			}
+			neighbor := ny*c.Width + nx
+			if slices.Contains(states, c.state(date, neighbor)) {
+				neighbors = append(neighbors, neighbor)
+			}
+		}
+	}
+	return neighbors
+}
+
+// removeDates drops the dates for which drop returns true.
+func (c *Cube) removeDates(drop func(date int) bool) {
+	plane := c.plane()
+	block := len(c.Indexes) * plane
+	kept := 0
+	for date := range c.Dates {
+		if drop(date) {
+			continue
+		}
+		if kept != date {
+			copy(c.Values[kept*block:(kept+1)*block], c.Values[date*block:(date+1)*block])
+			copy(c.status[kept*plane:(kept+1)*plane], c.status[date*plane:(date+1)*plane])
+			copy(c.provenance[kept*plane:(kept+1)*plane], c.provenance[date*plane:(date+1)*plane])
+			copy(c.gapDays[kept*plane:(kept+1)*plane], c.gapDays[date*plane:(date+1)*plane])
+			c.Dates[kept] = c.Dates[date]
+			c.Sensors[kept] = c.Sensors[date]
+		}
+		kept++
+	}
+	c.Dates = c.Dates[:kept]
+	c.Sensors = c.Sensors[:kept]
+	c.Values = c.Values[:kept*block]
+	c.status = c.status[:kept*plane]
+	c.provenance = c.provenance[:kept*plane]
+	c.gapDays = c.gapDays[:kept*plane]
+}
+
+// removeIndex drops an index from every date.
+func (c *Cube) removeIndex(index int) {
+	plane := c.plane()
+	values := make([]float32, 0, len(c.Dates)*(len(c.Indexes)-1)*plane)
+	for date := range c.Dates {
+		for i := range c.Indexes {
+			if i != index {
+				start := c.offset(date, i, 0)
+				values = append(values, c.Values[start:start+plane]...)
+			}
+		}
+	}
+	c.Values = values
+	c.Indexes = slices.Delete(slices.Clone(c.Indexes), index, index+1)
+}
+
+// Pixel returns a pixel on a date as PixelData.
+func (c *Cube) Pixel(date, x, y int) PixelData {
+	pixel := y*c.Width + x
+	data := PixelData{
+		X:          x,
+		Y:          y,
+		Latitude:   c.Latitude[pixel],
+		Longitude:  c.Longitude[pixel],
+		Status:     c.state(date, pixel).status(),
+		Sensor:     c.Sensors[date],
+		Coverage:   c.Coverage[pixel],
+		Provenance: c.provenanceOf(date, pixel),
+		GapDays:    int(c.gapDays[date*c.plane()+pixel]),
+	}
+	values := make(map[string]float64, len(c.Indexes))
+	for i, name := range c.Indexes {
+		values[name] = c.value(date, i, pixel)
+	}
+	data.SetIndexValues(values)
+	return data
+}
+
+// ValidPixels returns the valid pixels of a date as PixelData.
+func (c *Cube) ValidPixels(date int) []PixelData {
+	var pixels []PixelData
+	for _, pixel := range c.pixelsWithState(date, stateValid) {
+		pixels = append(pixels, c.Pixel(date, pixel%c.Width, pixel/c.Width))
+	}
+	return pixels
+}
@@ -7,7 +7,6 @@ import (
 	"time"
 
 	"github.com/forest-guardian/forest-guardian-api-poc/internal/sentinel"
-	"github.com/forest-guardian/forest-guardian-api-poc/internal/utils"
 	"github.com/schollz/progressbar/v3"
 )
 
@@ -24,79 +23,121 @@ type DeltaData struct {
 	NDMIDerivative float64   `csv:"ndmi_derivative"`
 	PSRIDerivative float64   `csv:"psri_derivative"`
 	NDVIDerivative float64   `csv:"ndvi_derivative"`
-	Label          *string   `csv:"label"`
+	// IndexDerivatives holds the derivatives of PixelData.Indexes, keyed by index name.
+	IndexDerivatives map[string]float64 `csv:"-"`
+	Label            *string            `csv:"label"`
+	// GapFilling names the GapFiller of the clean dataset the sample comes from
+	GapFilling string `csv:"gap_filling"`
+	// Features holds the configured temporal features at EndDate, keyed by column name.
+	Features map[string]float64 `csv:"-"`
+	// Compositing names the compositing of the dates the sample pairs, "" for acquisition dates
+	Compositing string `csv:"compositing"`
 }
 
-func CreateDeltaDataset(forest, plot string, deltaMin, deltaMax int, cleanDataset map[[2]int]map[time.Time]PixelData) (map[[2]int]map[time.Time]DeltaData, error) {
+// CreateDeltaDataset pairs every valid date of each pixel with the latest valid date between
+// deltaMin and deltaMax days earlier, and derives the indices over that span. Samples are keyed
+// by their end date, so each end date gets the one pair of the shortest span in the window.
+// The configured temporal features are added at the later date. With compositing configured,
+// the dates are those of the composites of the clean dataset rather than the acquisition
+// dates. The delta dataset of a stored clean dataset is stored as well, and reused for the
+// same settings.
+func CreateDeltaDataset(forest, plot string, deltaMin, deltaMax int, cleanDataset *Cube) (map[[2]int]map[time.Time]DeltaData, error) {
+	features, err := configuredTemporalFeatures()
+	if err != nil {
+		return nil, err
+	}
+	compositing, err := configuredCompositing()
+	if err != nil {
+		return nil, err
+	}
+	stage, keyed, err := deltaStage(forest, plot, deltaMin, deltaMax, cleanDataset)
+	if err != nil {
+		return nil, err
+	}
+	if keyed {
+		if deltaDataset, ok := loadDeltaDataset(stage); ok {
+			return deltaDataset, nil
+		}
+	}
+	cleanDataset = compositing.apply(cleanDataset)
 
 	var deltaDataset = make(map[[2]int]map[time.Time]DeltaData)
 	found := 0
 	notFound := 0
-	target := len(cleanDataset)
+	target := cleanDataset.Width * cleanDataset.Height
 
 	progressBar := progressbar.Default(int64(target), "Creating delta dataset")
 
-	for _, data := range cleanDataset {
-		if len(data) < 3 {
+	for pixel := range target {
+		ascSortedDates := cleanDataset.validDates(pixel)
+		if len(ascSortedDates) < 3 {
 			notFound++
 			progressBar.Add(1)
 			continue
 		}
-
-		ascSortedDates := utils.GetSortedKeys(data, true)
-
-		for _, startDate := range ascSortedDates {
-			minTargetDate := startDate.AddDate(0, 0, deltaMin)
-			maxTargetDate := startDate.AddDate(0, 0, deltaMax)
-
-			for _, endDate := range ascSortedDates {
-				if endDate.After(maxTargetDate) {
-					notFound++
+		x, y := pixel%cleanDataset.Width, pixel/cleanDataset.Width
+
+		for _, end := range ascSortedDates {
+			endDate := cleanDataset.Dates[end]
+			earliestStart := endDate.AddDate(0, 0, -deltaMax)
+			latestStart := endDate.AddDate(0, 0, -deltaMin)
+			start := -1
+			for _, candidate := range ascSortedDates {
+				candidateDate := cleanDataset.Dates[candidate]
+				if candidate >= end || candidateDate.After(latestStart) {
 					break
 				}
-				if endDate.Before(minTargetDate) {
-					continue
+				if !candidateDate.Before(earliestStart) {
+					start = candidate
 				}
+			}
+			if start < 0 {
+				notFound++
+				continue
+			}
+			startDate := cleanDataset.Dates[start]
+			timeDiff := int(endDate.Sub(startDate).Hours() / 24)
+			derivatives := make(map[string]float64, len(cleanDataset.Indexes))
+			for i, name := range cleanDataset.Indexes {
+				derivatives[name] = (cleanDataset.value(end, i, pixel) - cleanDataset.value(start, i, pixel)) / float64(timeDiff)
+			}
 
-				ndreValue := data[endDate].NDRE
-				ndreStart := data[startDate].NDRE
-				ndmiValue := data[endDate].NDMI
-				ndmiStart := data[startDate].NDMI
-				psriValue := data[endDate].PSRI
-				psriStart := data[startDate].PSRI
-				ndviValue := data[endDate].NDVI
-				ndviStart := data[startDate].NDVI
-
-				timeDiff := int(endDate.Sub(startDate).Hours() / 24)
-				ndreDerivative := (ndreValue - ndreStart) / float64(timeDiff)
-				ndmiDerivative := (ndmiValue - ndmiStart) / float64(timeDiff)
-				psriDerivative := (psriValue - psriStart) / float64(timeDiff)
-				ndviDerivative := (ndviValue - ndviStart) / float64(timeDiff)
-
-				data := DeltaData{
-					Forest:         forest,
-					Plot:           plot,
-					DeltaMin:       deltaMin,
-					DeltaMax:       deltaMax,
-					Delta:          timeDiff,
-					StartDate:      startDate,
-					EndDate:        endDate,
-					PixelData:      data[endDate],
-					NDREDerivative: ndreDerivative,
-					NDMIDerivative: ndmiDerivative,
-					PSRIDerivative: psriDerivative,
-					NDVIDerivative: ndviDerivative,
+			var indexDerivatives map[string]float64
+			for name, value := range derivatives {
+				if slices.Contains(sentinel.CoreIndexes, name) {
+					continue
 				}
-
-				if _, exists := deltaDataset[[2]int{data.X, data.Y}]; !exists {
-					deltaDataset[[2]int{data.X, data.Y}] = make(map[time.Time]DeltaData)
+				if indexDerivatives == nil {
+					indexDerivatives = make(map[string]float64)
 				}
-				deltaDataset[[2]int{data.X, data.Y}][endDate] = data
+				indexDerivatives[name] = value
+			}
 
-				found++
+			data := DeltaData{
+				Forest:           forest,
+				Plot:             plot,
+				DeltaMin:         deltaMin,
+				DeltaMax:         deltaMax,
+				Delta:            timeDiff,
+				StartDate:        startDate,
+				EndDate:          endDate,
+				PixelData:        cleanDataset.Pixel(end, x, y),
+				NDREDerivative:   derivatives["ndre"],
+				NDMIDerivative:   derivatives["ndmi"],
+				PSRIDerivative:   derivatives["psri"],
+				NDVIDerivative:   derivatives["ndvi"],
+				IndexDerivatives: indexDerivatives,
+				GapFilling:       cleanDataset.GapFilling,
+				Features:         features.compute(cleanDataset, pixel, ascSortedDates, end),
+				Compositing:      cleanDataset.Compositing,
+			}
 
-				break
+			if _, exists := deltaDataset[[2]int{data.X, data.Y}]; !exists {
+				deltaDataset[[2]int{data.X, data.Y}] = make(map[time.Time]DeltaData)
 			}
+			deltaDataset[[2]int{data.X, data.Y}][endDate] = data
+
+			found++
 		}
 		progressBar.Add(1)
 	}
@@ -107,128 +148,66 @@ func CreateDeltaDataset(forest, plot string, deltaMin, deltaMax int, cleanDatase
 		return nil, errors.New("no valid delta data found. The delta dataset is empty")
 	}
 
+	if keyed {
+		saveDeltaDataset(stage, deltaDataset)
+	}
 	return deltaDataset, nil
 }
 
-type InTreatmentPixel struct {
-	PixelData
-	mostRecentValidPixelDate *time.Time
+// removeInvalidDates drops the dates on which every pixel is invalid.
+func removeInvalidDates(cube *Cube) {
+	cube.removeDates(func(date int) bool {
+		counts := cube.countStates(date)
+		return counts[stateInvalid] == cube.plane()
+	})
 }
 
-func (p InTreatmentPixel) ListNeighborsByStatus(images map[[2]int]map[time.Time]InTreatmentPixel, date time.Time, statuses ...sentinel.PixelStatus) []InTreatmentPixel {
-	//look for valid neighbors
-	directions := [][2]int{
-		{p.X - 1, p.Y},     // Up
-		{p.X + 1, p.Y},     // Down
-		{p.X, p.Y - 1},     // Left
-		{p.X, p.Y + 1},     // Right
-		{p.X - 1, p.Y - 1}, // Up-Left
-		{p.X - 1, p.Y + 1}, // Up-Right
-		{p.X + 1, p.Y - 1}, // Down-Left
-		{p.X + 1, p.Y + 1}, // Down-Right
-	}
-	var validNeighbors []InTreatmentPixel
-	for _, direction := range directions {
-		pixelData, directionExists := images[direction]
-		if directionExists {
-			_, ok := pixelData[date]
-			if !ok {
-				continue // Skip if the date does not exist for this pixel
-			}
-
-			if slices.Contains(statuses, pixelData[date].Status) {
-				validNeighbors = append(validNeighbors, pixelData[date])
-			}
-		}
-
-	}
-	return validNeighbors
+// prepareCleaning drops the dates without any usable pixel, estimates the obscured pixels with
+// filler and fills the missing indices, leaving the series the smoother receives.
+func prepareCleaning(data *Cube, filler GapFiller) {
+	removeInvalidDates(data)
+
+	observedSAR := observedSARFeatures(data)
+	filler.Fill(data)
+	data.GapFilling = filler.Name()
+	data.recordGapDays()
+	restoreSARFeatures(data, observedSAR)
+	fillMissingIndexes(data)
 }
 
-func (p InTreatmentPixel) FindMostRecentPixelsByStatus(datePixel map[time.Time]InTreatmentPixel, currentDate time.Time, statuses ...sentinel.PixelStatus) (*InTreatmentPixel, *time.Time) {
-	descSortedDates := utils.GetSortedKeys(datePixel, false)
-
-	for _, date := range descSortedDates {
-		if date.After(currentDate) || date.Equal(currentDate) {
-			continue
-		}
-
-		pixelRegressive, ok := datePixel[date]
-		if !ok {
-			continue
-		}
-
-		if slices.Contains(statuses, pixelRegressive.Status) {
-			return &pixelRegressive, &date
-		}
+// CreateCleanDataset estimates the obscured pixels of the dataset with the configured
+// GapFiller and smooths the series of every pixel, in place. Only valid pixels hold clean data
+// in the returned cube. The clean dataset of a stored pixel dataset is stored as well, and
+// returned instead while the gap filling and smoothing settings are unchanged.
+func CreateCleanDataset(forest, plot string, data *Cube) (*Cube, error) {
+	filler, err := NewGapFiller()
+	if err != nil {
+		return nil, err
 	}
-	return nil, nil
-}
-func (p InTreatmentPixel) GetNextValidPixel(datePixel map[time.Time]InTreatmentPixel, curretDate time.Time) *InTreatmentPixel {
-	var nextValidPixel *InTreatmentPixel
-	ascSortedDates := utils.GetSortedKeys(datePixel, true)
-	for _, date := range ascSortedDates {
-		if date.Before(curretDate) || date.Equal(curretDate) {
-			continue
-		}
-		pixelData, ok := datePixel[date]
-		if !ok {
-			continue
-		}
-		if pixelData.Status == sentinel.PixelStatusValid {
-			nextValidPixel = &pixelData
-			break
-		}
+	smoother, err := NewSmoother()
+	if err != nil {
+		return nil, err
 	}
-
-	return nextValidPixel
-}
-
-func getUniqueDates(result map[[2]int]map[time.Time]PixelData) map[time.Time]struct{} {
-	allDates := make(map[time.Time]struct{})
-	for _, pixels := range result {
-		for date := range pixels {
-			allDates[date] = struct{}{}
-		}
+	defer smoother.Close()
+	stage, keyed, err := cleanStage(forest, plot, data, filler, smoother)
+	if err != nil {
+		return nil, err
 	}
-
-	return allDates
-}
-
-func removeInvalidDates(result map[[2]int]map[time.Time]PixelData) map[[2]int]map[time.Time]PixelData {
-	allDates := getUniqueDates(result)
-
-	for date := range allDates {
-		allInvalid := true
-		for _, pixels := range result {
-			if pixel, exists := pixels[date]; exists {
-				if pixel.Status != sentinel.PixelStatusInvalid {
-					allInvalid = false
-					break
-				}
-			}
-		}
-
-		// If all pixels are Invalid for this date, remove the date from all entries
-		if allInvalid {
-			for _, pixels := range result {
-				delete(pixels, date)
-			}
+	if keyed {
+		if clean, ok := loadCube(stage); ok {
+			return clean, nil
 		}
 	}
+	prepareCleaning(data, filler)
 
-	return result
-}
-
-func CreateCleanDataset(forest, plot string, data map[[2]int]map[time.Time]PixelData) (map[[2]int]map[time.Time]PixelData, error) {
-	result := removeInvalidDates(data)
-
-	result = estimatePixels(result)
-
-	result, err := cleanDataset(result)
-	if err != nil {
+	if err := cleanDataset(data, smoother); err != nil {
 		return nil, err
 	}
+	// Dates left without a valid pixel carry no clean data
+	removeInvalidDates(data)
 
-	return result, nil
+	if keyed {
+		saveCube(stage, data)
+	}
+	return data, nil
 }
@@ -3,284 +3,175 @@ package dataset
 import (
 	"fmt"
 	"slices"
-	"time"
-
-	"github.com/forest-guardian/forest-guardian-api-poc/internal/sentinel"
-	"github.com/forest-guardian/forest-guardian-api-poc/internal/utils"
 )
 
-func createTreatmentImages(images map[[2]int]map[time.Time]PixelData) map[[2]int]map[time.Time]InTreatmentPixel {
-	treatmentImages := make(map[[2]int]map[time.Time]InTreatmentPixel)
-	for key, datePixel := range images {
-		for date, pixel := range datePixel {
-			if _, exists := treatmentImages[key]; !exists {
-				treatmentImages[key] = make(map[time.Time]InTreatmentPixel)
-			}
-			treatmentImages[key][date] = InTreatmentPixel{
-				PixelData: pixel,
-			}
+// neighborsShared reports whether a and b hold a common pixel.
+func neighborsShared(a, b []int) bool {
+	for _, pixel := range a {
+		if slices.Contains(b, pixel) {
+			return true
 		}
 	}
-
-	return treatmentImages
+	return false
 }
 
-type GroupedPixels struct {
-	Treatable []InTreatmentPixel
-	Invalid   []InTreatmentPixel
-	Valid     []InTreatmentPixel
-	Unknown   []InTreatmentPixel
-}
-
-func groupPixelsByStatus(images map[[2]int]map[time.Time]InTreatmentPixel, date time.Time) GroupedPixels {
-	groupedPixels := GroupedPixels{}
-	for _, datePixel := range images {
-		pixel, exists := datePixel[date]
-		if !exists {
-			continue
-		}
-		switch pixel.Status {
-		case sentinel.PixelStatusTreatable:
-			groupedPixels.Treatable = append(groupedPixels.Treatable, pixel)
-		case sentinel.PixelStatusInvalid:
-			groupedPixels.Invalid = append(groupedPixels.Invalid, pixel)
-		case sentinel.PixelStatusValid:
-			groupedPixels.Valid = append(groupedPixels.Valid, pixel)
-		case sentinel.PixelStatusUnknown:
-			groupedPixels.Unknown = append(groupedPixels.Unknown, pixel)
+// mostRecentDate returns the latest date before date on which the pixel is in one of states,
+// or -1.
+func (c *Cube) mostRecentDate(date, pixel int, states ...pixelState) int {
+	for previous := date - 1; previous >= 0; previous-- {
+		if slices.Contains(states, c.state(previous, pixel)) {
+			return previous
 		}
 	}
-	return groupedPixels
+	return -1
 }
 
-func parseInTreatmentToPixelData(treatmentImages map[[2]int]map[time.Time]InTreatmentPixel) map[[2]int]map[time.Time]PixelData {
-	treatedImages := make(map[[2]int]map[time.Time]PixelData)
-	for k, datePixel := range treatmentImages {
-		for date, pixel := range datePixel {
-			if pixel.Status != sentinel.PixelStatusInvalid {
-				if _, exists := treatedImages[k]; !exists {
-					treatedImages[k] = make(map[time.Time]PixelData)
-				}
-				treatedImages[k][date] = pixel.PixelData
-			}
+// nextValidDate returns the first date after date on which the pixel is valid, or -1.
+func (c *Cube) nextValidDate(date, pixel int) int {
+	for next := date + 1; next < len(c.Dates); next++ {
+		if c.state(next, pixel) == stateValid {
+			return next
 		}
 	}
-	return treatedImages
+	return -1
 }
 
-type delta struct {
-	NDRE float64
-	NDMI float64
-	PSRI float64
-	NDVI float64
-}
-
-func estimatePixelIndexes(date time.Time, pixel InTreatmentPixel, treatmentImages map[[2]int]map[time.Time]InTreatmentPixel) *InTreatmentPixel {
-	nextValidPixel := pixel.GetNextValidPixel(treatmentImages[[2]int{pixel.X, pixel.Y}], date)
-	if nextValidPixel != nil {
-		deltaMean := delta{
-			NDRE: (nextValidPixel.NDRE - pixel.NDRE) / 2,
-			NDMI: (nextValidPixel.NDMI - pixel.NDMI) / 2,
-			PSRI: (nextValidPixel.PSRI - pixel.PSRI) / 2,
-			NDVI: (nextValidPixel.NDVI - pixel.NDVI) / 2,
+// estimatePixelIndexes estimates a treatable pixel, whose values were copied from the date in
+// sources, and reports whether it could. The pixel is halfway to its next valid observation
+// when there is one, otherwise it moves by the average change of the neighbors valid on both
+// dates.
+func estimatePixelIndexes(cube *Cube, date, pixel int, sources []int) bool {
+	if next := cube.nextValidDate(date, pixel); next >= 0 {
+		for i := range cube.Indexes {
+			value := cube.value(date, i, pixel)
+			cube.setValue(date, i, pixel, value+(cube.value(next, i, pixel)-value)/2)
 		}
-
-		pixel.NDRE += deltaMean.NDRE
-		pixel.NDMI += deltaMean.NDMI
-		pixel.PSRI += deltaMean.PSRI
-		pixel.NDVI += deltaMean.NDVI
-		pixel.Status = sentinel.PixelStatusValid
-		return &pixel
+		cube.setEstimated(date, pixel, ProvenanceTemporal)
+		return true
 	}
 
-	deltas := getPixelDeltas(treatmentImages, pixel, date)
+	deltas := getPixelDeltas(cube, date, pixel, sources[date*cube.plane()+pixel])
 	if deltas == nil {
-		return nil
+		return false
 	}
-
-	avgNDRE := 0.0
-	avgNDMI := 0.0
-	avgPSRI := 0.0
-	avgNDVI := 0.0
-
-	for _, d := range deltas {
-		avgNDRE += d.NDRE
-		avgNDMI += d.NDMI
-		avgPSRI += d.PSRI
-		avgNDVI += d.NDVI
+	for i := range cube.Indexes {
+		cube.setValue(date, i, pixel, cube.value(date, i, pixel)+deltas[i])
 	}
-
-	avgNDRE /= float64(len(deltas))
-	avgNDMI /= float64(len(deltas))
-	avgPSRI /= float64(len(deltas))
-	avgNDVI /= float64(len(deltas))
-
-	pixel.Status = sentinel.PixelStatusValid
-	pixel.NDRE += avgNDRE
-	pixel.NDMI += avgNDMI
-	pixel.PSRI += avgPSRI
-	pixel.NDVI += avgNDVI
-
-	return &pixel
+	cube.setEstimated(date, pixel, ProvenanceSpatial)
+	return true
 }
 
-func getPixelDeltas(treatmentImages map[[2]int]map[time.Time]InTreatmentPixel, pixel InTreatmentPixel, date time.Time) []delta {
-	/*
-		- if its treatable, its value is the value of the most recent valid pixel
-		- I need to get the valid pixel neighbors and calculate the delta from its past values it they are valid
-
-	*/
-
-	var deltas []delta
-	pixelValidNeighbors := pixel.ListNeighborsByStatus(treatmentImages, date, sentinel.PixelStatusValid)
+// getPixelDeltas returns the average change of every index, between source and date, of the
+// neighbors valid on both dates, or nil if there is none.
+func getPixelDeltas(cube *Cube, date, pixel, source int) []float64 {
+	pixelValidNeighbors := cube.neighbors(date, pixel, stateValid)
 	if len(pixelValidNeighbors) == 0 {
 		return nil
 	}
-	mostRecentValidNeighbors := pixel.ListNeighborsByStatus(treatmentImages, *pixel.mostRecentValidPixelDate, sentinel.PixelStatusValid)
+	// The treatable neighbors the pixel was copied alongside may have failed estimation since,
+	// leaving none valid on the source date
+	mostRecentValidNeighbors := cube.neighbors(source, pixel, stateValid)
 	if len(mostRecentValidNeighbors) == 0 {
-		panic("No valid pixels found for most recent valid pixel")
+		return nil
 	}
-	for _, validNeighbor := range pixelValidNeighbors {
-		for _, mostRecentValidPixel := range mostRecentValidNeighbors {
-			if validNeighbor.X == mostRecentValidPixel.X && validNeighbor.Y == mostRecentValidPixel.Y {
-				deltas = append(deltas, delta{
-					NDRE: validNeighbor.NDRE - mostRecentValidPixel.NDRE,
-					NDMI: validNeighbor.NDMI - mostRecentValidPixel.NDMI,
-					PSRI: validNeighbor.PSRI - mostRecentValidPixel.PSRI,
-					NDVI: validNeighbor.NDVI - mostRecentValidPixel.NDVI,
-				})
-			}
+
+	deltas := make([]float64, len(cube.Indexes))
+	count := 0
+	for _, neighbor := range pixelValidNeighbors {
+		if !slices.Contains(mostRecentValidNeighbors, neighbor) {
+			continue
 		}
+		for i := range cube.Indexes {
+			deltas[i] += cube.value(date, i, neighbor) - cube.value(source, i, neighbor)
+		}
+		count++
 	}
-	if len(deltas) == 0 {
+	if count == 0 {
 		return nil
 	}
+	for i := range deltas {
+		deltas[i] /= float64(count)
+	}
 	return deltas
 }
 
-func estimatePixels(images map[[2]int]map[time.Time]PixelData) map[[2]int]map[time.Time]PixelData {
-	treatmentImages := createTreatmentImages(images)
-	var includedDates []time.Time
-	for _, datePixel := range treatmentImages {
-		for date := range datePixel {
-			if !slices.Contains(includedDates, date) {
-				includedDates = append(includedDates, date)
-			}
-		}
-	}
-
-	ascDates := utils.SortDates(includedDates, true)
+// estimatePixels resolves the unknown pixels of every date. An unknown pixel sharing a valid
+// or treatable neighbor with its most recent valid or treatable observation becomes
+// treatable and takes that observation's values, the others become invalid. Treatable
+// pixels are then estimated; those that cannot be become invalid.
+func estimatePixels(cube *Cube) {
+	// sources holds the date each treatable pixel was copied from, flattened as [date][y][x]
+	sources := make([]int, len(cube.status))
 
-	for i, date := range ascDates {
+	for date := range cube.Dates {
 		rounds := 0
 		statusUpdated := true
 		unknownCount := 1
 		for statusUpdated && unknownCount > 0 {
 			statusUpdated = false
 			rounds++
-			groupedPixels := groupPixelsByStatus(treatmentImages, date)
-			unknownCount = len(groupedPixels.Unknown)
-			fmt.Printf("%d - Treatable: %d, Invalid: %d, Valid: %d, Unknown: %d\n", rounds, len(groupedPixels.Treatable), len(groupedPixels.Invalid), len(groupedPixels.Valid), len(groupedPixels.Unknown))
-			for _, pixel := range groupedPixels.Unknown {
-				key := [2]int{pixel.X, pixel.Y}
-
+			counts := cube.countStates(date)
+			unknownCount = counts[stateUnknown]
+			fmt.Printf("%d - Treatable: %d, Invalid: %d, Valid: %d, Unknown: %d\n", rounds, counts[stateTreatable], counts[stateInvalid], counts[stateValid], counts[stateUnknown])
+			for _, pixel := range cube.pixelsWithState(date, stateUnknown) {
 				//if its the first image all unknown pixels are invalid
-				if i == 0 {
-					pixel.Status = sentinel.PixelStatusInvalid
-					treatmentImages[key][date] = pixel
+				if date == 0 {
+					cube.setState(date, pixel, stateInvalid)
 					statusUpdated = true
 					continue
 				}
-				mostRecentValidPixel, mostRecentValidPixelDate := pixel.FindMostRecentPixelsByStatus(treatmentImages[key], date, sentinel.PixelStatusValid, sentinel.PixelStatusTreatable)
-				if mostRecentValidPixel == nil {
+				mostRecentDate := cube.mostRecentDate(date, pixel, stateValid, stateTreatable)
+				if mostRecentDate < 0 {
 					continue
 				}
 
-				mostRecentValidPixelValidOrTreatableNeighbors := mostRecentValidPixel.ListNeighborsByStatus(treatmentImages, *mostRecentValidPixelDate, sentinel.PixelStatusValid, sentinel.PixelStatusTreatable)
-				if len(mostRecentValidPixelValidOrTreatableNeighbors) == 0 {
+				mostRecentNeighbors := cube.neighbors(mostRecentDate, pixel, stateValid, stateTreatable)
+				if len(mostRecentNeighbors) == 0 {
 					continue
 				}
 				// at least one valid or treatable neighbor must match a current valid or treatable pixel neighbor
-				isTreatable := false
-				currentValidNeighbors := pixel.ListNeighborsByStatus(treatmentImages, date, sentinel.PixelStatusValid, sentinel.PixelStatusTreatable, sentinel.PixelStatusUnknown)
+				currentNeighbors := cube.neighbors(date, pixel, stateValid, stateTreatable, stateUnknown)
 
 				//if all are unknown, continue
-				unknownCount := 0
-				for _, currentNeighbor := range currentValidNeighbors {
-					if currentNeighbor.Status == sentinel.PixelStatusUnknown {
-						unknownCount++
-					}
-				}
-				if unknownCount == len(currentValidNeighbors) {
+				if len(cube.neighbors(date, pixel, stateUnknown)) == len(currentNeighbors) {
 					continue
 				}
 
-				for _, currentNeighbor := range currentValidNeighbors {
-					for _, mostRecentNeighbor := range mostRecentValidPixelValidOrTreatableNeighbors {
-						if mostRecentNeighbor.X == currentNeighbor.X && mostRecentNeighbor.Y == currentNeighbor.Y {
-							mostRecentValidPixel.Status = sentinel.PixelStatusTreatable
-							mostRecentValidPixel.mostRecentValidPixelDate = mostRecentValidPixelDate
-							treatmentImages[key][date] = *mostRecentValidPixel
-							isTreatable = true
-							statusUpdated = true
-							break
-						}
+				if neighborsShared(currentNeighbors, mostRecentNeighbors) {
+					for i := range cube.Indexes {
+						cube.setValue(date, i, pixel, cube.value(mostRecentDate, i, pixel))
 					}
-					if isTreatable {
-						break
-					}
-				}
-
-				if !isTreatable {
-					pixel.Status = sentinel.PixelStatusInvalid
-					treatmentImages[key][date] = pixel
-					statusUpdated = true
+					cube.setState(date, pixel, stateTreatable)
+					sources[date*cube.plane()+pixel] = mostRecentDate
+				} else {
+					cube.setState(date, pixel, stateInvalid)
 				}
-
+				statusUpdated = true
 			}
 		}
 	}
 
 	fmt.Println("Starting estimation rounds...")
 
-	for _, date := range ascDates {
+	for date := range cube.Dates {
 		round := 0
 		statusUpdate := true
 		for statusUpdate {
 			statusUpdate = false
-			groupedPixels := groupPixelsByStatus(treatmentImages, date)
-			fmt.Printf("%d - Treatable: %d, Invalid: %d, Valid: %d, Unknown: %d\n", round, len(groupedPixels.Treatable), len(groupedPixels.Invalid), len(groupedPixels.Valid), len(groupedPixels.Unknown))
-			if len(groupedPixels.Treatable) == 0 {
+			counts := cube.countStates(date)
+			fmt.Printf("%d - Treatable: %d, Invalid: %d, Valid: %d, Unknown: %d\n", round, counts[stateTreatable], counts[stateInvalid], counts[stateValid], counts[stateUnknown])
+			if counts[stateTreatable] == 0 {
 				break
 			}
 
-			for _, pixel := range groupedPixels.Treatable {
-				if pixel.Status != sentinel.PixelStatusTreatable {
-					continue
-				}
-
-				estimatedPixel := estimatePixelIndexes(date, pixel, treatmentImages)
-				if estimatedPixel == nil {
-					continue
+			for _, pixel := range cube.pixelsWithState(date, stateTreatable) {
+				if estimatePixelIndexes(cube, date, pixel, sources) {
+					statusUpdate = true
 				}
-
-				treatmentImages[[2]int{pixel.X, pixel.Y}][date] = *estimatedPixel
-				statusUpdate = true
 			}
 			round++
 		}
 	}
 
-	for _, datePixel := range treatmentImages {
-		for date, pixel := range datePixel {
-			if pixel.Status == sentinel.PixelStatusTreatable || pixel.Status == sentinel.PixelStatusUnknown {
-				pixel.Status = sentinel.PixelStatusInvalid
-				treatmentImages[[2]int{pixel.X, pixel.Y}][date] = pixel
-			}
-		}
-	}
-
-	treatedImages := parseInTreatmentToPixelData(treatmentImages)
-
-	return treatedImages
+	invalidateUnresolved(cube)
 }
@@ -0,0 +1,222 @@
+package dataset
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/forest-guardian/forest-guardian-api-poc/internal/sentinel"
+	"github.com/gocarina/gocsv"
+)
+
+// ExtraIndexColumns returns the names of the non-core indices stored in a final data CSV
+// header, recognised as a column "<index>" paired with a column "<index>_derivative".
+func ExtraIndexColumns(header []string) []string {
+	var names []string
+	for _, column := range header {
+		if slices.Contains(sentinel.CoreIndexes, column) || strings.HasSuffix(column, "_derivative") || IsTemporalFeature(column) {
+			continue
+		}
+		if slices.Contains(header, column+"_derivative") {
+			names = append(names, column)
+		}
+	}
+	return names
+}
+
+// FinalDataRecords converts rows to CSV records. The struct fields are encoded by gocsv, every
+// extra index gets a "<index>" and a "<index>_derivative" column after them, and every
+// temporal feature a column of its name last.
+func FinalDataRecords(rows []FinalData) ([]string, [][]string, error) {
+	encoded, err := gocsv.MarshalBytes(&rows)
+	if err != nil {
+		return nil, nil, err
+	}
+	records, err := csv.NewReader(bytes.NewReader(encoded)).ReadAll()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil, fmt.Errorf("no final data to encode")
+	}
+
+	extraNames := make(map[string]struct{})
+	for _, row := range rows {
+		for name := range row.Indexes {
+			extraNames[name] = struct{}{}
+		}
+	}
+	var names []string
+	for name := range extraNames {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	featureNames := make(map[string]struct{})
+	for _, row := range rows {
+		for name := range row.Features {
+			featureNames[name] = struct{}{}
+		}
+	}
+	var features []string
+	for name := range featureNames {
+		features = append(features, name)
+	}
+	sort.Strings(features)
+
+	header := records[0]
+	for _, name := range names {
+		header = append(header, name, name+"_derivative")
+	}
+	header = append(header, features...)
+	body := records[1:]
+	for i, row := range rows {
+		for _, name := range names {
+			value, ok := row.Indexes[name]
+			derivative := row.IndexDerivatives[name]
+			if !ok {
+				body[i] = append(body[i], "", "")
+				continue
+			}
+			body[i] = append(body[i], strconv.FormatFloat(value, 'f', -1, 64), strconv.FormatFloat(derivative, 'f', -1, 64))
+		}
+		for _, name := range features {
+			value, ok := row.Features[name]
+			if !ok {
+				body[i] = append(body[i], "")
+				continue
+			}
+			body[i] = append(body[i], strconv.FormatFloat(value, 'f', -1, 64))
+		}
+	}
+	return header, body, nil
+}
+
+// WriteFinalData writes rows as CSV. With a nil header the rows' own header is written first;
+// otherwise only the rows are written, with their columns arranged to match header.
+func WriteFinalData(writer *csv.Writer, rows []FinalData, header []string) error {
+	rowHeader, records, err := FinalDataRecords(rows)
+	if err != nil {
+		return err
+	}
+
+	if header == nil {
+		if err := writer.Write(rowHeader); err != nil {
+			return err
+		}
+		if err := writer.WriteAll(records); err != nil {
+			return err
+		}
+		return writer.Error()
+	}
+
+	columnIndex := make(map[string]int, len(rowHeader))
+	for i, column := range rowHeader {
+		columnIndex[column] = i
+	}
+	// Columns the existing header lacks, such as indices or features enabled after the file
+	// was created, cannot be appended
+	var dropped []string
+	for _, column := range rowHeader {
+		if !slices.Contains(header, column) {
+			dropped = append(dropped, column)
+		}
+	}
+	if len(dropped) > 0 {
+		fmt.Printf("Warning: the existing file has no %s columns, their values are not written; create a new dataset to keep them\n", strings.Join(dropped, ", "))
+	}
+	for _, record := range records {
+		aligned := make([]string, len(header))
+		for i, column := range header {
+			if idx, ok := columnIndex[column]; ok {
+				aligned[i] = record[idx]
+			}
+		}
+		if err := writer.Write(aligned); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// ReadFinalData parses a final data CSV written by WriteFinalData, restoring the extra
+// index columns into Indexes and IndexDerivatives and the temporal features into Features.
+func ReadFinalData(reader io.Reader) ([]FinalData, error) {
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []FinalData
+	if err := gocsv.UnmarshalBytes(content, &rows); err != nil {
+		return nil, err
+	}
+
+	records, err := csv.NewReader(bytes.NewReader(content)).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return rows, nil
+	}
+	header := records[0]
+	names := ExtraIndexColumns(header)
+	var features []string
+	for _, column := range header {
+		if IsTemporalFeature(column) {
+			features = append(features, column)
+		}
+	}
+	if len(names) == 0 && len(features) == 0 {
+		return rows, nil
+	}
+
+	columnIndex := make(map[string]int, len(header))
+	for i, column := range header {
+		columnIndex[column] = i
+	}
+	for i := range rows {
+		record := records[i+1]
+		for _, name := range names {
+			valueText := record[columnIndex[name]]
+			if valueText == "" {
+				continue
+			}
+			value, err := strconv.ParseFloat(valueText, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s value %q: %v", name, valueText, err)
+			}
+			derivative, err := strconv.ParseFloat(record[columnIndex[name+"_derivative"]], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s_derivative value %q: %v", name, record[columnIndex[name+"_derivative"]], err)
+			}
+			if rows[i].Indexes == nil {
+				rows[i].Indexes = make(map[string]float64)
+				rows[i].IndexDerivatives = make(map[string]float64)
+			}
+			rows[i].Indexes[name] = value
+			rows[i].IndexDerivatives[name] = derivative
+		}
+		for _, name := range features {
+			valueText := record[columnIndex[name]]
+			if valueText == "" {
+				continue
+			}
+			value, err := strconv.ParseFloat(valueText, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s value %q: %v", name, valueText, err)
+			}
+			if rows[i].Features == nil {
+				rows[i].Features = make(map[string]float64)
+			}
+			rows[i].Features[name] = value
+		}
+	}
+	return rows, nil
+}
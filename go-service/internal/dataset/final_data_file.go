@@ -0,0 +1,92 @@
+package dataset
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Final data file extensions, which choose the format files are read and written in
+const (
+	CSVExtension     = ".csv"
+	ParquetExtension = ".parquet"
+)
+
+// IsParquet tells whether a final data file is stored as Parquet rather than CSV.
+func IsParquet(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ParquetExtension)
+}
+
+// TrimFinalDataExtension returns the name of a final data file without its CSV or Parquet
+// extension.
+func TrimFinalDataExtension(name string) string {
+	if IsParquet(name) {
+		return name[:len(name)-len(ParquetExtension)]
+	}
+	return strings.TrimSuffix(name, CSVExtension)
+}
+
+// ReadFinalDataFile reads a final data file, as Parquet or as CSV by its extension.
+func ReadFinalDataFile(path string) ([]FinalData, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	if !IsParquet(path) {
+		return ReadFinalData(file)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return ReadFinalDataParquet(file, info.Size())
+}
+
+// WriteFinalDataFile writes rows to a final data file, as Parquet or as CSV by its extension,
+// replacing it whole.
+func WriteFinalDataFile(path string, rows []FinalData) error {
+	_, err := writeFileAtomically(path, func(w io.Writer) error {
+		if IsParquet(path) {
+			return WriteFinalDataParquet(w, rows)
+		}
+		return WriteFinalData(csv.NewWriter(w), rows, nil)
+	})
+	return err
+}
+
+// ConvertFinalDataFile writes the rows of a final data file to another, in the format of its
+// extension, and returns how many there were. An existing destination is not overwritten.
+func ConvertFinalDataFile(source, destination string) (int, error) {
+	if fileExists(destination) {
+		return 0, fmt.Errorf("%s already exists", destination)
+	}
+	rows, err := ReadFinalDataFile(source)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", source, err)
+	}
+	if len(rows) == 0 {
+		return 0, fmt.Errorf("no final data in %s", source)
+	}
+	if err := WriteFinalDataFile(destination, rows); err != nil {
+		return 0, fmt.Errorf("failed to write %s: %w", destination, err)
+	}
+	return len(rows), nil
+}
+
+// GapFillingStrategies lists the distinct gap filling strategies recorded in final data rows,
+// in order of appearance. Rows written before strategies were recorded are left out.
+func GapFillingStrategies(rows []FinalData) []string {
+	var strategies []string
+	for _, row := range rows {
+		if row.GapFilling != "" && !slices.Contains(strategies, row.GapFilling) {
+			strategies = append(strategies, row.GapFilling)
+		}
+	}
+	return strategies
+}
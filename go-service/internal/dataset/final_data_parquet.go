@@ -0,0 +1,310 @@
+package dataset
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/gocarina/gocsv"
+	"github.com/parquet-go/parquet-go"
+)
+
+// parquetKind is how a struct field of FinalData is stored in Parquet.
+type parquetKind int
+
+const (
+	parquetDouble parquetKind = iota
+	parquetInt
+	parquetString
+	// parquetOptionalString stores a *string, nil as null
+	parquetOptionalString
+	parquetTimestamp
+	// parquetText stores a type through its CSV text, as Provenance
+	parquetText
+)
+
+// parquetField is a column of FinalData taken from a struct field, named by its CSV tag.
+type parquetField struct {
+	name  string
+	index []int
+	kind  parquetKind
+}
+
+var (
+	timeType          = reflect.TypeFor[time.Time]()
+	csvMarshallerType = reflect.TypeFor[gocsv.TypeMarshaller]()
+)
+
+// finalDataFields returns the columns of the struct fields of FinalData, in the order gocsv
+// writes them.
+func finalDataFields() ([]parquetField, error) {
+	var fields []parquetField
+	var walk func(t reflect.Type, index []int) error
+	walk = func(t reflect.Type, index []int) error {
+		for i := range t.NumField() {
+			field := t.Field(i)
+			path := append(slices.Clone(index), i)
+			if field.Anonymous && field.Type.Kind() == reflect.Struct {
+				if err := walk(field.Type, path); err != nil {
+					return err
+				}
+				continue
+			}
+			name := field.Tag.Get("csv")
+			if !field.IsExported() || name == "" || name == "-" {
+				continue
+			}
+			var kind parquetKind
+			switch {
+			case field.Type == timeType:
+				kind = parquetTimestamp
+			case field.Type.Implements(csvMarshallerType):
+				kind = parquetText
+			case field.Type.Kind() == reflect.Pointer && field.Type.Elem().Kind() == reflect.String:
+				kind = parquetOptionalString
+			case field.Type.Kind() == reflect.String:
+				kind = parquetString
+			case field.Type.Kind() == reflect.Int:
+				kind = parquetInt
+			case field.Type.Kind() == reflect.Float64:
+				kind = parquetDouble
+			default:
+				return fmt.Errorf("unsupported type %s of final data column %s", field.Type, name)
+			}
+			fields = append(fields, parquetField{name: name, index: path, kind: kind})
+		}
+		return nil
+	}
+	if err := walk(reflect.TypeFor[FinalData](), nil); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+func (f parquetField) node() parquet.Node {
+	switch f.kind {
+	case parquetInt:
+		return parquet.Int(64)
+	case parquetString, parquetText:
+		return parquet.String()
+	case parquetOptionalString:
+		return parquet.Optional(parquet.String())
+	case parquetTimestamp:
+		return parquet.Timestamp(parquet.Microsecond)
+	default:
+		return parquet.Leaf(parquet.DoubleType)
+	}
+}
+
+// value returns the value of the field in row, with its definition level.
+func (f parquetField) value(row reflect.Value) (parquet.Value, int, error) {
+	field := row.FieldByIndex(f.index)
+	switch f.kind {
+	case parquetInt:
+		return parquet.Int64Value(field.Int()), 0, nil
+	case parquetString:
+		return parquet.ByteArrayValue([]byte(field.String())), 0, nil
+	case parquetText:
+		text, err := field.Interface().(gocsv.TypeMarshaller).MarshalCSV()
+		if err != nil {
+			return parquet.Value{}, 0, fmt.Errorf("failed to encode %s: %v", f.name, err)
+		}
+		return parquet.ByteArrayValue([]byte(text)), 0, nil
+	case parquetOptionalString:
+		if field.IsNil() {
+			return parquet.NullValue(), 0, nil
+		}
+		return parquet.ByteArrayValue([]byte(field.Elem().String())), 1, nil
+	case parquetTimestamp:
+		return parquet.Int64Value(field.Interface().(time.Time).UnixMicro()), 0, nil
+	default:
+		return parquet.DoubleValue(field.Float()), 0, nil
+	}
+}
+
+// set stores a value read from the column of the field into row.
+func (f parquetField) set(row reflect.Value, value parquet.Value) error {
+	if value.IsNull() {
+		return nil
+	}
+	field := row.FieldByIndex(f.index)
+	switch f.kind {
+	case parquetInt:
+		field.SetInt(value.Int64())
+	case parquetString:
+		field.SetString(string(value.ByteArray()))
+	case parquetText:
+		if err := field.Addr().Interface().(gocsv.TypeUnmarshaller).UnmarshalCSV(string(value.ByteArray())); err != nil {
+			return fmt.Errorf("invalid %s value: %v", f.name, err)
+		}
+	case parquetOptionalString:
+		text := string(value.ByteArray())
+		field.Set(reflect.ValueOf(&text))
+	case parquetTimestamp:
+		field.Set(reflect.ValueOf(time.UnixMicro(value.Int64()).UTC()))
+	default:
+		field.SetFloat(value.Double())
+	}
+	return nil
+}
+
+// WriteFinalDataParquet writes rows as a Parquet file. The struct fields of FinalData are typed
+// columns named as in CSV, with nil labels as nulls and times as UTC microsecond timestamps.
+// Every extra index gets an optional "<index>" and "<index>_derivative" column and every
+// temporal feature an optional column of its name, null on the rows that lack them.
+func WriteFinalDataParquet(w io.Writer, rows []FinalData) error {
+	fields, err := finalDataFields()
+	if err != nil {
+		return err
+	}
+
+	extraNames := make(map[string]struct{})
+	featureNames := make(map[string]struct{})
+	for _, row := range rows {
+		for name := range row.Indexes {
+			extraNames[name] = struct{}{}
+		}
+		for name := range row.Features {
+			featureNames[name] = struct{}{}
+		}
+	}
+	var indexColumns, features []string
+	for name := range extraNames {
+		indexColumns = append(indexColumns, name, name+"_derivative")
+	}
+	for name := range featureNames {
+		features = append(features, name)
+	}
+	sort.Strings(indexColumns)
+	sort.Strings(features)
+
+	group := parquet.Group{}
+	for _, field := range fields {
+		group[field.name] = field.node()
+	}
+	for _, name := range slices.Concat(indexColumns, features) {
+		group[name] = parquet.Optional(parquet.Leaf(parquet.DoubleType))
+	}
+	schema := parquet.NewSchema("final_data", group)
+	columnIndex := make(map[string]int)
+	for i, path := range schema.Columns() {
+		columnIndex[path[0]] = i
+	}
+
+	writer := parquet.NewWriter(w, schema, parquet.Compression(&parquet.Zstd))
+	records := make([]parquet.Row, 0, len(rows))
+	for _, row := range rows {
+		record := make(parquet.Row, len(columnIndex))
+		value := reflect.ValueOf(row)
+		for _, field := range fields {
+			columnValue, definition, err := field.value(value)
+			if err != nil {
+				return err
+			}
+			column := columnIndex[field.name]
+			record[column] = columnValue.Level(0, definition, column)
+		}
+		optional := func(name string, value float64, ok bool) {
+			column := columnIndex[name]
+			if !ok {
+				record[column] = parquet.NullValue().Level(0, 0, column)
+				return
+			}
+			record[column] = parquet.DoubleValue(value).Level(0, 1, column)
+		}
+		for name := range extraNames {
+			value, ok := row.Indexes[name]
+			optional(name, value, ok)
+			optional(name+"_derivative", row.IndexDerivatives[name], ok)
+		}
+		for _, name := range features {
+			value, ok := row.Features[name]
+			optional(name, value, ok)
+		}
+		records = append(records, record)
+	}
+	if _, err := writer.WriteRows(records); err != nil {
+		return err
+	}
+	return writer.Close()
+}
+
+// ReadFinalDataParquet parses a Parquet file written by WriteFinalDataParquet, restoring the
+// extra index columns into Indexes and IndexDerivatives and the temporal features into
+// Features. Columns the file lacks, such as those added after it was written, are left zero.
+func ReadFinalDataParquet(r io.ReaderAt, size int64) ([]FinalData, error) {
+	file, err := parquet.OpenFile(r, size)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := finalDataFields()
+	if err != nil {
+		return nil, err
+	}
+
+	var header []string
+	for _, path := range file.Schema().Columns() {
+		header = append(header, path[0])
+	}
+	// columnField[i] is the struct field of the i-th column, if any
+	columnField := make([]*parquetField, len(header))
+	for i := range fields {
+		if column := slices.Index(header, fields[i].name); column >= 0 {
+			columnField[column] = &fields[i]
+		}
+	}
+	extraColumns := make(map[string]string)
+	for _, name := range ExtraIndexColumns(header) {
+		extraColumns[name] = name
+		extraColumns[name+"_derivative"] = name
+	}
+
+	rows := make([]FinalData, 0, file.NumRows())
+	reader := parquet.NewReader(file)
+	defer reader.Close()
+	buffer := make([]parquet.Row, 256)
+	for {
+		n, err := reader.ReadRows(buffer)
+		for _, record := range buffer[:n] {
+			var row FinalData
+			value := reflect.ValueOf(&row).Elem()
+			for _, columnValue := range record {
+				column := columnValue.Column()
+				name := header[column]
+				switch {
+				case columnField[column] != nil:
+					if err := columnField[column].set(value, columnValue); err != nil {
+						return nil, err
+					}
+				case columnValue.IsNull():
+				case extraColumns[name] != "":
+					if row.Indexes == nil {
+						row.Indexes = make(map[string]float64)
+						row.IndexDerivatives = make(map[string]float64)
+					}
+					if index := extraColumns[name]; index == name {
+						row.Indexes[index] = columnValue.Double()
+					} else {
+						row.IndexDerivatives[index] = columnValue.Double()
+					}
+				case IsTemporalFeature(name):
+					if row.Features == nil {
+						row.Features = make(map[string]float64)
+					}
+					row.Features[name] = columnValue.Double()
+				}
+			}
+			rows = append(rows, row)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return rows, nil
+}
@@ -0,0 +1,231 @@
+package dataset
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/forest-guardian/forest-guardian-api-poc/internal/config"
+	"github.com/forest-guardian/forest-guardian-api-poc/internal/smoothing"
+)
+
+// GapFiller estimates the values of the pixels the quality mask left unknown, such as the
+// pixels under clouds. Pixels it cannot estimate become invalid.
+type GapFiller interface {
+	// Name identifies the strategy in the configuration and in the datasets it fills
+	Name() string
+	Fill(cube *Cube)
+}
+
+// DefaultGapFilling is the strategy used when the configuration names none.
+const DefaultGapFilling = "neighbors"
+
+// NewGapFiller returns the gap filler selected by the gap filling configuration.
+func NewGapFiller() (GapFiller, error) {
+	cfg, err := config.Get()
+	if err != nil {
+		return nil, err
+	}
+	switch cfg.GapFilling.Strategy {
+	case "", DefaultGapFilling:
+		return NeighborGapFiller{}, nil
+	case "linear":
+		return LinearGapFiller{}, nil
+	case "harmonic":
+		return HarmonicGapFiller{Order: 1, Period: 365.25}, nil
+	case "idw":
+		return IDWGapFiller{Radius: 3, Power: 2}, nil
+	default:
+		return nil, fmt.Errorf("unknown gap filling strategy %q in %s: use \"neighbors\", \"linear\", \"harmonic\" or \"idw\"", cfg.GapFilling.Strategy, config.Path())
+	}
+}
+
+// invalidateUnresolved marks invalid the pixels left unknown or treatable.
+func invalidateUnresolved(cube *Cube) {
+	for date := range cube.Dates {
+		for _, state := range []pixelState{stateTreatable, stateUnknown} {
+			for _, pixel := range cube.pixelsWithState(date, state) {
+				cube.setState(date, pixel, stateInvalid)
+			}
+		}
+	}
+}
+
+// unknownDates lists the dates on which the pixel is unknown.
+func (c *Cube) unknownDates(pixel int) []int {
+	var dates []int
+	for date := range c.Dates {
+		if c.state(date, pixel) == stateUnknown {
+			dates = append(dates, date)
+		}
+	}
+	return dates
+}
+
+// knownValues lists the dates among dates on which an index of the pixel is not NaN.
+func (c *Cube) knownValues(dates []int, index, pixel int) []int {
+	var known []int
+	for _, date := range dates {
+		if !math.IsNaN(c.value(date, index, pixel)) {
+			known = append(known, date)
+		}
+	}
+	return known
+}
+
+// NeighborGapFiller is the original heuristic: an unknown pixel sharing a valid neighbor with
+// its previous observation takes that observation's values, moved halfway to its next valid
+// observation or by the mean change of its neighbors.
+type NeighborGapFiller struct{}
+
+func (NeighborGapFiller) Name() string {
+	return DefaultGapFilling
+}
+
+func (NeighborGapFiller) Fill(cube *Cube) {
+	estimatePixels(cube)
+}
+
+// LinearGapFiller interpolates every index of an unknown pixel linearly in time between the
+// valid observations of the pixel around it. Pixels unknown before the first or after the
+// last observation are not extrapolated.
+type LinearGapFiller struct{}
+
+func (LinearGapFiller) Name() string {
+	return "linear"
+}
+
+func (LinearGapFiller) Fill(cube *Cube) {
+	for pixel := range cube.plane() {
+		observed := cube.validDates(pixel)
+		if len(observed) < 2 {
+			continue
+		}
+		var gaps []int
+		for _, date := range cube.unknownDates(pixel) {
+			if date > observed[0] && date < observed[len(observed)-1] {
+				gaps = append(gaps, date)
+			}
+		}
+
+		for i := range cube.Indexes {
+			known := cube.knownValues(observed, i, pixel)
+			value := func(date int) float64 { return cube.value(date, i, pixel) }
+			for _, date := range gaps {
+				estimate := math.NaN()
+				if len(known) > 0 {
+					estimate = interpolateObserved(cube.Dates, known, value, date)
+				}
+				cube.setValue(date, i, pixel, estimate)
+			}
+		}
+		for _, date := range gaps {
+			cube.setEstimated(date, pixel, ProvenanceTemporal)
+		}
+	}
+	invalidateUnresolved(cube)
+}
+
+// HarmonicGapFiller fits a mean, a linear trend and Order harmonics of Period days to the
+// valid observations of every index of a pixel, and takes the fit on its unknown dates.
+// Pixels with too few observations for the fit are not filled.
+type HarmonicGapFiller struct {
+	Order  int
+	Period float64
+}
+
+func (HarmonicGapFiller) Name() string {
+	return "harmonic"
+}
+
+func (f HarmonicGapFiller) Fill(cube *Cube) {
+	days := make([]float64, len(cube.Dates))
+	for date := range cube.Dates {
+		days[date] = cube.Dates[date].Sub(cube.Dates[0]).Hours() / 24
+	}
+
+	for pixel := range cube.plane() {
+		observed := cube.validDates(pixel)
+		gaps := cube.unknownDates(pixel)
+		if len(observed) <= smoothing.HarmonicTerms(f.Order) || len(gaps) == 0 {
+			continue
+		}
+
+		for i := range cube.Indexes {
+			known := cube.knownValues(observed, i, pixel)
+			times := make([]float64, len(known))
+			values := make([]float64, len(known))
+			for j, date := range known {
+				times[j] = days[date]
+				values[j] = cube.value(date, i, pixel)
+			}
+			// Indices the pixel lacks are left NaN, for fillMissingIndexes
+			fit, err := smoothing.FitHarmonic(times, values, f.Order, f.Period)
+			for _, date := range gaps {
+				estimate := math.NaN()
+				if err == nil {
+					estimate = fit.At(days[date])
+				}
+				cube.setValue(date, i, pixel, estimate)
+			}
+		}
+		for _, date := range gaps {
+			cube.setEstimated(date, pixel, ProvenanceTemporal)
+		}
+	}
+	invalidateUnresolved(cube)
+}
+
+// IDWGapFiller estimates every index of an unknown pixel from the pixels valid on the same
+// date within Radius pixels, weighted by the inverse of their distance to the power Power.
+// Pixels without a valid pixel within Radius are not filled.
+type IDWGapFiller struct {
+	Radius int
+	Power  float64
+}
+
+func (IDWGapFiller) Name() string {
+	return "idw"
+}
+
+func (f IDWGapFiller) Fill(cube *Cube) {
+	for date := range cube.Dates {
+		// Only the pixels observed on the date contribute, not the ones filled before them
+		observed := make([]bool, cube.plane())
+		for _, pixel := range cube.pixelsWithState(date, stateValid) {
+			observed[pixel] = true
+		}
+
+		for _, pixel := range cube.pixelsWithState(date, stateUnknown) {
+			x, y := pixel%cube.Width, pixel/cube.Width
+			sums := make([]float64, len(cube.Indexes))
+			weights := make([]float64, len(cube.Indexes))
+			found := false
+			for ny := max(0, y-f.Radius); ny <= min(cube.Height-1, y+f.Radius); ny++ {
+				for nx := max(0, x-f.Radius); nx <= min(cube.Width-1, x+f.Radius); nx++ {
+					dx, dy := float64(nx-x), float64(ny-y)
+					distance := math.Hypot(dx, dy)
+					neighbor := ny*cube.Width + nx
+					if !observed[neighbor] || distance > float64(f.Radius) {
+						continue
+					}
+					found = true
+					weight := math.Pow(distance, -f.Power)
+					for i := range cube.Indexes {
+						if value := cube.value(date, i, neighbor); !math.IsNaN(value) {
+							sums[i] += weight * value
+							weights[i] += weight
+						}
+					}
+				}
+			}
+			if !found {
+				continue
+			}
+			for i := range cube.Indexes {
+				cube.setValue(date, i, pixel, sums[i]/weights[i])
+			}
+			cube.setEstimated(date, pixel, ProvenanceSpatial)
+		}
+	}
+	invalidateUnresolved(cube)
+}
@@ -1,7 +1,9 @@
 package dataset
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"os"
 	"slices"
 	"time"
@@ -9,7 +11,6 @@ import (
 	"github.com/forest-guardian/forest-guardian-api-poc/internal/properties"
 	"github.com/forest-guardian/forest-guardian-api-poc/internal/utils"
 	"github.com/forest-guardian/forest-guardian-api-poc/internal/weather"
-	"github.com/gocarina/gocsv"
 )
 
 // isBetweenDates checks if a date is between startDate and endDate.
@@ -21,24 +22,70 @@ func fileExists(filename string) bool {
 	return !os.IsNotExist(err)
 }
 
-func buildFilePath(forest, plot string, date time.Time, deltaMin, deltaMax int) string {
-	return fmt.Sprintf("%s/data/final/%s_%s_%s_%d_%d.csv", properties.RootPath(), forest, plot, date.Format("2006-01-02"), deltaMin, deltaMax)
+// buildFilePath names the final data of a sample as saved in data/final before final data was
+// stored as a stage. Final data filled by another strategy than the default, or built from
+// composites, was kept apart, so switching settings does not reuse it.
+func buildFilePath(forest, plot string, date time.Time, deltaMin, deltaMax int, gapFilling, compositing string) string {
+	suffix := ""
+	if gapFilling != DefaultGapFilling {
+		suffix = "_" + gapFilling
+	}
+	if compositing != "" {
+		suffix += "_" + compositing
+	}
+	return fmt.Sprintf("%s/data/final/%s_%s_%s_%d_%d%s.csv", properties.RootPath(), forest, plot, date.Format("2006-01-02"), deltaMin, deltaMax, suffix)
 }
 
+// GetSavedFinalData returns the final data of a sample stored for the current configuration,
+// falling back to the final data saved in data/final by earlier versions, or nil if there is
+// none.
 func GetSavedFinalData(forest, plot string, date time.Time, deltaMin, deltaMax int) ([]FinalData, error) {
-	filePath := buildFilePath(forest, plot, date, deltaMin, deltaMax)
+	stage, err := finalStage(forest, plot, date, deltaMin, deltaMax)
+	if err != nil {
+		return nil, err
+	}
+	var storedFinalData []FinalData
+	if loadStage(stage, func(r io.Reader) error {
+		content, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		storedFinalData, err = ReadFinalDataParquet(bytes.NewReader(content), int64(len(content)))
+		return err
+	}) {
+		return storedFinalData, nil
+	}
+
+	filler, err := NewGapFiller()
+	if err != nil {
+		return nil, err
+	}
+	compositing, err := configuredCompositing()
+	if err != nil {
+		return nil, err
+	}
+	filePath := buildFilePath(forest, plot, date, deltaMin, deltaMax, filler.Name(), compositing.Name())
 	if fileExists(filePath) {
-		var existingFinalData []FinalData
 		file, err := os.Open(filePath)
 		if err != nil {
 			return nil, fmt.Errorf("failed to open existing final data file: %w", err)
 		}
 		defer file.Close()
 
-		err = gocsv.UnmarshalFile(file, &existingFinalData)
+		existingFinalData, err := ReadFinalData(file)
 		if err != nil {
 			return nil, fmt.Errorf("failed to read existing final data: %w", err)
 		}
+		if missing := missingTemporalFeatures(existingFinalData); missing != "" {
+			fmt.Printf("Final data at %s lacks the configured feature %s, recreating it.\n", filePath, missing)
+			return nil, nil
+		}
+		// Final data saved before strategies were recorded was filled by the default one
+		for i := range existingFinalData {
+			if existingFinalData[i].GapFilling == "" {
+				existingFinalData[i].GapFilling = filler.Name()
+			}
+		}
 
 		fmt.Printf("Final data already exists at %s.\n", filePath)
 		return existingFinalData, nil
@@ -47,32 +94,54 @@ func GetSavedFinalData(forest, plot string, date time.Time, deltaMin, deltaMax i
 	return nil, nil
 }
 
+// missingTemporalFeatures returns a configured temporal feature the rows lack, or "".
+func missingTemporalFeatures(rows []FinalData) string {
+	features, err := configuredTemporalFeatures()
+	if err != nil || len(rows) == 0 {
+		return ""
+	}
+	for index := range rows[0].IndexValues() {
+		for _, name := range features.names(index) {
+			if _, ok := rows[0].Features[name]; !ok {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// SaveFinalData stores the final data of a sample as its final stage, to be returned by
+// GetSavedFinalData while the configuration is unchanged.
 func SaveFinalData(finalData []FinalData, date time.Time) error {
 	if len(finalData) == 0 {
 		return fmt.Errorf("no final data to save")
 	}
 
-	filePath := buildFilePath(finalData[0].Forest, finalData[0].Plot, date, finalData[0].DeltaMin, finalData[0].DeltaMax)
-	file, err := os.Create(filePath)
+	stage, err := finalStage(finalData[0].Forest, finalData[0].Plot, date, finalData[0].DeltaMin, finalData[0].DeltaMax)
 	if err != nil {
-		return fmt.Errorf("failed to create final data file: %w", err)
+		return err
 	}
-	defer file.Close()
-
-	err = gocsv.MarshalFile(&finalData, file)
-	if err != nil {
-		return fmt.Errorf("failed to save final data to file: %w", err)
+	// Final data is encoded before it is stored, as saveStage only prints its failures
+	var encoded bytes.Buffer
+	if err := WriteFinalDataParquet(&encoded, finalData); err != nil {
+		return fmt.Errorf("failed to encode final data: %w", err)
 	}
+	saveStage(stage, ParquetExtension, fmt.Sprintf("%d rows", len(finalData)), func(w io.Writer) error {
+		_, err := w.Write(encoded.Bytes())
+		return err
+	})
 
-	fmt.Printf("Final data with %d rows successfully saved to %s.\n", len(finalData), filePath)
+	fmt.Printf("Final data with %d rows stored as final stage %s.\n", len(finalData), shortStageKey(stage.Key))
 	return nil
 }
 
 func GetFinalData(deltaDataset map[[2]int]map[time.Time]DeltaData, historicalWeather weather.HistoricalWeather, startDate, endDate time.Time, forest, plot string) ([]FinalData, error) {
+	// Dates are acquisition sensing times, so the whole end day has to be included
+	lastInstant := time.Date(endDate.Year(), endDate.Month(), endDate.Day(), 23, 59, 59, 0, endDate.Location())
 	dates := make([]time.Time, 0)
 	for date := range deltaDataset {
 		for date := range deltaDataset[date] {
-			if isBetweenDates(date, startDate, endDate) && !slices.Contains(dates, date) {
+			if isBetweenDates(date, startDate, lastInstant) && !slices.Contains(dates, date) {
 				dates = append(dates, date)
 			}
 		}
@@ -0,0 +1,79 @@
+package dataset
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// fillMissingIndexes interpolates in time the indices a pixel lacks on some dates, such as the
+// red-edge indices of Landsat images, from the dates where the same pixel has them. The dates
+// given interpolated values are marked estimated. Indices no pixel observed on any date, as the
+// red-edge indices of plots only Landsat covers, are dropped; those never observed on a single
+// pixel stay NaN and the smoother leaves the pixel out.
+func fillMissingIndexes(cube *Cube) {
+	observed := make([]bool, len(cube.Indexes))
+	for pixel := range cube.plane() {
+		dates := cube.validDates(pixel)
+		for i := range cube.Indexes {
+			var knownDates, missingDates []int
+			for _, date := range dates {
+				if math.IsNaN(cube.value(date, i, pixel)) {
+					missingDates = append(missingDates, date)
+				} else {
+					knownDates = append(knownDates, date)
+				}
+			}
+			observed[i] = observed[i] || len(knownDates) > 0
+			if len(knownDates) == 0 || len(missingDates) == 0 {
+				continue
+			}
+
+			observedValue := func(date int) float64 { return cube.value(date, i, pixel) }
+			for _, date := range missingDates {
+				cube.setValue(date, i, pixel, interpolateObserved(cube.Dates, knownDates, observedValue, date))
+				if cube.provenanceOf(date, pixel) == ProvenanceObserved {
+					cube.setEstimated(date, pixel, ProvenanceTemporal)
+				}
+			}
+		}
+	}
+
+	var unobserved []string
+	for i, name := range cube.Indexes {
+		if !observed[i] {
+			unobserved = append(unobserved, name)
+		}
+	}
+	if len(unobserved) > 0 && len(cube.Dates) > 0 {
+		fmt.Printf("No image of the plot holds %s, leaving them out\n", strings.Join(unobserved, ", "))
+	}
+	for _, name := range unobserved {
+		cube.removeIndex(cube.IndexPosition(name))
+	}
+}
+
+// interpolateObserved returns the value at date from the values observed on knownDates
+// (positions in dates, sorted ascending), holding the first and last values beyond the
+// observed range.
+func interpolateObserved(dates []time.Time, knownDates []int, observed func(date int) float64, date int) float64 {
+	first, last := knownDates[0], knownDates[len(knownDates)-1]
+	if date <= first {
+		return observed(first)
+	}
+	if date >= last {
+		return observed(last)
+	}
+	for i := 1; i < len(knownDates); i++ {
+		if knownDates[i] == date {
+			return observed(date)
+		}
+		if knownDates[i] > date {
+			before, after := knownDates[i-1], knownDates[i]
+			weight := dates[date].Sub(dates[before]).Hours() / dates[after].Sub(dates[before]).Hours()
+			return observed(before) + (observed(after)-observed(before))*weight
+		}
+	}
+	return observed(last)
+}
@@ -22,8 +22,10 @@ const (
 )
 
 type ClearAndSmoothRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Data          map[string]*DoubleList `protobuf:"bytes,1,rep,name=data,proto3" json:"data,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Data  map[string]*DoubleList `protobuf:"bytes,1,rep,name=data,proto3" json:"data,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// Smoothing penalty; 0 keeps the service default of 0.1
+	Lam           float64 `protobuf:"fixed64,2,opt,name=lam,proto3" json:"lam,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -65,6 +67,13 @@ func (x *ClearAndSmoothRequest) GetData() map[string]*DoubleList {
 	return nil
 }
 
+func (x *ClearAndSmoothRequest) GetLam() float64 {
+	if x != nil {
+		return x.Lam
+	}
+	return 0
+}
+
 type ClearAndSmoothResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	SmoothedData  map[string]*DoubleList `protobuf:"bytes,1,rep,name=smoothed_data,json=smoothedData,proto3" json:"smoothed_data,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
@@ -153,13 +162,303 @@ func (x *DoubleList) GetValues() []float64 {
 	return nil
 }
 
+type SmoothingParameters struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Smoothing penalty; 0 keeps the service default of 0.1
+	Lam float64 `protobuf:"fixed64,1,opt,name=lam,proto3" json:"lam,omitempty"`
+	// Dates on each side an outlier is compared with; 0 keeps the service default of 10
+	Window int32 `protobuf:"varint,2,opt,name=window,proto3" json:"window,omitempty"`
+	// Share of the window standard deviation beyond which a value is an outlier; 0 keeps the
+	// service default of 0.02
+	Threshold     float64 `protobuf:"fixed64,3,opt,name=threshold,proto3" json:"threshold,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SmoothingParameters) Reset() {
+	*x = SmoothingParameters{}
+	mi := &file_internal_delta_clear_and_smooth_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SmoothingParameters) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SmoothingParameters) ProtoMessage() {}
+
+func (x *SmoothingParameters) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_delta_clear_and_smooth_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SmoothingParameters.ProtoReflect.Descriptor instead.
+func (*SmoothingParameters) Descriptor() ([]byte, []int) {
+	return file_internal_delta_clear_and_smooth_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *SmoothingParameters) GetLam() float64 {
+	if x != nil {
+		return x.Lam
+	}
+	return 0
+}
+
+func (x *SmoothingParameters) GetWindow() int32 {
+	if x != nil {
+		return x.Window
+	}
+	return 0
+}
+
+func (x *SmoothingParameters) GetThreshold() float64 {
+	if x != nil {
+		return x.Threshold
+	}
+	return 0
+}
+
+type PixelSeries struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	X             int32                  `protobuf:"varint,1,opt,name=x,proto3" json:"x,omitempty"`
+	Y             int32                  `protobuf:"varint,2,opt,name=y,proto3" json:"y,omitempty"`
+	Data          map[string]*DoubleList `protobuf:"bytes,3,rep,name=data,proto3" json:"data,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PixelSeries) Reset() {
+	*x = PixelSeries{}
+	mi := &file_internal_delta_clear_and_smooth_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PixelSeries) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PixelSeries) ProtoMessage() {}
+
+func (x *PixelSeries) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_delta_clear_and_smooth_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PixelSeries.ProtoReflect.Descriptor instead.
+func (*PixelSeries) Descriptor() ([]byte, []int) {
+	return file_internal_delta_clear_and_smooth_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *PixelSeries) GetX() int32 {
+	if x != nil {
+		return x.X
+	}
+	return 0
+}
+
+func (x *PixelSeries) GetY() int32 {
+	if x != nil {
+		return x.Y
+	}
+	return 0
+}
+
+func (x *PixelSeries) GetData() map[string]*DoubleList {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+type ClearAndSmoothBatchRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Parameters    *SmoothingParameters   `protobuf:"bytes,1,opt,name=parameters,proto3" json:"parameters,omitempty"`
+	Pixels        []*PixelSeries         `protobuf:"bytes,2,rep,name=pixels,proto3" json:"pixels,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ClearAndSmoothBatchRequest) Reset() {
+	*x = ClearAndSmoothBatchRequest{}
+	mi := &file_internal_delta_clear_and_smooth_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ClearAndSmoothBatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ClearAndSmoothBatchRequest) ProtoMessage() {}
+
+func (x *ClearAndSmoothBatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_delta_clear_and_smooth_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ClearAndSmoothBatchRequest.ProtoReflect.Descriptor instead.
+func (*ClearAndSmoothBatchRequest) Descriptor() ([]byte, []int) {
+	return file_internal_delta_clear_and_smooth_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ClearAndSmoothBatchRequest) GetParameters() *SmoothingParameters {
+	if x != nil {
+		return x.Parameters
+	}
+	return nil
+}
+
+func (x *ClearAndSmoothBatchRequest) GetPixels() []*PixelSeries {
+	if x != nil {
+		return x.Pixels
+	}
+	return nil
+}
+
+type SmoothedPixel struct {
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	X            int32                  `protobuf:"varint,1,opt,name=x,proto3" json:"x,omitempty"`
+	Y            int32                  `protobuf:"varint,2,opt,name=y,proto3" json:"y,omitempty"`
+	SmoothedData map[string]*DoubleList `protobuf:"bytes,3,rep,name=smoothed_data,json=smoothedData,proto3" json:"smoothed_data,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// Set when the series of the pixel could not be smoothed
+	Error         string `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SmoothedPixel) Reset() {
+	*x = SmoothedPixel{}
+	mi := &file_internal_delta_clear_and_smooth_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SmoothedPixel) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SmoothedPixel) ProtoMessage() {}
+
+func (x *SmoothedPixel) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_delta_clear_and_smooth_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SmoothedPixel.ProtoReflect.Descriptor instead.
+func (*SmoothedPixel) Descriptor() ([]byte, []int) {
+	return file_internal_delta_clear_and_smooth_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *SmoothedPixel) GetX() int32 {
+	if x != nil {
+		return x.X
+	}
+	return 0
+}
+
+func (x *SmoothedPixel) GetY() int32 {
+	if x != nil {
+		return x.Y
+	}
+	return 0
+}
+
+func (x *SmoothedPixel) GetSmoothedData() map[string]*DoubleList {
+	if x != nil {
+		return x.SmoothedData
+	}
+	return nil
+}
+
+func (x *SmoothedPixel) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type ClearAndSmoothBatchResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Pixels        []*SmoothedPixel       `protobuf:"bytes,1,rep,name=pixels,proto3" json:"pixels,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ClearAndSmoothBatchResponse) Reset() {
+	*x = ClearAndSmoothBatchResponse{}
+	mi := &file_internal_delta_clear_and_smooth_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ClearAndSmoothBatchResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ClearAndSmoothBatchResponse) ProtoMessage() {}
+
+func (x *ClearAndSmoothBatchResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_delta_clear_and_smooth_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ClearAndSmoothBatchResponse.ProtoReflect.Descriptor instead.
+func (*ClearAndSmoothBatchResponse) Descriptor() ([]byte, []int) {
+	return file_internal_delta_clear_and_smooth_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ClearAndSmoothBatchResponse) GetPixels() []*SmoothedPixel {
+	if x != nil {
+		return x.Pixels
+	}
+	return nil
+}
+
 var File_internal_delta_clear_and_smooth_proto protoreflect.FileDescriptor
 
 const file_internal_delta_clear_and_smooth_proto_rawDesc = "" +
 	"\n" +
-	"%internal/delta/clear_and_smooth.proto\"\x93\x01\n" +
+	"%internal/delta/clear_and_smooth.proto\"\xa5\x01\n" +
 	"\x15ClearAndSmoothRequest\x124\n" +
-	"\x04data\x18\x01 \x03(\v2 .ClearAndSmoothRequest.DataEntryR\x04data\x1aD\n" +
+	"\x04data\x18\x01 \x03(\v2 .ClearAndSmoothRequest.DataEntryR\x04data\x12\x10\n" +
+	"\x03lam\x18\x02 \x01(\x01R\x03lam\x1aD\n" +
 	"\tDataEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12!\n" +
 	"\x05value\x18\x02 \x01(\v2\v.DoubleListR\x05value:\x028\x01\"\xb6\x01\n" +
@@ -170,9 +469,36 @@ const file_internal_delta_clear_and_smooth_proto_rawDesc = "" +
 	"\x05value\x18\x02 \x01(\v2\v.DoubleListR\x05value:\x028\x01\"$\n" +
 	"\n" +
 	"DoubleList\x12\x16\n" +
-	"\x06values\x18\x01 \x03(\x01R\x06values2Z\n" +
+	"\x06values\x18\x01 \x03(\x01R\x06values\"]\n" +
+	"\x13SmoothingParameters\x12\x10\n" +
+	"\x03lam\x18\x01 \x01(\x01R\x03lam\x12\x16\n" +
+	"\x06window\x18\x02 \x01(\x05R\x06window\x12\x1c\n" +
+	"\tthreshold\x18\x03 \x01(\x01R\tthreshold\"\x9b\x01\n" +
+	"\vPixelSeries\x12\f\n" +
+	"\x01x\x18\x01 \x01(\x05R\x01x\x12\f\n" +
+	"\x01y\x18\x02 \x01(\x05R\x01y\x12*\n" +
+	"\x04data\x18\x03 \x03(\v2\x16.PixelSeries.DataEntryR\x04data\x1aD\n" +
+	"\tDataEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12!\n" +
+	"\x05value\x18\x02 \x01(\v2\v.DoubleListR\x05value:\x028\x01\"x\n" +
+	"\x1aClearAndSmoothBatchRequest\x124\n" +
+	"\n" +
+	"parameters\x18\x01 \x01(\v2\x14.SmoothingParametersR\n" +
+	"parameters\x12$\n" +
+	"\x06pixels\x18\x02 \x03(\v2\f.PixelSeriesR\x06pixels\"\xd6\x01\n" +
+	"\rSmoothedPixel\x12\f\n" +
+	"\x01x\x18\x01 \x01(\x05R\x01x\x12\f\n" +
+	"\x01y\x18\x02 \x01(\x05R\x01y\x12E\n" +
+	"\rsmoothed_data\x18\x03 \x03(\v2 .SmoothedPixel.SmoothedDataEntryR\fsmoothedData\x12\x14\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error\x1aL\n" +
+	"\x11SmoothedDataEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12!\n" +
+	"\x05value\x18\x02 \x01(\v2\v.DoubleListR\x05value:\x028\x01\"E\n" +
+	"\x1bClearAndSmoothBatchResponse\x12&\n" +
+	"\x06pixels\x18\x01 \x03(\v2\x0e.SmoothedPixelR\x06pixels2\xb0\x01\n" +
 	"\x15ClearAndSmoothService\x12A\n" +
-	"\x0eClearAndSmooth\x12\x16.ClearAndSmoothRequest\x1a\x17.ClearAndSmoothResponseBWZUgithub.com/forest-guardian/forest-guardian-api-poc/internal/delta/protobufs;protobufsb\x06proto3"
+	"\x0eClearAndSmooth\x12\x16.ClearAndSmoothRequest\x1a\x17.ClearAndSmoothResponse\x12T\n" +
+	"\x13ClearAndSmoothBatch\x12\x1b.ClearAndSmoothBatchRequest\x1a\x1c.ClearAndSmoothBatchResponse(\x010\x01BWZUgithub.com/forest-guardian/forest-guardian-api-poc/internal/delta/protobufs;protobufsb\x06proto3"
 
 var (
 	file_internal_delta_clear_and_smooth_proto_rawDescOnce sync.Once
@@ -186,26 +512,42 @@ func file_internal_delta_clear_and_smooth_proto_rawDescGZIP() []byte {
 	return file_internal_delta_clear_and_smooth_proto_rawDescData
 }
 
-var file_internal_delta_clear_and_smooth_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_internal_delta_clear_and_smooth_proto_msgTypes = make([]protoimpl.MessageInfo, 12)
 var file_internal_delta_clear_and_smooth_proto_goTypes = []any{
-	(*ClearAndSmoothRequest)(nil),  // 0: ClearAndSmoothRequest
-	(*ClearAndSmoothResponse)(nil), // 1: ClearAndSmoothResponse
-	(*DoubleList)(nil),             // 2: DoubleList
-	nil,                            // 3: ClearAndSmoothRequest.DataEntry
-	nil,                            // 4: ClearAndSmoothResponse.SmoothedDataEntry
+	(*ClearAndSmoothRequest)(nil),       // 0: ClearAndSmoothRequest
+	(*ClearAndSmoothResponse)(nil),      // 1: ClearAndSmoothResponse
+	(*DoubleList)(nil),                  // 2: DoubleList
+	(*SmoothingParameters)(nil),         // 3: SmoothingParameters
+	(*PixelSeries)(nil),                 // 4: PixelSeries
+	(*ClearAndSmoothBatchRequest)(nil),  // 5: ClearAndSmoothBatchRequest
+	(*SmoothedPixel)(nil),               // 6: SmoothedPixel
+	(*ClearAndSmoothBatchResponse)(nil), // 7: ClearAndSmoothBatchResponse
+	nil,                                 // 8: ClearAndSmoothRequest.DataEntry
+	nil,                                 // 9: ClearAndSmoothResponse.SmoothedDataEntry
+	nil,                                 // 10: PixelSeries.DataEntry
+	nil,                                 // 11: SmoothedPixel.SmoothedDataEntry
 }
 var file_internal_delta_clear_and_smooth_proto_depIdxs = []int32{
-	3, // 0: ClearAndSmoothRequest.data:type_name -> ClearAndSmoothRequest.DataEntry
-	4, // 1: ClearAndSmoothResponse.smoothed_data:type_name -> ClearAndSmoothResponse.SmoothedDataEntry
-	2, // 2: ClearAndSmoothRequest.DataEntry.value:type_name -> DoubleList
-	2, // 3: ClearAndSmoothResponse.SmoothedDataEntry.value:type_name -> DoubleList
-	0, // 4: ClearAndSmoothService.ClearAndSmooth:input_type -> ClearAndSmoothRequest
-	1, // 5: ClearAndSmoothService.ClearAndSmooth:output_type -> ClearAndSmoothResponse
-	5, // [5:6] is the sub-list for method output_type
-	4, // [4:5] is the sub-list for method input_type
-	4, // [4:4] is the sub-list for extension type_name
-	4, // [4:4] is the sub-list for extension extendee
-	0, // [0:4] is the sub-list for field type_name
+	8,  // 0: ClearAndSmoothRequest.data:type_name -> ClearAndSmoothRequest.DataEntry
+	9,  // 1: ClearAndSmoothResponse.smoothed_data:type_name -> ClearAndSmoothResponse.SmoothedDataEntry
+	10, // 2: PixelSeries.data:type_name -> PixelSeries.DataEntry
+	3,  // 3: ClearAndSmoothBatchRequest.parameters:type_name -> SmoothingParameters
+	4,  // 4: ClearAndSmoothBatchRequest.pixels:type_name -> PixelSeries
+	11, // 5: SmoothedPixel.smoothed_data:type_name -> SmoothedPixel.SmoothedDataEntry
+	6,  // 6: ClearAndSmoothBatchResponse.pixels:type_name -> SmoothedPixel
+	2,  // 7: ClearAndSmoothRequest.DataEntry.value:type_name -> DoubleList
+	2,  // 8: ClearAndSmoothResponse.SmoothedDataEntry.value:type_name -> DoubleList
+	2,  // 9: PixelSeries.DataEntry.value:type_name -> DoubleList
+	2,  // 10: SmoothedPixel.SmoothedDataEntry.value:type_name -> DoubleList
+	0,  // 11: ClearAndSmoothService.ClearAndSmooth:input_type -> ClearAndSmoothRequest
+	5,  // 12: ClearAndSmoothService.ClearAndSmoothBatch:input_type -> ClearAndSmoothBatchRequest
+	1,  // 13: ClearAndSmoothService.ClearAndSmooth:output_type -> ClearAndSmoothResponse
+	7,  // 14: ClearAndSmoothService.ClearAndSmoothBatch:output_type -> ClearAndSmoothBatchResponse
+	13, // [13:15] is the sub-list for method output_type
+	11, // [11:13] is the sub-list for method input_type
+	11, // [11:11] is the sub-list for extension type_name
+	11, // [11:11] is the sub-list for extension extendee
+	0,  // [0:11] is the sub-list for field type_name
 }
 
 func init() { file_internal_delta_clear_and_smooth_proto_init() }
@@ -219,7 +561,7 @@ func file_internal_delta_clear_and_smooth_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_internal_delta_clear_and_smooth_proto_rawDesc), len(file_internal_delta_clear_and_smooth_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   5,
+			NumMessages:   12,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -19,7 +19,8 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	ClearAndSmoothService_ClearAndSmooth_FullMethodName = "/ClearAndSmoothService/ClearAndSmooth"
+	ClearAndSmoothService_ClearAndSmooth_FullMethodName      = "/ClearAndSmoothService/ClearAndSmooth"
+	ClearAndSmoothService_ClearAndSmoothBatch_FullMethodName = "/ClearAndSmoothService/ClearAndSmoothBatch"
 )
 
 // ClearAndSmoothServiceClient is the client API for ClearAndSmoothService service.
@@ -27,6 +28,9 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type ClearAndSmoothServiceClient interface {
 	ClearAndSmooth(ctx context.Context, in *ClearAndSmoothRequest, opts ...grpc.CallOption) (*ClearAndSmoothResponse, error)
+	// Smooths the pixels of a plot streamed in batches; every request batch is answered by one
+	// response batch, and a pixel that fails carries its error instead of failing the stream
+	ClearAndSmoothBatch(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[ClearAndSmoothBatchRequest, ClearAndSmoothBatchResponse], error)
 }
 
 type clearAndSmoothServiceClient struct {
@@ -47,11 +51,27 @@ func (c *clearAndSmoothServiceClient) ClearAndSmooth(ctx context.Context, in *Cl
 	return out, nil
 }
 
+func (c *clearAndSmoothServiceClient) ClearAndSmoothBatch(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[ClearAndSmoothBatchRequest, ClearAndSmoothBatchResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ClearAndSmoothService_ServiceDesc.Streams[0], ClearAndSmoothService_ClearAndSmoothBatch_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ClearAndSmoothBatchRequest, ClearAndSmoothBatchResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ClearAndSmoothService_ClearAndSmoothBatchClient = grpc.BidiStreamingClient[ClearAndSmoothBatchRequest, ClearAndSmoothBatchResponse]
+
 // ClearAndSmoothServiceServer is the server API for ClearAndSmoothService service.
 // All implementations must embed UnimplementedClearAndSmoothServiceServer
 // for forward compatibility.
 type ClearAndSmoothServiceServer interface {
 	ClearAndSmooth(context.Context, *ClearAndSmoothRequest) (*ClearAndSmoothResponse, error)
+	// Smooths the pixels of a plot streamed in batches; every request batch is answered by one
+	// response batch, and a pixel that fails carries its error instead of failing the stream
+	ClearAndSmoothBatch(grpc.BidiStreamingServer[ClearAndSmoothBatchRequest, ClearAndSmoothBatchResponse]) error
 	mustEmbedUnimplementedClearAndSmoothServiceServer()
 }
 
@@ -65,6 +85,9 @@ type UnimplementedClearAndSmoothServiceServer struct{}
 func (UnimplementedClearAndSmoothServiceServer) ClearAndSmooth(context.Context, *ClearAndSmoothRequest) (*ClearAndSmoothResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ClearAndSmooth not implemented")
 }
+func (UnimplementedClearAndSmoothServiceServer) ClearAndSmoothBatch(grpc.BidiStreamingServer[ClearAndSmoothBatchRequest, ClearAndSmoothBatchResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method ClearAndSmoothBatch not implemented")
+}
 func (UnimplementedClearAndSmoothServiceServer) mustEmbedUnimplementedClearAndSmoothServiceServer() {}
 func (UnimplementedClearAndSmoothServiceServer) testEmbeddedByValue()                               {}
 
@@ -104,6 +127,13 @@ func _ClearAndSmoothService_ClearAndSmooth_Handler(srv interface{}, ctx context.
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ClearAndSmoothService_ClearAndSmoothBatch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ClearAndSmoothServiceServer).ClearAndSmoothBatch(&grpc.GenericServerStream[ClearAndSmoothBatchRequest, ClearAndSmoothBatchResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ClearAndSmoothService_ClearAndSmoothBatchServer = grpc.BidiStreamingServer[ClearAndSmoothBatchRequest, ClearAndSmoothBatchResponse]
+
 // ClearAndSmoothService_ServiceDesc is the grpc.ServiceDesc for ClearAndSmoothService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -116,6 +146,13 @@ var ClearAndSmoothService_ServiceDesc = grpc.ServiceDesc{
 			Handler:    _ClearAndSmoothService_ClearAndSmooth_Handler,
 		},
 	},
-	Streams:  []grpc.StreamDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ClearAndSmoothBatch",
+			Handler:       _ClearAndSmoothService_ClearAndSmoothBatch_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
 	Metadata: "internal/delta/clear_and_smooth.proto",
 }
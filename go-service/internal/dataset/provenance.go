@@ -0,0 +1,106 @@
+package dataset
+
+import (
+	"fmt"
+	"math"
+)
+
+// Provenance tells whether the values of a pixel on a date were observed, or estimated by
+// gap filling and from what.
+type Provenance uint8
+
+const (
+	ProvenanceObserved Provenance = iota
+	// ProvenanceTemporal values were estimated from the pixel's own observations on other dates
+	ProvenanceTemporal
+	// ProvenanceSpatial values were estimated from the pixels around it
+	ProvenanceSpatial
+)
+
+func (p Provenance) String() string {
+	switch p {
+	case ProvenanceTemporal:
+		return "estimated-temporal"
+	case ProvenanceSpatial:
+		return "estimated-spatial"
+	default:
+		return "observed"
+	}
+}
+
+// ParseProvenance reads a provenance written by Provenance.String. An empty string, from rows
+// written before provenance was recorded, reads as observed.
+func ParseProvenance(text string) (Provenance, error) {
+	for _, provenance := range []Provenance{ProvenanceObserved, ProvenanceTemporal, ProvenanceSpatial} {
+		if text == provenance.String() {
+			return provenance, nil
+		}
+	}
+	if text == "" {
+		return ProvenanceObserved, nil
+	}
+	return ProvenanceObserved, fmt.Errorf("unknown provenance %q", text)
+}
+
+func (p Provenance) MarshalCSV() (string, error) {
+	return p.String(), nil
+}
+
+func (p *Provenance) UnmarshalCSV(text string) error {
+	provenance, err := ParseProvenance(text)
+	if err != nil {
+		return err
+	}
+	*p = provenance
+	return nil
+}
+
+func (c *Cube) provenanceOf(date, pixel int) Provenance {
+	return c.provenance[date*c.plane()+pixel]
+}
+
+// setEstimated makes a pixel valid on a date with values estimated from provenance.
+func (c *Cube) setEstimated(date, pixel int, provenance Provenance) {
+	c.setState(date, pixel, stateValid)
+	c.provenance[date*c.plane()+pixel] = provenance
+}
+
+// recordGapDays stores, for every valid pixel and date, the days to the nearest date on which
+// the pixel was observed, or -1 if it never was. It runs once gaps are filled, before cleaning
+// drops dates and pixels.
+func (c *Cube) recordGapDays() {
+	for pixel := range c.plane() {
+		observed := func(date int) bool {
+			return c.state(date, pixel) == stateValid && c.provenanceOf(date, pixel) == ProvenanceObserved
+		}
+		// previous[date] is the latest observation up to date, or -1
+		previous := make([]int, len(c.Dates))
+		last := -1
+		for date := range c.Dates {
+			if observed(date) {
+				last = date
+			}
+			previous[date] = last
+		}
+		next := -1
+		for date := len(c.Dates) - 1; date >= 0; date-- {
+			if observed(date) {
+				next = date
+			}
+			if c.state(date, pixel) != stateValid {
+				continue
+			}
+			gap := math.Inf(1)
+			for _, observation := range []int{previous[date], next} {
+				if observation >= 0 {
+					gap = math.Min(gap, math.Abs(c.Dates[date].Sub(c.Dates[observation]).Hours()/24))
+				}
+			}
+			days := int32(-1)
+			if !math.IsInf(gap, 1) {
+				days = int32(math.Round(gap))
+			}
+			c.gapDays[date*c.plane()+pixel] = days
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package dataset
+
+import (
+	"math"
+
+	"github.com/forest-guardian/forest-guardian-api-poc/internal/sentinel"
+)
+
+// sarObservations holds a copy of each radar feature plane, flattened as [date][y][x], keyed
+// by index name.
+type sarObservations map[string][]float32
+
+// observedSARFeatures copies the radar features of every pixel before the optical
+// estimation rewrites them. Missing acquisitions stay NaN.
+func observedSARFeatures(cube *Cube) sarObservations {
+	observed := make(sarObservations)
+	plane := cube.plane()
+	for i, name := range cube.Indexes {
+		if !sentinel.IsSARIndex(name) {
+			continue
+		}
+		values := make([]float32, len(cube.Dates)*plane)
+		for date := range cube.Dates {
+			start := cube.offset(date, i, 0)
+			copy(values[date*plane:(date+1)*plane], cube.Values[start:start+plane])
+		}
+		observed[name] = values
+	}
+	return observed
+}
+
+// restoreSARFeatures puts the observed radar features back on the estimated pixels, as radar
+// sees through the clouds that made the optical values estimates. Dates without a radar
+// acquisition are interpolated linearly in time from the surrounding ones. Features no
+// pixel observed are dropped.
+func restoreSARFeatures(cube *Cube, observed sarObservations) {
+	plane := cube.plane()
+	var unobserved []string
+	for name, values := range observed {
+		i := cube.IndexPosition(name)
+		anyObserved := false
+		for pixel := range plane {
+			var knownDates []int
+			for date := range cube.Dates {
+				if !math.IsNaN(float64(values[date*plane+pixel])) {
+					knownDates = append(knownDates, date)
+				}
+			}
+			anyObserved = anyObserved || len(knownDates) > 0
+
+			observedValue := func(date int) float64 { return float64(values[date*plane+pixel]) }
+			for _, date := range cube.validDates(pixel) {
+				value := math.NaN()
+				if len(knownDates) > 0 {
+					value = interpolateObserved(cube.Dates, knownDates, observedValue, date)
+				}
+				cube.setValue(date, i, pixel, value)
+			}
+		}
+		if !anyObserved {
+			unobserved = append(unobserved, name)
+		}
+	}
+	for _, name := range unobserved {
+		cube.removeIndex(cube.IndexPosition(name))
+	}
+}
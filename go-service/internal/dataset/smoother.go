@@ -0,0 +1,293 @@
+package dataset
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/forest-guardian/forest-guardian-api-poc/internal/config"
+	pb "github.com/forest-guardian/forest-guardian-api-poc/internal/dataset/protobufs"
+	"github.com/forest-guardian/forest-guardian-api-poc/internal/properties"
+	"github.com/forest-guardian/forest-guardian-api-poc/internal/smoothing"
+	"github.com/gammazero/workerpool"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// PixelSeries holds the index series of a pixel on the dates it is valid, keyed by index name.
+type PixelSeries struct {
+	X, Y   int
+	Values map[string][]float64
+}
+
+// SmoothedSeries is the outcome of smoothing a PixelSeries: its smoothed series, or the error
+// that kept it from being smoothed.
+type SmoothedSeries struct {
+	X, Y   int
+	Values map[string][]float64
+	Err    error
+}
+
+// Smoother clears the outliers of the index series of the pixels of a plot and smooths them.
+type Smoother interface {
+	// ClearAndSmooth calls handle with the outcome of every series, in any order but from one
+	// goroutine at a time. A series that cannot be smoothed is reported through its outcome; the
+	// returned error is kept for failures of the smoother itself.
+	ClearAndSmooth(series []PixelSeries, handle func(SmoothedSeries)) error
+	// Name identifies the backend that smooths, "go" or "python", as the auto backend resolves
+	Name() string
+	Close() error
+}
+
+// NewSmoother returns the smoother selected by the smoothing configuration.
+func NewSmoother() (Smoother, error) {
+	cfg, err := config.Get()
+	if err != nil {
+		return nil, err
+	}
+	parameters, err := smoothingParameters()
+	if err != nil {
+		return nil, err
+	}
+
+	switch cfg.Smoothing.Backend {
+	case "go":
+		return GoSmoother{Parameters: parameters}, nil
+	case "python":
+		return NewGRPCSmoother(parameters)
+	case "", "auto":
+		if pythonServiceAvailable() {
+			return NewGRPCSmoother(parameters)
+		}
+		fmt.Printf("Python service not reachable on port %d, smoothing in Go\n", properties.GrpcPort)
+		return GoSmoother{Parameters: parameters}, nil
+	default:
+		return nil, fmt.Errorf("unknown smoothing backend %q in %s: use \"auto\", \"go\" or \"python\"", cfg.Smoothing.Backend, config.Path())
+	}
+}
+
+// smoothingParameters returns the configured smoothing parameters, the defaults of the
+// Python service filling those left unset.
+func smoothingParameters() (smoothing.Parameters, error) {
+	cfg, err := config.Get()
+	if err != nil {
+		return smoothing.Parameters{}, err
+	}
+	if cfg.Smoothing.Lambda < 0 || cfg.Smoothing.Window < 0 || cfg.Smoothing.Threshold < 0 {
+		return smoothing.Parameters{}, fmt.Errorf("smoothing lambda, window and threshold must not be negative in %s", config.Path())
+	}
+	parameters := smoothing.DefaultParameters
+	if cfg.Smoothing.Lambda != 0 {
+		parameters.Lambda = cfg.Smoothing.Lambda
+	}
+	if cfg.Smoothing.Window != 0 {
+		parameters.Window = cfg.Smoothing.Window
+	}
+	if cfg.Smoothing.Threshold != 0 {
+		parameters.Threshold = cfg.Smoothing.Threshold
+	}
+	return parameters, nil
+}
+
+// pythonServiceAvailable reports whether the Python service accepts connections.
+func pythonServiceAvailable() bool {
+	conn, err := net.DialTimeout("tcp", fmt.Sprintf("localhost:%d", properties.GrpcPort), 2*time.Second)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// GoSmoother smooths in process, with the algorithm of the Python service.
+type GoSmoother struct {
+	Parameters smoothing.Parameters
+}
+
+func (s GoSmoother) ClearAndSmooth(series []PixelSeries, handle func(SmoothedSeries)) error {
+	var mu sync.Mutex
+	wp := workerpool.New(runtime.NumCPU())
+	for _, pixel := range series {
+		wp.Submit(func() {
+			result := s.smooth(pixel)
+			mu.Lock()
+			defer mu.Unlock()
+			handle(result)
+		})
+	}
+	wp.StopWait()
+	return nil
+}
+
+func (s GoSmoother) smooth(pixel PixelSeries) SmoothedSeries {
+	result := SmoothedSeries{X: pixel.X, Y: pixel.Y, Values: make(map[string][]float64, len(pixel.Values))}
+	for name, values := range pixel.Values {
+		smoothed, err := smoothing.ClearAndSmooth(values, s.Parameters)
+		if err != nil {
+			return SmoothedSeries{X: pixel.X, Y: pixel.Y, Err: fmt.Errorf("failed to smooth %s: %v", name, err)}
+		}
+		result.Values[name] = smoothed
+	}
+	return result
+}
+
+func (GoSmoother) Name() string {
+	return "go"
+}
+
+func (s GoSmoother) Close() error {
+	return nil
+}
+
+const (
+	// smoothingBatchSize is the number of pixels sent in one message of a smoothing stream
+	smoothingBatchSize = 256
+	// smoothingStreams is the number of streams a plot is split across, so that the Python
+	// service smooths on several threads
+	smoothingStreams = 8
+)
+
+// GRPCSmoother streams the pixels of a plot to the ClearAndSmoothBatch service of the Python
+// service.
+type GRPCSmoother struct {
+	Parameters smoothing.Parameters
+	conn       *grpc.ClientConn
+}
+
+// NewGRPCSmoother connects to the Python service on properties.GrpcPort.
+func NewGRPCSmoother(parameters smoothing.Parameters) (*GRPCSmoother, error) {
+	conn, err := grpc.Dial(fmt.Sprintf("localhost:%d", properties.GrpcPort), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to gRPC server: %v", err)
+	}
+	return &GRPCSmoother{Parameters: parameters, conn: conn}, nil
+}
+
+func (s *GRPCSmoother) ClearAndSmooth(series []PixelSeries, handle func(SmoothedSeries)) error {
+	var (
+		mu        sync.Mutex
+		wg        sync.WaitGroup
+		errGlobal error
+	)
+	size := (len(series) + smoothingStreams - 1) / smoothingStreams
+	for start := 0; start < len(series); start += size {
+		part := series[start:min(start+size, len(series))]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := s.stream(part, func(result SmoothedSeries) {
+				mu.Lock()
+				defer mu.Unlock()
+				handle(result)
+			})
+			if err != nil {
+				mu.Lock()
+				defer mu.Unlock()
+				if errGlobal == nil {
+					errGlobal = err
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return errGlobal
+}
+
+// stream smooths series over one ClearAndSmoothBatch stream, sending batches while the
+// answers of the previous ones are received.
+func (s *GRPCSmoother) stream(series []PixelSeries, handle func(SmoothedSeries)) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	// Cancelling also unblocks the sender when receiving fails
+	defer cancel()
+	stream, err := pb.NewClearAndSmoothServiceClient(s.conn).ClearAndSmoothBatch(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open smoothing stream: %v", err)
+	}
+
+	sendErr := make(chan error, 1)
+	go func() {
+		sendErr <- s.send(stream, series)
+	}()
+
+	received := 0
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to receive smoothed pixels: %v", err)
+		}
+		for _, pixel := range resp.Pixels {
+			result := SmoothedSeries{X: int(pixel.X), Y: int(pixel.Y)}
+			if pixel.Error != "" {
+				result.Err = errors.New(pixel.Error)
+			} else {
+				result.Values = convertFromProtobufList(pixel.SmoothedData)
+			}
+			handle(result)
+			received++
+		}
+	}
+	if err := <-sendErr; err != nil {
+		return err
+	}
+	if received != len(series) {
+		return fmt.Errorf("smoothing service answered %d of %d pixels", received, len(series))
+	}
+	return nil
+}
+
+func (s *GRPCSmoother) send(stream pb.ClearAndSmoothService_ClearAndSmoothBatchClient, series []PixelSeries) error {
+	parameters := &pb.SmoothingParameters{
+		Lam:       s.Parameters.Lambda,
+		Window:    int32(s.Parameters.Window),
+		Threshold: s.Parameters.Threshold,
+	}
+	for start := 0; start < len(series); start += smoothingBatchSize {
+		batch := series[start:min(start+smoothingBatchSize, len(series))]
+		req := &pb.ClearAndSmoothBatchRequest{Parameters: parameters, Pixels: make([]*pb.PixelSeries, len(batch))}
+		for i, pixel := range batch {
+			req.Pixels[i] = &pb.PixelSeries{X: int32(pixel.X), Y: int32(pixel.Y), Data: convertToProtobufList(pixel.Values)}
+		}
+		if err := stream.Send(req); err != nil {
+			// io.EOF means the service ended the stream, whose status Recv reports
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("failed to send pixels to smooth: %v", err)
+		}
+	}
+	return stream.CloseSend()
+}
+
+func (*GRPCSmoother) Name() string {
+	return "python"
+}
+
+func (s *GRPCSmoother) Close() error {
+	return s.conn.Close()
+}
+
+func convertToProtobufList(data map[string][]float64) map[string]*pb.DoubleList {
+	result := make(map[string]*pb.DoubleList)
+	for key, values := range data {
+		result[key] = &pb.DoubleList{
+			Values: values,
+		}
+	}
+	return result
+}
+
+func convertFromProtobufList(data map[string]*pb.DoubleList) map[string][]float64 {
+	result := make(map[string][]float64)
+	for key, doubleList := range data {
+		result[key] = doubleList.Values
+	}
+	return result
+}
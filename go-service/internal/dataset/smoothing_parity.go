@@ -0,0 +1,106 @@
+package dataset
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/forest-guardian/forest-guardian-api-poc/internal/properties"
+	"github.com/forest-guardian/forest-guardian-api-poc/internal/smoothing"
+	"github.com/schollz/progressbar/v3"
+)
+
+// SmoothingParity compares the Go and Python smoothers on the series of a pixel dataset.
+type SmoothingParity struct {
+	Pixels int
+	// MaxDiff and MeanDiff are the largest and mean absolute differences, keyed by index name
+	MaxDiff  map[string]float64
+	MeanDiff map[string]float64
+	// Failed counts the pixels neither smoother could smooth, Disagreements those only one could
+	Failed        int
+	Disagreements int
+}
+
+// Matches reports whether both smoothers failed on the same pixels and every index is within
+// smoothing.ParityTolerance.
+func (p SmoothingParity) Matches() bool {
+	if p.Disagreements > 0 {
+		return false
+	}
+	for _, diff := range p.MaxDiff {
+		if !(diff <= smoothing.ParityTolerance) {
+			return false
+		}
+	}
+	return true
+}
+
+// add records the differences between the series of one pixel.
+func (p *SmoothingParity) add(expected, actual map[string][]float64, counts map[string]int) {
+	p.Pixels++
+	for name, series := range expected {
+		for i, value := range series {
+			diff := math.Abs(actual[name][i] - value)
+			p.MaxDiff[name] = math.Max(p.MaxDiff[name], diff)
+			p.MeanDiff[name] += diff
+			counts[name]++
+		}
+	}
+}
+
+// CompareSmoothers prepares the pixel dataset as CreateCleanDataset does and smooths the
+// series of every pixel with both the Go smoother and the Python service, using the
+// configured parameters. The dataset is modified in place.
+func CompareSmoothers(data *Cube) (SmoothingParity, error) {
+	parameters, err := smoothingParameters()
+	if err != nil {
+		return SmoothingParity{}, err
+	}
+	if !pythonServiceAvailable() {
+		return SmoothingParity{}, fmt.Errorf("python service not reachable on port %d", properties.GrpcPort)
+	}
+	python, err := NewGRPCSmoother(parameters)
+	if err != nil {
+		return SmoothingParity{}, err
+	}
+	defer python.Close()
+	native := GoSmoother{Parameters: parameters}
+	filler, err := NewGapFiller()
+	if err != nil {
+		return SmoothingParity{}, err
+	}
+
+	prepareCleaning(data, filler)
+	series, _ := plotSeries(data)
+
+	progressBar := progressbar.Default(int64(2*len(series)), "Comparing smoothers")
+	results := make([]map[[2]int]SmoothedSeries, 2)
+	for i, smoother := range []Smoother{python, native} {
+		results[i] = make(map[[2]int]SmoothedSeries, len(series))
+		err := smoother.ClearAndSmooth(series, func(result SmoothedSeries) {
+			results[i][[2]int{result.X, result.Y}] = result
+			progressBar.Add(1)
+		})
+		if err != nil {
+			return SmoothingParity{}, fmt.Errorf("error while comparing smoothers: %v", err)
+		}
+	}
+
+	parity := SmoothingParity{MaxDiff: make(map[string]float64), MeanDiff: make(map[string]float64)}
+	counts := make(map[string]int)
+	for _, pixel := range series {
+		key := [2]int{pixel.X, pixel.Y}
+		expected, actual := results[0][key], results[1][key]
+		switch {
+		case expected.Err != nil && actual.Err != nil:
+			parity.Failed++
+		case expected.Err != nil || actual.Err != nil:
+			parity.Disagreements++
+		default:
+			parity.add(expected.Values, actual.Values, counts)
+		}
+	}
+	for name, count := range counts {
+		parity.MeanDiff[name] /= float64(count)
+	}
+	return parity, nil
+}
@@ -0,0 +1,332 @@
+package dataset
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/forest-guardian/forest-guardian-api-poc/internal/properties"
+)
+
+// stageFormat versions the encoding of the stored stages; bumping it invalidates every key.
+const stageFormat = 2
+
+// Pipeline stages whose outputs are stored, in pipeline order
+const (
+	StagePixel = "pixel"
+	StageClean = "clean"
+	StageDelta = "delta"
+	StageFinal = "final"
+)
+
+var stageOrder = []string{StagePixel, StageClean, StageDelta, StageFinal}
+
+// StageEntry describes a stored stage output. It is saved as <key>.json beside the output, in
+// data/stages/<stage>.
+type StageEntry struct {
+	Key    string `json:"key"`
+	Stage  string `json:"stage"`
+	Forest string `json:"forest"`
+	Plot   string `json:"plot"`
+	// Parent is the key of the stored stage the output was computed from, "" for the first stage
+	Parent string `json:"parent,omitempty"`
+	// Parameters are the inputs the key was built from, besides the parent
+	Parameters json.RawMessage `json:"parameters"`
+	Summary    string          `json:"summary"`
+	File       string          `json:"file"`
+	Size       int64           `json:"size"`
+	CreatedAt  time.Time       `json:"created_at"`
+	LastUsedAt time.Time       `json:"last_used_at"`
+}
+
+func stagesPath() string {
+	return fmt.Sprintf("%s/data/stages", properties.RootPath())
+}
+
+func stageDir(stage string) string {
+	return filepath.Join(stagesPath(), stage)
+}
+
+func (entry StageEntry) entryPath() string {
+	return filepath.Join(stageDir(entry.Stage), entry.Key+".json")
+}
+
+func (entry StageEntry) dataPath() string {
+	return filepath.Join(stageDir(entry.Stage), entry.File)
+}
+
+// newStageEntry builds the entry of a stage output, keyed by the SHA-256 of the stage, the plot,
+// its parent and its parameters.
+func newStageEntry(stage, forest, plot, parent string, parameters any) (StageEntry, error) {
+	encoded, err := json.Marshal(parameters)
+	if err != nil {
+		return StageEntry{}, fmt.Errorf("failed to encode %s stage parameters: %v", stage, err)
+	}
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%d\n%s\n%s\n%s\n%s\n", stageFormat, stage, forest, plot, parent)
+	hash.Write(encoded)
+	return StageEntry{
+		Key:        hex.EncodeToString(hash.Sum(nil)),
+		Stage:      stage,
+		Forest:     forest,
+		Plot:       plot,
+		Parent:     parent,
+		Parameters: encoded,
+	}, nil
+}
+
+func readStageEntry(path string) (StageEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return StageEntry{}, err
+	}
+	var entry StageEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return StageEntry{}, fmt.Errorf("invalid JSON in %s: %v", path, err)
+	}
+	return entry, nil
+}
+
+// writeFileAtomically writes a file through a temporary file in the same folder, so a reader
+// never sees it half written.
+func writeFileAtomically(path string, write func(io.Writer) error) (int64, error) {
+	temp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.temp")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(temp.Name())
+	if err := write(temp); err != nil {
+		temp.Close()
+		return 0, err
+	}
+	info, err := temp.Stat()
+	if err != nil {
+		temp.Close()
+		return 0, err
+	}
+	if err := temp.Close(); err != nil {
+		return 0, err
+	}
+	return info.Size(), os.Rename(temp.Name(), path)
+}
+
+func (entry StageEntry) save() error {
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal stage entry: %v", err)
+	}
+	_, err = writeFileAtomically(entry.entryPath(), func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+	return err
+}
+
+// loadStage decodes the stored output of entry with decode and marks it used. It reports
+// false when the output is not stored or cannot be read, for the caller to compute it again.
+func loadStage(entry StageEntry, decode func(io.Reader) error) bool {
+	stored, err := readStageEntry(entry.entryPath())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			fmt.Printf("Ignoring stored %s stage %s: %v\n", entry.Stage, entry.Key, err)
+		}
+		return false
+	}
+	file, err := os.Open(stored.dataPath())
+	if err != nil {
+		fmt.Printf("Ignoring stored %s stage %s: %v\n", entry.Stage, entry.Key, err)
+		return false
+	}
+	defer file.Close()
+	if err := decode(file); err != nil {
+		fmt.Printf("Ignoring stored %s stage %s: %v\n", entry.Stage, entry.Key, err)
+		return false
+	}
+
+	stored.LastUsedAt = time.Now().UTC()
+	if err := stored.save(); err != nil {
+		fmt.Printf("failed to mark stored %s stage %s used: %v\n", entry.Stage, entry.Key, err)
+	}
+	fmt.Printf("Reusing stored %s stage %s (%s)\n", entry.Stage, shortStageKey(entry.Key), stored.Summary)
+	return true
+}
+
+// saveStage stores an output under entry, written by encode to a file with the extension.
+// Failures are printed rather than returned: the output is already computed, only its reuse
+// is lost.
+func saveStage(entry StageEntry, extension, summary string, encode func(io.Writer) error) {
+	if err := os.MkdirAll(stageDir(entry.Stage), os.ModePerm); err != nil {
+		fmt.Printf("failed to store %s stage: %v\n", entry.Stage, err)
+		return
+	}
+	entry.File = entry.Key + extension
+	size, err := writeFileAtomically(entry.dataPath(), encode)
+	if err != nil {
+		fmt.Printf("failed to store %s stage %s: %v\n", entry.Stage, entry.Key, err)
+		return
+	}
+	now := time.Now().UTC()
+	entry.Summary = summary
+	entry.Size = size
+	entry.CreatedAt = now
+	entry.LastUsedAt = now
+	if err := entry.save(); err != nil {
+		fmt.Printf("failed to store %s stage %s: %v\n", entry.Stage, entry.Key, err)
+	}
+}
+
+func shortStageKey(key string) string {
+	return key[:min(len(key), 12)]
+}
+
+// ListStages returns every stored stage, in pipeline order and then by forest, plot and
+// creation time.
+func ListStages() ([]StageEntry, error) {
+	var entries []StageEntry
+	for _, stage := range stageOrder {
+		paths, err := filepath.Glob(filepath.Join(stageDir(stage), "*.json"))
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range paths {
+			entry, err := readStageEntry(path)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, entry)
+		}
+	}
+	slices.SortStableFunc(entries, func(a, b StageEntry) int {
+		if order := slices.Index(stageOrder, a.Stage) - slices.Index(stageOrder, b.Stage); order != 0 {
+			return order
+		}
+		if a.Forest != b.Forest {
+			return strings.Compare(a.Forest, b.Forest)
+		}
+		if a.Plot != b.Plot {
+			return strings.Compare(a.Plot, b.Plot)
+		}
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return entries, nil
+}
+
+// FindStage returns the stored stage whose key starts with prefix, which must match one only.
+func FindStage(prefix string) (StageEntry, error) {
+	if prefix == "" {
+		return StageEntry{}, errors.New("empty stage key")
+	}
+	entries, err := ListStages()
+	if err != nil {
+		return StageEntry{}, err
+	}
+	var found []StageEntry
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Key, prefix) {
+			found = append(found, entry)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return StageEntry{}, fmt.Errorf("no stored stage has a key starting with %s", prefix)
+	case 1:
+		return found[0], nil
+	default:
+		return StageEntry{}, fmt.Errorf("%d stored stages have a key starting with %s", len(found), prefix)
+	}
+}
+
+// StageCollection reports what CollectStages removed.
+type StageCollection struct {
+	Removed int
+	Freed   int64
+}
+
+// stageWriteGrace is how long a file no entry refers to is kept, as another run may still be
+// writing it or be about to save its entry.
+const stageWriteGrace = time.Hour
+
+// CollectStages removes the stored stages not used within unusedFor, along with the files no
+// entry refers to and the entries whose output is missing, such as those left by an
+// interrupted run. Files modified within stageWriteGrace are kept.
+func CollectStages(unusedFor time.Duration) (StageCollection, error) {
+	var collection StageCollection
+	now := time.Now()
+	cutoff := now.Add(-unusedFor)
+	remove := func(path string) error {
+		info, err := os.Stat(path)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("failed to delete %s: %v", path, err)
+		}
+		collection.Freed += info.Size()
+		return nil
+	}
+
+	for _, stage := range stageOrder {
+		files, err := os.ReadDir(stageDir(stage))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return collection, err
+		}
+
+		referenced := make(map[string]bool)
+		for _, file := range files {
+			if !strings.HasSuffix(file.Name(), ".json") {
+				continue
+			}
+			path := filepath.Join(stageDir(stage), file.Name())
+			entry, err := readStageEntry(path)
+			if err != nil {
+				return collection, err
+			}
+			_, statErr := os.Stat(entry.dataPath())
+			if statErr == nil && !entry.LastUsedAt.Before(cutoff) {
+				referenced[entry.File] = true
+				continue
+			}
+			if err := remove(entry.dataPath()); err != nil {
+				return collection, err
+			}
+			if err := remove(path); err != nil {
+				return collection, err
+			}
+			collection.Removed++
+		}
+
+		for _, file := range files {
+			if strings.HasSuffix(file.Name(), ".json") || referenced[file.Name()] {
+				continue
+			}
+			info, err := file.Info()
+			if os.IsNotExist(err) {
+				continue
+			}
+			if err != nil {
+				return collection, err
+			}
+			if now.Sub(info.ModTime()) < stageWriteGrace {
+				continue
+			}
+			if err := remove(filepath.Join(stageDir(stage), file.Name())); err != nil {
+				return collection, err
+			}
+		}
+	}
+	return collection, nil
+}
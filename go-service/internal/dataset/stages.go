@@ -0,0 +1,255 @@
+package dataset
+
+import (
+	"compress/gzip"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"path/filepath"
+	"time"
+
+	"github.com/airbusgeo/godal"
+	"github.com/forest-guardian/forest-guardian-api-poc/internal/config"
+	"github.com/forest-guardian/forest-guardian-api-poc/internal/sentinel"
+	"github.com/forest-guardian/forest-guardian-api-poc/internal/smoothing"
+)
+
+// stageImage identifies an image of the pixel stage by its file and content.
+type stageImage struct {
+	File     string `json:"file"`
+	Checksum string `json:"checksum"`
+}
+
+type pixelStageParameters struct {
+	Images []stageImage `json:"images"`
+	// Boundary is the WKT of the plot polygon the coverage is computed from
+	Boundary string                `json:"boundary"`
+	Indexes  config.IndexesConfig  `json:"indexes"`
+	Coverage config.CoverageConfig `json:"coverage"`
+	Quality  config.QualityConfig  `json:"quality"`
+}
+
+type cleanStageParameters struct {
+	GapFilling string               `json:"gap_filling"`
+	Backend    string               `json:"backend"`
+	Smoothing  smoothing.Parameters `json:"smoothing"`
+}
+
+type deltaStageParameters struct {
+	DeltaMin    int                      `json:"delta_min"`
+	DeltaMax    int                      `json:"delta_max"`
+	Features    config.FeaturesConfig    `json:"features"`
+	Compositing config.CompositingConfig `json:"compositing"`
+}
+
+// finalStageParameters key the final data on the sample and the whole configuration rather
+// than on the images, so it is found before any image is requested.
+type finalStageParameters struct {
+	Date     string        `json:"date"`
+	DeltaMin int           `json:"delta_min"`
+	DeltaMax int           `json:"delta_max"`
+	Config   config.Config `json:"config"`
+	// Format keys final data by its file format, so final data stored as CSV is not read as Parquet
+	Format string `json:"format"`
+}
+
+// pixelStage returns the stage entry of the pixel dataset of images. It reports false when an
+// image is not recorded with a checksum in the plot manifest, as the images could then change
+// without changing the key.
+func pixelStage(forest, plot string, images map[time.Time]*godal.Dataset, dates []time.Time, geometry *godal.Geometry, cfg config.Config) (StageEntry, bool, error) {
+	manifest, err := sentinel.LoadManifest(forest, plot)
+	if err != nil {
+		return StageEntry{}, false, err
+	}
+	parameters := pixelStageParameters{
+		Indexes:  cfg.Indexes,
+		Coverage: cfg.Coverage,
+		Quality:  cfg.Quality,
+	}
+	for _, date := range dates {
+		file := filepath.Base(images[date].Description())
+		entry, ok := manifest.Entry(file)
+		if !ok || entry.Checksum == "" {
+			return StageEntry{}, false, nil
+		}
+		parameters.Images = append(parameters.Images, stageImage{File: file, Checksum: entry.Checksum})
+	}
+	parameters.Boundary, err = geometry.WKT()
+	if err != nil {
+		return StageEntry{}, false, fmt.Errorf("failed to describe the plot boundary: %v", err)
+	}
+	entry, err := newStageEntry(StagePixel, forest, plot, "", parameters)
+	return entry, err == nil, err
+}
+
+// cleanStage returns the stage entry of the clean dataset of a pixel dataset, reporting false
+// when the pixel dataset is not keyed. The key holds the backend smoother resolved to, so the
+// outputs of the Go and Python smoothers are never reused for one another.
+func cleanStage(forest, plot string, data *Cube, filler GapFiller, smoother Smoother) (StageEntry, bool, error) {
+	if data.stageKey == "" {
+		return StageEntry{}, false, nil
+	}
+	parameters, err := smoothingParameters()
+	if err != nil {
+		return StageEntry{}, false, err
+	}
+	entry, err := newStageEntry(StageClean, forest, plot, data.stageKey, cleanStageParameters{
+		GapFilling: filler.Name(),
+		Backend:    smoother.Name(),
+		Smoothing:  parameters,
+	})
+	return entry, err == nil, err
+}
+
+// deltaStage returns the stage entry of the delta dataset of a clean dataset, reporting false
+// when the clean dataset is not keyed.
+func deltaStage(forest, plot string, deltaMin, deltaMax int, cleanDataset *Cube) (StageEntry, bool, error) {
+	if cleanDataset.stageKey == "" {
+		return StageEntry{}, false, nil
+	}
+	cfg, err := config.Get()
+	if err != nil {
+		return StageEntry{}, false, err
+	}
+	entry, err := newStageEntry(StageDelta, forest, plot, cleanDataset.stageKey, deltaStageParameters{
+		DeltaMin:    deltaMin,
+		DeltaMax:    deltaMax,
+		Features:    cfg.Features,
+		Compositing: cfg.Compositing,
+	})
+	return entry, err == nil, err
+}
+
+func finalStage(forest, plot string, date time.Time, deltaMin, deltaMax int) (StageEntry, error) {
+	cfg, err := config.Get()
+	if err != nil {
+		return StageEntry{}, err
+	}
+	return newStageEntry(StageFinal, forest, plot, "", finalStageParameters{
+		Date:     date.Format("2006-01-02"),
+		DeltaMin: deltaMin,
+		DeltaMax: deltaMax,
+		Config:   cfg,
+		Format:   "parquet",
+	})
+}
+
+// cubeRecord is the stored form of a Cube, its unexported planes included.
+type cubeRecord struct {
+	Dates        []time.Time
+	Indexes      []string
+	Width        int
+	Height       int
+	GeoTransform [6]float64
+	Values       []float32
+	Sensors      []sentinel.Sensor
+	Latitude     []float64
+	Longitude    []float64
+	Coverage     []float64
+	GapFilling   string
+	Compositing  string
+	Status       []pixelState
+	Provenance   []Provenance
+	GapDays      []int32
+}
+
+// encodeGob writes value as gzip-compressed gob.
+func encodeGob(value any) func(io.Writer) error {
+	return func(w io.Writer) error {
+		compressed := gzip.NewWriter(w)
+		if err := gob.NewEncoder(compressed).Encode(value); err != nil {
+			return err
+		}
+		return compressed.Close()
+	}
+}
+
+// decodeGob reads into value what encodeGob wrote.
+func decodeGob(value any) func(io.Reader) error {
+	return func(r io.Reader) error {
+		compressed, err := gzip.NewReader(r)
+		if err != nil {
+			return err
+		}
+		defer compressed.Close()
+		return gob.NewDecoder(compressed).Decode(value)
+	}
+}
+
+// loadCube returns the stored cube of entry, keyed by it.
+func loadCube(entry StageEntry) (*Cube, bool) {
+	var record cubeRecord
+	if !loadStage(entry, decodeGob(&record)) {
+		return nil, false
+	}
+	return &Cube{
+		Dates:        record.Dates,
+		Indexes:      record.Indexes,
+		Width:        record.Width,
+		Height:       record.Height,
+		GeoTransform: record.GeoTransform,
+		Values:       record.Values,
+		Sensors:      record.Sensors,
+		Latitude:     record.Latitude,
+		Longitude:    record.Longitude,
+		Coverage:     record.Coverage,
+		GapFilling:   record.GapFilling,
+		Compositing:  record.Compositing,
+		status:       record.Status,
+		provenance:   record.Provenance,
+		gapDays:      record.GapDays,
+		stageKey:     entry.Key,
+	}, true
+}
+
+// saveCube stores the cube under entry and keys it by it.
+func saveCube(entry StageEntry, cube *Cube) {
+	record := cubeRecord{
+		Dates:        cube.Dates,
+		Indexes:      cube.Indexes,
+		Width:        cube.Width,
+		Height:       cube.Height,
+		GeoTransform: cube.GeoTransform,
+		Values:       cube.Values,
+		Sensors:      cube.Sensors,
+		Latitude:     cube.Latitude,
+		Longitude:    cube.Longitude,
+		Coverage:     cube.Coverage,
+		GapFilling:   cube.GapFilling,
+		Compositing:  cube.Compositing,
+		Status:       cube.status,
+		Provenance:   cube.provenance,
+		GapDays:      cube.gapDays,
+	}
+	summary := fmt.Sprintf("%d dates, %dx%d pixels, %d indices", len(cube.Dates), cube.Width, cube.Height, len(cube.Indexes))
+	saveStage(entry, ".gob.gz", summary, encodeGob(record))
+	cube.stageKey = entry.Key
+}
+
+// loadDeltaDataset returns the stored delta dataset of entry.
+func loadDeltaDataset(entry StageEntry) (map[[2]int]map[time.Time]DeltaData, bool) {
+	var samples []DeltaData
+	if !loadStage(entry, decodeGob(&samples)) {
+		return nil, false
+	}
+	deltaDataset := make(map[[2]int]map[time.Time]DeltaData)
+	for _, sample := range samples {
+		key := [2]int{sample.X, sample.Y}
+		if _, exists := deltaDataset[key]; !exists {
+			deltaDataset[key] = make(map[time.Time]DeltaData)
+		}
+		deltaDataset[key][sample.EndDate] = sample
+	}
+	return deltaDataset, true
+}
+
+func saveDeltaDataset(entry StageEntry, deltaDataset map[[2]int]map[time.Time]DeltaData) {
+	var samples []DeltaData
+	for _, pixelSamples := range deltaDataset {
+		for _, sample := range pixelSamples {
+			samples = append(samples, sample)
+		}
+	}
+	summary := fmt.Sprintf("%d samples of %d pixels", len(samples), len(deltaDataset))
+	saveStage(entry, ".gob.gz", summary, encodeGob(samples))
+}
@@ -0,0 +1,194 @@
+package dataset
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"time"
+
+	"github.com/forest-guardian/forest-guardian-api-poc/internal/config"
+)
+
+// seasonDays is the span the seasonal maximum of an index is taken over.
+const seasonDays = 365
+
+// featureColumn matches the names of the temporal features of an index.
+var featureColumn = regexp.MustCompile(`^.+_((derivative|acceleration|mean|variance)_[0-9]+d|drop_from_max)$`)
+
+// IsTemporalFeature reports whether a final data column holds a temporal feature.
+func IsTemporalFeature(column string) bool {
+	return featureColumn.MatchString(column)
+}
+
+// temporalFeatures are the configured multi-scale features, computed on the smoothed series
+// of every index of a pixel at the end date of a sample:
+//   - <index>_derivative_<w>d: the change per day over the last w days
+//   - <index>_acceleration_<w>d: the change of that derivative per day, over the last 2w days
+//   - <index>_mean_<r>d and <index>_variance_<r>d: the mean and variance of the valid values of
+//     the last r days
+//   - <index>_drop_from_max: the maximum of the last year minus the current value
+//
+// Features whose span reaches before the first valid date of the pixel are NaN.
+type temporalFeatures struct {
+	windows       []int
+	rollingWindow int
+	seasonalDrop  bool
+}
+
+func configuredTemporalFeatures() (temporalFeatures, error) {
+	cfg, err := config.Get()
+	if err != nil {
+		return temporalFeatures{}, err
+	}
+	features := cfg.Features
+	for _, window := range features.DerivativeWindows {
+		if window <= 0 {
+			return temporalFeatures{}, fmt.Errorf("derivative windows must be positive in %s", config.Path())
+		}
+	}
+	if features.RollingWindow < 0 {
+		return temporalFeatures{}, fmt.Errorf("rolling window must not be negative in %s", config.Path())
+	}
+	windows := slices.Clone(features.DerivativeWindows)
+	slices.Sort(windows)
+	return temporalFeatures{
+		windows:       slices.Compact(windows),
+		rollingWindow: features.RollingWindow,
+		seasonalDrop:  features.SeasonalDrop,
+	}, nil
+}
+
+// names lists the features of an index.
+func (f temporalFeatures) names(index string) []string {
+	var names []string
+	for _, window := range f.windows {
+		names = append(names, fmt.Sprintf("%s_derivative_%dd", index, window), fmt.Sprintf("%s_acceleration_%dd", index, window))
+	}
+	if f.rollingWindow > 0 {
+		names = append(names, fmt.Sprintf("%s_mean_%dd", index, f.rollingWindow), fmt.Sprintf("%s_variance_%dd", index, f.rollingWindow))
+	}
+	if f.seasonalDrop {
+		names = append(names, index+"_drop_from_max")
+	}
+	return names
+}
+
+// days returns the days of history before the end date the features span.
+func (f temporalFeatures) days() int {
+	days := f.rollingWindow
+	if len(f.windows) > 0 {
+		days = max(days, 2*f.windows[len(f.windows)-1])
+	}
+	if f.seasonalDrop {
+		days = max(days, seasonDays)
+	}
+	return days
+}
+
+// TemporalFeatureDays returns the days of images before the end date of a sample the
+// configured temporal features need, 0 when none is configured.
+func TemporalFeatureDays() (int, error) {
+	features, err := configuredTemporalFeatures()
+	if err != nil {
+		return 0, err
+	}
+	return features.days(), nil
+}
+
+func (f temporalFeatures) empty() bool {
+	return len(f.windows) == 0 && f.rollingWindow == 0 && !f.seasonalDrop
+}
+
+// compute returns the features of every index of a pixel at date end, one of the valid dates
+// of the pixel in dates, or nil when none is configured.
+func (f temporalFeatures) compute(cube *Cube, pixel int, dates []int, end int) map[string]float64 {
+	if f.empty() {
+		return nil
+	}
+	endDate := cube.Dates[end]
+	features := make(map[string]float64)
+	for i, name := range cube.Indexes {
+		current := cube.value(end, i, pixel)
+		at := func(daysBefore int) float64 {
+			return seriesValueAt(cube, dates, i, pixel, endDate.AddDate(0, 0, -daysBefore))
+		}
+
+		for _, window := range f.windows {
+			w := float64(window)
+			previous, earlier := at(window), at(2*window)
+			features[fmt.Sprintf("%s_derivative_%dd", name, window)] = (current - previous) / w
+			features[fmt.Sprintf("%s_acceleration_%dd", name, window)] = (current - 2*previous + earlier) / (w * w)
+		}
+
+		if f.rollingWindow > 0 {
+			values := seriesValuesSince(cube, dates, i, pixel, endDate.AddDate(0, 0, -f.rollingWindow), end)
+			mean, variance := math.NaN(), math.NaN()
+			if len(values) > 0 {
+				mean, variance = 0, 0
+				for _, value := range values {
+					mean += value
+				}
+				mean /= float64(len(values))
+				for _, value := range values {
+					variance += (value - mean) * (value - mean)
+				}
+				variance /= float64(len(values))
+			}
+			features[fmt.Sprintf("%s_mean_%dd", name, f.rollingWindow)] = mean
+			features[fmt.Sprintf("%s_variance_%dd", name, f.rollingWindow)] = variance
+		}
+
+		if f.seasonalDrop {
+			maximum := math.Inf(-1)
+			for _, value := range seriesValuesSince(cube, dates, i, pixel, endDate.AddDate(0, 0, -seasonDays), end) {
+				maximum = math.Max(maximum, value)
+			}
+			drop := math.NaN()
+			if !math.IsInf(maximum, -1) {
+				drop = maximum - current
+			}
+			features[name+"_drop_from_max"] = drop
+		}
+	}
+	return features
+}
+
+// seriesValueAt interpolates an index of a pixel at a time linearly between its valid dates,
+// NaN before the first of them.
+func seriesValueAt(cube *Cube, dates []int, index, pixel int, t time.Time) float64 {
+	for j, date := range dates {
+		if cube.Dates[date].Before(t) {
+			continue
+		}
+		if cube.Dates[date].Equal(t) {
+			return cube.value(date, index, pixel)
+		}
+		if j == 0 {
+			return math.NaN()
+		}
+		before := dates[j-1]
+		span := cube.Dates[date].Sub(cube.Dates[before]).Hours()
+		share := t.Sub(cube.Dates[before]).Hours() / span
+		return cube.value(before, index, pixel) + share*(cube.value(date, index, pixel)-cube.value(before, index, pixel))
+	}
+	return math.NaN()
+}
+
+// seriesValuesSince lists the values of an index of a pixel on its valid dates from since up
+// to date end, skipping NaN.
+func seriesValuesSince(cube *Cube, dates []int, index, pixel int, since time.Time, end int) []float64 {
+	var values []float64
+	for _, date := range dates {
+		if date > end {
+			break
+		}
+		if cube.Dates[date].Before(since) {
+			continue
+		}
+		if value := cube.value(date, index, pixel); !math.IsNaN(value) {
+			values = append(values, value)
+		}
+	}
+	return values
+}
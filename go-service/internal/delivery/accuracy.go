@@ -11,7 +11,6 @@ import (
 	"github.com/forest-guardian/forest-guardian-api-poc/internal/dataset"
 	"github.com/forest-guardian/forest-guardian-api-poc/internal/notification"
 	"github.com/forest-guardian/forest-guardian-api-poc/internal/properties"
-	"github.com/gocarina/gocsv"
 )
 
 // GroupedData represents data grouped by forest and date
@@ -65,7 +64,7 @@ func RunAccuracyTest(sourceModelFileName, trainingModelFileName string, training
 	fmt.Printf("Split data: %d training rows, %d validation rows\n", len(trainingData), len(validationData))
 
 	// Create training model file with proper naming format
-	err = createTrainingModelFile(trainingData, sourceModelFileName, trainingModelFileName)
+	err = createTrainingModelFile(trainingData, sourceModelFileName, trainingModelFileName, trainingRatio)
 	if err != nil {
 		return 0, 0, 0, nil, nil, nil, fmt.Errorf("failed to create training model: %w", err)
 	}
@@ -92,21 +91,18 @@ func cleanupTrainingModelFile(trainingModelFileName string) {
 	} else {
 		fmt.Printf("Training model file %s deleted successfully\n", trainingModelFileName)
 	}
+	if err := removeDatasetLineage(trainingModelFileName); err != nil {
+		fmt.Printf("Warning: Failed to delete the lineage of training model file %s: %v\n", trainingModelFileName, err)
+	}
 }
 
 // readModelDataset reads and parses the model dataset
 func readModelDataset(modelFileName string) ([]dataset.FinalData, error) {
 	modelDataPath := fmt.Sprintf("%s/data/model/%s", properties.RootPath(), modelFileName)
 
-	file, err := os.OpenFile(modelDataPath, os.O_RDONLY, os.ModePerm)
+	rows, err := dataset.ReadFinalDataFile(modelDataPath)
 	if err != nil {
-		return nil, fmt.Errorf("error opening file: %w", err)
-	}
-	defer file.Close()
-
-	var rows []dataset.FinalData
-	if err := gocsv.UnmarshalFile(file, &rows); err != nil {
-		return nil, fmt.Errorf("error unmarshalling CSV: %w", err)
+		return nil, fmt.Errorf("error reading model dataset: %w", err)
 	}
 
 	return rows, nil
@@ -163,21 +159,29 @@ func splitModelDataByRatio(data []dataset.FinalData, trainingRatio int) ([]datas
 	return trainingData, validationData
 }
 
-// createTrainingModelFile creates a training model file from training data
-func createTrainingModelFile(trainingData []dataset.FinalData, sourceModelFileName, trainingModelFileName string) error {
+// createTrainingModelFile creates a training model file from training data, as Parquet or as
+// CSV by its extension. The lineage of the source model is carried over to it, so evaluating it
+// reads the parameters the source was built with.
+func createTrainingModelFile(trainingData []dataset.FinalData, sourceModelFileName, trainingModelFileName string, trainingRatio int) error {
 	fmt.Println("Creating training model file...")
 
 	filePath := fmt.Sprintf("%s/data/model/%s", properties.RootPath(), trainingModelFileName)
 
-	file, err := os.Create(filePath)
+	err := dataset.WriteFinalDataFile(filePath, trainingData)
 	if err != nil {
-		return fmt.Errorf("error creating training model file: %w", err)
+		return fmt.Errorf("error writing training model file: %w", err)
 	}
-	defer file.Close()
 
-	err = gocsv.MarshalFile(&trainingData, file)
+	lineage, ok, err := ReadDatasetLineage(sourceModelFileName)
 	if err != nil {
-		return fmt.Errorf("error writing training model file: %w", err)
+		return err
+	}
+	if ok {
+		lineage.SourceModel = sourceModelFileName
+		lineage.TrainingRatio = trainingRatio
+		if err := saveDatasetLineage(trainingModelFileName, lineage); err != nil {
+			return err
+		}
 	}
 
 	fmt.Printf("Training model saved to: %s\n", filePath)
@@ -4,12 +4,16 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/airbusgeo/godal"
 	"github.com/forest-guardian/forest-guardian-api-poc/internal/dataset"
 	ml "github.com/forest-guardian/forest-guardian-api-poc/internal/ml"
+	"github.com/forest-guardian/forest-guardian-api-poc/internal/properties"
 	"github.com/forest-guardian/forest-guardian-api-poc/internal/sentinel"
 	"github.com/forest-guardian/forest-guardian-api-poc/internal/weather"
+	"github.com/gammazero/workerpool"
 )
 
 func EvaluatePlotCleanData(forest, plot string, endDate time.Time) ([]dataset.PixelData, error) {
@@ -20,44 +24,37 @@ func EvaluatePlotCleanData(forest, plot string, endDate time.Time) ([]dataset.Pi
 		return nil, err
 	}
 
-	images, err := sentinel.GetImages(geometry, forest, plot, startDate, endDate, 1)
+	provider, err := sentinel.NewImageProvider()
 	if err != nil {
 		return nil, err
 	}
 
-	data, err := dataset.CreatePixelDataset(forest, plot, images)
+	images, err := sentinel.GetImages(provider, geometry, forest, plot, startDate, endDate, 1)
 	if err != nil {
 		return nil, err
 	}
 
-	cleanDataset, err := dataset.CreateCleanDataset(forest, plot, data)
+	data, err := dataset.CreatePixelDataset(forest, plot, images)
 	if err != nil {
 		return nil, err
 	}
 
-	groupedData := make(map[time.Time][]dataset.PixelData)
-	for _, sortedPixels := range cleanDataset {
-		for date, pixel := range sortedPixels {
-			//if pixel.Status == sentinel.PixelStatusTreatable {
-			//	fmt.Println("TREATABLE FOUND")
-			//}
-			groupedData[date] = append(groupedData[date], pixel)
-		}
-	}
-
-	var mostRecentDate time.Time
-	for date := range groupedData {
-		if date.After(mostRecentDate) {
-			mostRecentDate = date
-		}
+	cleanDataset, err := dataset.CreateCleanDataset(forest, plot, data)
+	if err != nil {
+		return nil, err
 	}
 
-	return groupedData[mostRecentDate], nil
+	// Every date of the clean dataset holds valid pixels, the last one is the most recent
+	return cleanDataset.ValidPixels(len(cleanDataset.Dates) - 1), nil
 }
 
 func EvaluatePlotDeltaData(deltaDays, deltaDaysThreshold int, forest, plot string, endDate time.Time) (map[[2]int]map[time.Time]dataset.DeltaData, error) {
 
-	getDaysBeforeEvidenceToAnalyse := deltaDays + deltaDaysThreshold
+	featureDays, err := dataset.TemporalFeatureDays()
+	if err != nil {
+		return nil, err
+	}
+	getDaysBeforeEvidenceToAnalyse := max(deltaDays+deltaDaysThreshold, featureDays)
 	startDate := endDate.AddDate(0, 0, -getDaysBeforeEvidenceToAnalyse)
 
 	geometry, err := sentinel.GetGeometryFromGeoJSON(forest, plot)
@@ -65,7 +62,12 @@ func EvaluatePlotDeltaData(deltaDays, deltaDaysThreshold int, forest, plot strin
 		return nil, err
 	}
 
-	images, err := sentinel.GetImages(geometry, forest, plot, startDate, endDate, 1)
+	provider, err := sentinel.NewImageProvider()
+	if err != nil {
+		return nil, err
+	}
+
+	images, err := sentinel.GetImages(provider, geometry, forest, plot, startDate, endDate, 1)
 	if err != nil {
 		return nil, err
 	}
@@ -90,43 +92,23 @@ func EvaluatePlotDeltaData(deltaDays, deltaDaysThreshold int, forest, plot strin
 
 func EvaluatePlotFinalData(model, forest, plot string, endDate time.Time) ([]ml.PixelResult, error) {
 	start := time.Now()
-	// Parse the model string according to the expected format
-	fmt.Println("Parsing model string:", model)
-	model = strings.TrimSuffix(model, ".csv")
-	parts := strings.Split(model, "_")
-	if len(parts) != 8 {
-		return nil, fmt.Errorf("model string has %d parts, expected 8: %v", len(parts), parts)
-	}
-	_, err := strconv.Atoi(parts[0]) // modelID, unused
-	if err != nil {
-		return nil, err
-	}
-	// modelDate := parts[1], unused
-	deltaDays, err := strconv.Atoi(parts[2])
+	// Read the dataset parameters of the model from its lineage
+	params, err := modelParametersOf(model)
 	if err != nil {
 		return nil, err
 	}
-	deltaDaysThreshold, err := strconv.Atoi(parts[3])
+	deltaDays := params.DeltaDays
+	deltaDaysThreshold := params.DeltaDaysThreshold
+	daysBeforeEvidenceToAnalyze := params.DaysBeforeEvidenceToAnalyze
+	daysBeforeEvidenceToFetch, err := params.daysBeforeEvidenceToFetch()
 	if err != nil {
 		return nil, err
 	}
-	daysBeforeEvidenceToAnalyze, err := strconv.Atoi(parts[4])
-	if err != nil {
-		return nil, err
-	}
-	if parts[5] != "training" {
-		return nil, fmt.Errorf("expected 'training' literal in model string, got '%s'", parts[5])
-	}
-	_, err = strconv.Atoi(parts[7]) // trainingRatio, unused
+	startDate, endDate, err := params.imageDateRange(endDate)
 	if err != nil {
 		return nil, err
 	}
 
-	daysBeforeEvidenceToFetch := deltaDays + deltaDaysThreshold + daysBeforeEvidenceToAnalyze
-
-	endDate = endDate.AddDate(0, 0, -daysBeforeEvidenceToAnalyze)
-	startDate := endDate.AddDate(0, 0, -daysBeforeEvidenceToFetch)
-
 	fmt.Println("daysBeforeEvidenceToAnalyze", daysBeforeEvidenceToAnalyze)
 	fmt.Println("daysBeforeEvidenceToFetch", daysBeforeEvidenceToFetch)
 	fmt.Println("deltaDays", deltaDays)
@@ -140,7 +122,12 @@ func EvaluatePlotFinalData(model, forest, plot string, endDate time.Time) ([]ml.
 	}
 
 	stepStart := time.Now()
-	images, err := sentinel.GetImages(geometry, forest, plot, startDate, endDate, 1)
+	provider, err := sentinel.NewImageProvider()
+	if err != nil {
+		return nil, err
+	}
+
+	images, err := sentinel.GetImages(provider, geometry, forest, plot, startDate, endDate, 1)
 	if err != nil {
 		return nil, err
 	}
@@ -193,3 +180,113 @@ func EvaluatePlotFinalData(model, forest, plot string, endDate time.Time) ([]ml.
 	fmt.Printf("Total evaluatePlot execution time: %v\n", time.Since(start))
 	return result, nil
 }
+
+// modelParameters holds the dataset parameters a model was built with, recorded in its lineage
+// or, for models created before lineage was recorded, encoded in its file name:
+// {id}_{date}_{deltaDays}_{deltaDaysThreshold}_{daysBeforeEvidenceToAnalyze}_training_{...}_{trainingRatio}.csv,
+// or .parquet
+type modelParameters struct {
+	DeltaDays                   int
+	DeltaDaysThreshold          int
+	DaysBeforeEvidenceToAnalyze int
+}
+
+func parseModelName(model string) (modelParameters, error) {
+	model = dataset.TrimFinalDataExtension(model)
+	parts := strings.Split(model, "_")
+	if len(parts) != 8 {
+		return modelParameters{}, fmt.Errorf("model string has %d parts, expected 8: %v", len(parts), parts)
+	}
+	_, err := strconv.Atoi(parts[0]) // modelID, unused
+	if err != nil {
+		return modelParameters{}, err
+	}
+	// modelDate := parts[1], unused
+	deltaDays, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return modelParameters{}, err
+	}
+	deltaDaysThreshold, err := strconv.Atoi(parts[3])
+	if err != nil {
+		return modelParameters{}, err
+	}
+	daysBeforeEvidenceToAnalyze, err := strconv.Atoi(parts[4])
+	if err != nil {
+		return modelParameters{}, err
+	}
+	if parts[5] != "training" {
+		return modelParameters{}, fmt.Errorf("expected 'training' literal in model string, got '%s'", parts[5])
+	}
+	_, err = strconv.Atoi(parts[7]) // trainingRatio, unused
+	if err != nil {
+		return modelParameters{}, err
+	}
+
+	return modelParameters{
+		DeltaDays:                   deltaDays,
+		DeltaDaysThreshold:          deltaDaysThreshold,
+		DaysBeforeEvidenceToAnalyze: daysBeforeEvidenceToAnalyze,
+	}, nil
+}
+
+// daysBeforeEvidenceToFetch returns the days of images before the end date covering the delta
+// window and the history of the configured temporal features, as CreateDataset fetches them.
+func (p modelParameters) daysBeforeEvidenceToFetch() (int, error) {
+	featureDays, err := dataset.TemporalFeatureDays()
+	if err != nil {
+		return 0, err
+	}
+	return max(p.DeltaDays+p.DeltaDaysThreshold, featureDays) + p.DaysBeforeEvidenceToAnalyze, nil
+}
+
+// imageDateRange returns the image window needed to evaluate a plot on endDate with this model.
+func (p modelParameters) imageDateRange(endDate time.Time) (time.Time, time.Time, error) {
+	days, err := p.daysBeforeEvidenceToFetch()
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	endDate = endDate.AddDate(0, 0, -p.DaysBeforeEvidenceToAnalyze)
+	return endDate.AddDate(0, 0, -days), endDate, nil
+}
+
+// PrefetchForestImages downloads the images every plot needs for EvaluatePlotFinalData in
+// parallel, so the per-plot evaluation afterwards reads them from the image cache. Failed plots
+// are returned as errors and are left for the evaluation step to retry.
+func PrefetchForestImages(model, forest string, plots []string, endDate time.Time) []error {
+	params, err := modelParametersOf(model)
+	if err != nil {
+		return []error{err}
+	}
+	startDate, endDate, err := params.imageDateRange(endDate)
+	if err != nil {
+		return []error{err}
+	}
+
+	provider, err := sentinel.NewImageProvider()
+	if err != nil {
+		return []error{err}
+	}
+
+	var errs []error
+	var mutex sync.Mutex
+	wp := workerpool.New(properties.ImageDownloadWorkers())
+	for _, plot := range plots {
+		wp.Submit(func() {
+			geometry, err := sentinel.GetGeometryFromGeoJSON(forest, plot)
+			if err == nil {
+				var images map[time.Time]*godal.Dataset
+				images, err = sentinel.GetImages(provider, geometry, forest, plot, startDate, endDate, 1)
+				for _, image := range images {
+					image.Close()
+				}
+			}
+			if err != nil {
+				mutex.Lock()
+				errs = append(errs, fmt.Errorf("plot %s: %v", plot, err))
+				mutex.Unlock()
+			}
+		})
+	}
+	wp.StopWait()
+	return errs
+}
@@ -0,0 +1,221 @@
+package delivery
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/airbusgeo/godal"
+	"github.com/forest-guardian/forest-guardian-api-poc/internal/dataset"
+	"github.com/forest-guardian/forest-guardian-api-poc/internal/properties"
+	"github.com/forest-guardian/forest-guardian-api-poc/internal/sentinel"
+)
+
+// lineageExtension names the sidecar of a model dataset, <name>.lineage.json beside it. It is
+// shared by the CSV and Parquet files of the same name, so conversions keep their lineage.
+const lineageExtension = ".lineage.json"
+
+// DatasetLineage records how a model dataset was produced. CreateDataset saves it as a JSON
+// sidecar of the dataset. A run appending to an existing dataset must use the same settings; it
+// adds its samples to those of the earlier runs and its input to Appends.
+type DatasetLineage struct {
+	CreatedAt   time.Time `json:"created_at"`
+	CLIVersion  string    `json:"cli_version"`
+	InputFile   string    `json:"input_file"`
+	InputSHA256 string    `json:"input_sha256"`
+
+	DeltaDays                   int `json:"delta_days"`
+	DeltaDaysThreshold          int `json:"delta_days_threshold"`
+	DaysBeforeEvidenceToAnalyze int `json:"days_before_evidence_to_analyze"`
+
+	QualityPolicy string `json:"quality_policy"`
+	GapFilling    string `json:"gap_filling"`
+	WeatherSource string `json:"weather_source"`
+
+	Samples []SampleLineage `json:"samples"`
+	// Appends lists the later runs that added samples to the dataset
+	Appends []RunLineage `json:"appends,omitempty"`
+
+	// SourceModel and TrainingRatio are set on the training models of accuracy tests
+	SourceModel   string `json:"source_model,omitempty"`
+	TrainingRatio int    `json:"training_ratio,omitempty"`
+}
+
+// RunLineage records the input and build of a run appending to a dataset.
+type RunLineage struct {
+	CreatedAt   time.Time `json:"created_at"`
+	CLIVersion  string    `json:"cli_version"`
+	InputFile   string    `json:"input_file"`
+	InputSHA256 string    `json:"input_sha256"`
+}
+
+// SampleLineage lists the images a sample of the input was built from.
+type SampleLineage struct {
+	Forest string `json:"forest"`
+	Plot   string `json:"plot"`
+	// Date is the evidence date of the sample
+	Date       string   `json:"date"`
+	ImageDates []string `json:"image_dates"`
+	// Reused tells the final data came from the stage store, in which case the image dates are
+	// those of the accepted images of the plot manifest in the sample window
+	Reused bool `json:"reused,omitempty"`
+}
+
+func lineagePath(modelFileName string) string {
+	return fmt.Sprintf("%s/data/model/%s%s", properties.RootPath(), dataset.TrimFinalDataExtension(modelFileName), lineageExtension)
+}
+
+// ReadDatasetLineage returns the lineage of a model dataset, reporting false when it has no
+// sidecar, as datasets created before lineage was recorded.
+func ReadDatasetLineage(modelFileName string) (DatasetLineage, bool, error) {
+	path := lineagePath(modelFileName)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return DatasetLineage{}, false, nil
+	}
+	if err != nil {
+		return DatasetLineage{}, false, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	var lineage DatasetLineage
+	if err := json.Unmarshal(data, &lineage); err != nil {
+		return DatasetLineage{}, false, fmt.Errorf("invalid JSON in %s: %w", path, err)
+	}
+	return lineage, true, nil
+}
+
+// saveDatasetLineage writes the sidecar of a model dataset.
+func saveDatasetLineage(modelFileName string, lineage DatasetLineage) error {
+	data, err := json.MarshalIndent(lineage, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal dataset lineage: %w", err)
+	}
+	if err := os.WriteFile(lineagePath(modelFileName), data, 0644); err != nil {
+		return fmt.Errorf("failed to write dataset lineage: %w", err)
+	}
+	return nil
+}
+
+// removeDatasetLineage deletes the sidecar of a model dataset, if any.
+func removeDatasetLineage(modelFileName string) error {
+	err := os.Remove(lineagePath(modelFileName))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// addSample records the image dates of a sample, replacing an earlier record of it.
+func (l *DatasetLineage) addSample(sample SampleLineage) {
+	l.Samples = slices.DeleteFunc(l.Samples, func(recorded SampleLineage) bool {
+		return recorded.Forest == sample.Forest && recorded.Plot == sample.Plot && recorded.Date == sample.Date
+	})
+	l.Samples = append(l.Samples, sample)
+}
+
+// run returns the input and build of the lineage.
+func (l DatasetLineage) run() RunLineage {
+	return RunLineage{CreatedAt: l.CreatedAt, CLIVersion: l.CLIVersion, InputFile: l.InputFile, InputSHA256: l.InputSHA256}
+}
+
+// checkAppend reports the settings of run, a run appending to the dataset, that differ from
+// those the dataset was created with, as its rows would not be comparable.
+func (l DatasetLineage) checkAppend(run DatasetLineage) error {
+	settings := []struct {
+		name          string
+		dataset, this any
+	}{
+		{"delta days", l.DeltaDays, run.DeltaDays},
+		{"delta days threshold", l.DeltaDaysThreshold, run.DeltaDaysThreshold},
+		{"days before evidence", l.DaysBeforeEvidenceToAnalyze, run.DaysBeforeEvidenceToAnalyze},
+		{"quality policy", l.QualityPolicy, run.QualityPolicy},
+		{"gap filling", l.GapFilling, run.GapFilling},
+		{"weather source", l.WeatherSource, run.WeatherSource},
+	}
+	for _, setting := range settings {
+		if setting.dataset != setting.this {
+			return fmt.Errorf("the dataset was created with %s %v, this run uses %v; create a new dataset instead", setting.name, setting.dataset, setting.this)
+		}
+	}
+	return nil
+}
+
+// modelParameters returns the dataset parameters of the lineage.
+func (l DatasetLineage) modelParameters() modelParameters {
+	return modelParameters{
+		DeltaDays:                   l.DeltaDays,
+		DeltaDaysThreshold:          l.DeltaDaysThreshold,
+		DaysBeforeEvidenceToAnalyze: l.DaysBeforeEvidenceToAnalyze,
+	}
+}
+
+// String summarises the lineage in one line, for reports.
+func (l DatasetLineage) String() string {
+	summary := fmt.Sprintf("input %s (sha256 %s), delta %d+%d days, %d days before evidence, %d samples, CLI %s, created %s",
+		l.InputFile, l.InputSHA256[:min(len(l.InputSHA256), 12)], l.DeltaDays, l.DeltaDaysThreshold, l.DaysBeforeEvidenceToAnalyze,
+		len(l.Samples), l.CLIVersion, l.CreatedAt.Format("2006-01-02"))
+	if l.SourceModel != "" {
+		summary += fmt.Sprintf(", %d%% training split of %s", l.TrainingRatio, l.SourceModel)
+	}
+	return summary
+}
+
+// modelParametersOf returns the dataset parameters of a model from its lineage, or from its file
+// name when it has no sidecar.
+func modelParametersOf(model string) (modelParameters, error) {
+	lineage, ok, err := ReadDatasetLineage(model)
+	if err != nil {
+		return modelParameters{}, err
+	}
+	if ok {
+		return lineage.modelParameters(), nil
+	}
+	fmt.Printf("Model %s has no lineage, parsing its parameters from its name\n", model)
+	return parseModelName(model)
+}
+
+// fileSHA256 returns the hex SHA-256 of a file.
+func fileSHA256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// imageDates returns the sorted acquisition dates of images.
+func imageDates(images map[time.Time]*godal.Dataset) []string {
+	var dates []string
+	for date := range images {
+		dates = append(dates, date.Format("2006-01-02"))
+	}
+	slices.Sort(dates)
+	return slices.Compact(dates)
+}
+
+// manifestImageDates returns the dates of the accepted images of a plot between startDate and
+// endDate, those GetImages serves from its cache.
+func manifestImageDates(forest, plot string, startDate, endDate time.Time) ([]string, error) {
+	manifest, err := sentinel.LoadManifest(forest, plot)
+	if err != nil {
+		return nil, err
+	}
+	first, last := startDate.Format("2006-01-02"), endDate.Format("2006-01-02")
+	var dates []string
+	for _, entry := range manifest.Entries() {
+		if entry.Status != sentinel.ManifestStatusAccepted || entry.Date < first || entry.Date > last {
+			continue
+		}
+		dates = append(dates, entry.Date)
+	}
+	return slices.Compact(dates), nil
+}
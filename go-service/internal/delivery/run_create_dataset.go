@@ -40,6 +40,8 @@ type DatasetReport struct {
 	DeltaDays            int
 	DeltaDaysThreshold   int
 	DaysBeforeEvidence   int
+	QualityPolicy        string
+	GapFilling           string
 }
 
 func generateMarkdownReport(report *DatasetReport) error {
@@ -139,6 +141,8 @@ This dataset has been processed with the following quality measures:
 - **Generated on**: %s
 - **Processing Pipeline Version**: v1.0
 - **Quality Score**: %.1f/10 (based on success rate and data completeness)
+- **Quality Policy**: %s
+- **Gap Filling**: %s
 `
 
 	qualityScore := (successRate / 10) + 2 // Simple quality scoring
@@ -146,7 +150,7 @@ This dataset has been processed with the following quality measures:
 		qualityScore = 10
 	}
 
-	content = fmt.Sprintf(content, time.Now().Format("2006-01-02 15:04:05"), qualityScore)
+	content = fmt.Sprintf(content, time.Now().Format("2006-01-02 15:04:05"), qualityScore, report.QualityPolicy, report.GapFilling)
 
 	_, err = file.WriteString(content)
 	if err != nil {
@@ -228,8 +232,22 @@ func getBestSamplesFromDeltaDataset(deltaDataset map[[2]int]map[time.Time]datase
 
 func CreateDataset(inputDataFileName, outputtDataFileName string, deltaDays, deltaDaysTrashHold, daysBeforeEvidenceToAnalyze int) error {
 	fmt.Println("create dataset")
-	daysToFetch := deltaDays + deltaDaysTrashHold + daysBeforeEvidenceToAnalyze
 	deltaMin, deltaMax := deltaDays, deltaDays+deltaDaysTrashHold
+	featureDays, err := dataset.TemporalFeatureDays()
+	if err != nil {
+		return err
+	}
+	// The images cover the delta window and the history of the temporal features
+	daysToFetch := max(deltaMax, featureDays) + daysBeforeEvidenceToAnalyze
+
+	qualityPolicy, err := sentinel.ActiveQualityPolicy()
+	if err != nil {
+		return err
+	}
+	gapFiller, err := dataset.NewGapFiller()
+	if err != nil {
+		return err
+	}
 
 	// Initialize report
 	report := &DatasetReport{
@@ -239,6 +257,8 @@ func CreateDataset(inputDataFileName, outputtDataFileName string, deltaDays, del
 		DeltaDays:            deltaDays,
 		DeltaDaysThreshold:   deltaDaysTrashHold,
 		DaysBeforeEvidence:   daysBeforeEvidenceToAnalyze,
+		QualityPolicy:        qualityPolicy.String(),
+		GapFilling:           gapFiller.Name(),
 		ProcessingStats:      make(map[string]int),
 		ForestStats:          make(map[string]int),
 		PestStats:            make(map[string]int),
@@ -246,6 +266,11 @@ func CreateDataset(inputDataFileName, outputtDataFileName string, deltaDays, del
 		Errors:               []string{},
 	}
 
+	provider, err := sentinel.NewImageProvider()
+	if err != nil {
+		return err
+	}
+
 	validationDataPath := fmt.Sprintf("%s/data/training_input/%s", properties.RootPath(), inputDataFileName)
 
 	file, err := os.OpenFile(validationDataPath, os.O_RDWR|os.O_CREATE, os.ModePerm)
@@ -265,6 +290,45 @@ func CreateDataset(inputDataFileName, outputtDataFileName string, deltaDays, del
 	report.TotalSamples = target
 	fmt.Printf("Creating dataset from file %s with %d samples\n", validationDataPath, target)
 
+	inputSHA256, err := fileSHA256(validationDataPath)
+	if err != nil {
+		return fmt.Errorf("failed to hash %s: %w", validationDataPath, err)
+	}
+	lineage := DatasetLineage{
+		CreatedAt:                   time.Now().UTC(),
+		CLIVersion:                  properties.Version(),
+		InputFile:                   inputDataFileName,
+		InputSHA256:                 inputSHA256,
+		DeltaDays:                   deltaDays,
+		DeltaDaysThreshold:          deltaDaysTrashHold,
+		DaysBeforeEvidenceToAnalyze: daysBeforeEvidenceToAnalyze,
+		QualityPolicy:               qualityPolicy.String(),
+		GapFilling:                  gapFiller.Name(),
+		WeatherSource:               weather.Source,
+	}
+	// A dataset appended to keeps the settings it was created with, and only gets a lineage
+	// if it already has one
+	recordLineage := true
+	if _, err := os.Stat(fmt.Sprintf("%s/data/model/%s", properties.RootPath(), outputtDataFileName)); err == nil {
+		existing, ok, err := ReadDatasetLineage(outputtDataFileName)
+		if err != nil {
+			return err
+		}
+		if ok {
+			if err := existing.checkAppend(lineage); err != nil {
+				return fmt.Errorf("cannot append to %s: %w", outputtDataFileName, err)
+			}
+			existing.Appends = append(existing.Appends, lineage.run())
+			lineage = existing
+		} else {
+			fmt.Printf("%s was created before lineage was recorded, appending without a lineage\n", outputtDataFileName)
+			recordLineage = false
+		}
+	}
+
+	// The rows of every sample are written once all are processed, as a Parquet file cannot be
+	// appended to
+	var modelRows []dataset.FinalData
 	for i := 0; i < target; i++ {
 		var err error
 		defer func() {
@@ -288,12 +352,23 @@ func CreateDataset(inputDataFileName, outputtDataFileName string, deltaDays, del
 		report.PestStats[pest]++
 		report.SeverityStats[severity]++
 
+		endDate := date.AddDate(0, 0, -daysBeforeEvidenceToAnalyze)
+		startDate := endDate.AddDate(0, 0, -daysToFetch)
+		sample := SampleLineage{Forest: forest, Plot: plot, Date: row.Date}
+
 		finalData, err := dataset.GetSavedFinalData(forest, plot, date, deltaMin, deltaMax)
 		if err != nil {
 			errMsg := fmt.Sprintf("Error getting saved final dataset: %v | Row: %d | Forest: %s | Plot: %s | Pest: %s | Severity: %s", err, i+1, forest, plot, pest, severity)
 			fmt.Println("Error getting saved final dataset: " + err.Error())
 			addErrorToReport(report, errMsg)
 		}
+		if finalData != nil {
+			sample.Reused = true
+			sample.ImageDates, err = manifestImageDates(forest, plot, startDate, endDate)
+			if err != nil {
+				fmt.Printf("Failed to list the images of %s_%s for the dataset lineage: %v\n", forest, plot, err)
+			}
+		}
 
 		if finalData == nil {
 
@@ -305,16 +380,14 @@ func CreateDataset(inputDataFileName, outputtDataFileName string, deltaDays, del
 				continue
 			}
 
-			endDate := date.AddDate(0, 0, -daysBeforeEvidenceToAnalyze)
-			startDate := endDate.AddDate(0, 0, -daysToFetch)
-
-			images, err := sentinel.GetImages(geometry, forest, plot, startDate, endDate, 1)
+			images, err := sentinel.GetImages(provider, geometry, forest, plot, startDate, endDate, 1)
 			if err != nil {
 				errMsg := fmt.Sprintf("Error getting images: %v | Row: %d | Forest: %s | Plot: %s | Pest: %s | Severity: %s", err, i+1, forest, plot, pest, severity)
 				fmt.Println(err.Error())
 				addErrorToReport(report, errMsg)
 				continue
 			}
+			sample.ImageDates = imageDates(images)
 
 			latitude, longitude, err := sentinel.GetCentroidLatitudeLongitudeFromGeometry(geometry)
 			if err != nil {
@@ -339,7 +412,7 @@ func CreateDataset(inputDataFileName, outputtDataFileName string, deltaDays, del
 				addErrorToReport(report, errMsg)
 				continue
 			}
-			if len(data) == 0 {
+			if len(data.Dates) == 0 {
 				err = fmt.Errorf("no data available to create the dataset for forest: %s, plot: %s using %d images", forest, plot, len(images))
 				errMsg := fmt.Sprintf("Error creating pixel dataset: %v | Row: %d | Forest: %s | Plot: %s | Pest: %s | Severity: %s", err, i+1, forest, plot, pest, severity)
 				fmt.Println(err.Error())
@@ -387,45 +460,8 @@ func CreateDataset(inputDataFileName, outputtDataFileName string, deltaDays, del
 			finalData = createdFinalData
 		}
 
-		filePath := fmt.Sprintf("%s/data/model/%s", properties.RootPath(), outputtDataFileName)
-		fileExists := false
-
-		// Check if the file already exists
-		if _, err := os.Stat(filePath); err == nil {
-			fileExists = true
-		}
-
-		file, err := os.OpenFile(filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-		if err != nil {
-			fmt.Println(err.Error())
-			continue
-		}
-		defer file.Close()
-
-		if fileExists {
-			_, err = file.Seek(0, io.SeekEnd)
-			if err != nil {
-				fmt.Println(err.Error())
-				continue
-			}
-		}
-
-		writer := csv.NewWriter(file)
-		defer writer.Flush()
-
-		// Write the header only if the file does not already exist
-		if !fileExists {
-			if err := gocsv.MarshalCSV(&finalData, writer); err != nil {
-				fmt.Println(err.Error())
-				continue
-			}
-			continue
-		}
-		// Write the data rows
-		if err := gocsv.MarshalCSVWithoutHeaders(&finalData, writer); err != nil {
-			fmt.Println(err.Error())
-			continue
-		}
+		modelRows = append(modelRows, finalData...)
+		lineage.addSample(sample)
 
 		fmt.Printf("Processed row %d/%d: Forest=%s, Plot=%s, Pest=%s, Severity=%s, rows=%d\n", i+1, target, forest, plot, pest, severity, len(finalData))
 		report.ProcessedSamples++
@@ -435,10 +471,18 @@ func CreateDataset(inputDataFileName, outputtDataFileName string, deltaDays, del
 	// Finalize report
 	report.EndTime = time.Now()
 	filePath := fmt.Sprintf("%s/data/model/%s", properties.RootPath(), outputtDataFileName)
-	err = deduplicateCSVFile(filePath)
-	if err != nil {
-		fmt.Printf("[Deduplication] Error during deduplication: %v\n", err)
-		addErrorToReport(report, fmt.Sprintf("Deduplication error: %v", err))
+	var writeErr error
+	if len(modelRows) > 0 {
+		writeErr = writeModelRows(filePath, modelRows)
+		if writeErr != nil {
+			addErrorToReport(report, fmt.Sprintf("Error writing model dataset: %v", writeErr))
+		}
+	}
+	if writeErr == nil && recordLineage && len(lineage.Samples) > 0 {
+		if err := saveDatasetLineage(outputtDataFileName, lineage); err != nil {
+			fmt.Println(err.Error())
+			addErrorToReport(report, fmt.Sprintf("Lineage error: %v", err))
+		}
 	}
 
 	// Generate markdown report
@@ -450,12 +494,102 @@ func CreateDataset(inputDataFileName, outputtDataFileName string, deltaDays, del
 	if report.ErrorCount == target {
 		return fmt.Errorf("all rows failed during dataset creation")
 	}
+	if writeErr != nil {
+		return fmt.Errorf("failed to write model dataset %s: %w", outputtDataFileName, writeErr)
+	}
 
 	fmt.Printf("Dataset created successfully. Processed %d/%d samples with %d errors\n", 
 		report.ProcessedSamples, report.TotalSamples, report.ErrorCount)
 	return nil
 }
 
+// writeModelRows adds rows to the model dataset at filePath, creating it if it does not
+// exist, and removes the duplicate rows. A CSV is appended to in the column order of its
+// header, then deduplicated; a Parquet file cannot be appended to, so it is read once, merged
+// with rows, deduplicated and rewritten.
+func writeModelRows(filePath string, rows []dataset.FinalData) error {
+	_, err := os.Stat(filePath)
+	fileExists := err == nil
+
+	if dataset.IsParquet(filePath) {
+		if fileExists {
+			existing, err := dataset.ReadFinalDataFile(filePath)
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %w", filePath, err)
+			}
+			rows = append(existing, rows...)
+		}
+		rows, err = deduplicateRows(rows)
+		if err != nil {
+			return err
+		}
+		return dataset.WriteFinalDataFile(filePath, rows)
+	}
+
+	var header []string
+	if fileExists {
+		header, err = readCSVHeader(filePath)
+		if err != nil {
+			return err
+		}
+	}
+
+	file, err := os.OpenFile(filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Write the header only if the file does not already exist, and otherwise the data rows
+	// in the column order of the existing file
+	if err := dataset.WriteFinalData(csv.NewWriter(file), rows, header); err != nil {
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := deduplicateCSVFile(filePath); err != nil {
+		fmt.Printf("[Deduplication] Error during deduplication: %v\n", err)
+		return fmt.Errorf("deduplication error: %w", err)
+	}
+	return nil
+}
+
+// readCSVHeader returns the first record of the CSV file at filePath.
+func readCSVHeader(filePath string) ([]string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", filePath, err)
+	}
+	defer file.Close()
+
+	header, err := csv.NewReader(file).Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header of %s: %w", filePath, err)
+	}
+	return header, nil
+}
+
+// deduplicateRows removes the rows of a model dataset that duplicate an earlier one.
+func deduplicateRows(rows []dataset.FinalData) ([]dataset.FinalData, error) {
+	fmt.Printf("[Deduplication] Starting deduplication of %d rows\n", len(rows))
+	headers, records, err := dataset.FinalDataRecords(rows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode rows for deduplication: %w", err)
+	}
+
+	var deduped []dataset.FinalData
+	for _, i := range uniqueRecords(headers, records) {
+		deduped = append(deduped, rows[i])
+	}
+	if len(deduped) == len(rows) {
+		fmt.Printf("[Deduplication] No duplicates found. Total rows: %d\n", len(deduped))
+	} else {
+		fmt.Printf("[Deduplication] Removed %d duplicate rows. Clean rows: %d\n", len(rows)-len(deduped), len(deduped))
+	}
+	return deduped, nil
+}
+
 // deduplicateCSVFile removes duplicate rows from a CSV file based on selected columns and overwrites the file.
 func deduplicateCSVFile(filePath string) error {
 	fmt.Printf("[Deduplication] Starting deduplication for file: %s\n", filePath)
@@ -483,34 +617,9 @@ func deduplicateCSVFile(filePath string) error {
 		records = append(records, record)
 	}
 
-	// Indices of columns to deduplicate on (based on header order)
-	colIdx := map[string]int{}
-	for i, h := range headers {
-		colIdx[h] = i
-	}
-	// List of columns to deduplicate on
-	dedupCols := []string{
-		"avg_temperature", "temp_std_dev", "avg_humidity", "humidity_std_dev", "total_precipitation", "dry_days_consecutive",
-		"ndre", "ndmi", "psri", "ndvi", "delta_min", "delta_max", "delta", "ndre_derivative", "ndmi_derivative", "psri_derivative", "ndvi_derivative", "label",
-	}
-
-	unique := make(map[string]struct{})
 	var deduped [][]string
-	for _, row := range records {
-		var keyParts []string
-		for _, col := range dedupCols {
-			idx, ok := colIdx[col]
-			if !ok || idx >= len(row) {
-				keyParts = append(keyParts, "")
-			} else {
-				keyParts = append(keyParts, row[idx])
-			}
-		}
-		key := strings.Join(keyParts, "||")
-		if _, exists := unique[key]; !exists {
-			unique[key] = struct{}{}
-			deduped = append(deduped, row)
-		}
+	for _, i := range uniqueRecords(headers, records) {
+		deduped = append(deduped, records[i])
 	}
 
 	if len(deduped) == len(records) {
@@ -547,3 +656,41 @@ func deduplicateCSVFile(filePath string) error {
 	fmt.Printf("[Deduplication] Deduplication complete. File updated: %s\n", filePath)
 	return nil
 }
+
+// uniqueRecords returns the positions of the records of a model dataset that are not
+// duplicates of an earlier one on the selected columns.
+func uniqueRecords(headers []string, records [][]string) []int {
+	// Indices of columns to deduplicate on (based on header order)
+	colIdx := map[string]int{}
+	for i, h := range headers {
+		colIdx[h] = i
+	}
+	// List of columns to deduplicate on
+	dedupCols := []string{
+		"avg_temperature", "temp_std_dev", "avg_humidity", "humidity_std_dev", "total_precipitation", "dry_days_consecutive",
+		"ndre", "ndmi", "psri", "ndvi", "delta_min", "delta_max", "delta", "ndre_derivative", "ndmi_derivative", "psri_derivative", "ndvi_derivative", "label",
+	}
+	for _, name := range dataset.ExtraIndexColumns(headers) {
+		dedupCols = append(dedupCols, name, name+"_derivative")
+	}
+
+	unique := make(map[string]struct{})
+	var kept []int
+	for i, row := range records {
+		var keyParts []string
+		for _, col := range dedupCols {
+			idx, ok := colIdx[col]
+			if !ok || idx >= len(row) {
+				keyParts = append(keyParts, "")
+			} else {
+				keyParts = append(keyParts, row[idx])
+			}
+		}
+		key := strings.Join(keyParts, "||")
+		if _, exists := unique[key]; !exists {
+			unique[key] = struct{}{}
+			kept = append(kept, i)
+		}
+	}
+	return kept
+}
@@ -88,6 +88,9 @@ type PixelResult struct {
 	Latitude      float64                `protobuf:"fixed64,3,opt,name=latitude,proto3" json:"latitude,omitempty"`
 	Longitude     float64                `protobuf:"fixed64,4,opt,name=longitude,proto3" json:"longitude,omitempty"`
 	Result        []*LabelProbability    `protobuf:"bytes,5,rep,name=result,proto3" json:"result,omitempty"`
+	Coverage      float64                `protobuf:"fixed64,6,opt,name=coverage,proto3" json:"coverage,omitempty"`
+	Provenance    string                 `protobuf:"bytes,7,opt,name=provenance,proto3" json:"provenance,omitempty"`
+	GapDays       int32                  `protobuf:"varint,8,opt,name=gap_days,json=gapDays,proto3" json:"gap_days,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -157,6 +160,27 @@ func (x *PixelResult) GetResult() []*LabelProbability {
 	return nil
 }
 
+func (x *PixelResult) GetCoverage() float64 {
+	if x != nil {
+		return x.Coverage
+	}
+	return 0
+}
+
+func (x *PixelResult) GetProvenance() string {
+	if x != nil {
+		return x.Provenance
+	}
+	return ""
+}
+
+func (x *PixelResult) GetGapDays() int32 {
+	if x != nil {
+		return x.GapDays
+	}
+	return 0
+}
+
 type LabelProbability struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Label         string                 `protobuf:"bytes,1,opt,name=label,proto3" json:"label,omitempty"`
@@ -411,8 +435,19 @@ type FinalData_DeltaData struct {
 	Label          string                 `protobuf:"bytes,18,opt,name=label,proto3" json:"label,omitempty"`
 	Latitude       float64                `protobuf:"fixed64,19,opt,name=latitude,proto3" json:"latitude,omitempty"`
 	Longitude      float64                `protobuf:"fixed64,20,opt,name=longitude,proto3" json:"longitude,omitempty"`
-	unknownFields  protoimpl.UnknownFields
-	sizeCache      protoimpl.SizeCache
+	// Enabled indices beyond ndre, ndmi, psri and ndvi, keyed by name
+	Indexes          map[string]float64 `protobuf:"bytes,21,rep,name=indexes,proto3" json:"indexes,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"fixed64,2,opt,name=value"`
+	IndexDerivatives map[string]float64 `protobuf:"bytes,22,rep,name=index_derivatives,json=indexDerivatives,proto3" json:"index_derivatives,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"fixed64,2,opt,name=value"`
+	// Share of the pixel inside the plot polygon, below 1 on edge pixels
+	Coverage float64 `protobuf:"fixed64,23,opt,name=coverage,proto3" json:"coverage,omitempty"`
+	// "observed", "estimated-temporal" or "estimated-spatial"
+	Provenance string `protobuf:"bytes,24,opt,name=provenance,proto3" json:"provenance,omitempty"`
+	// Days to the nearest observation of the pixel, -1 if it was never observed
+	GapDays int32 `protobuf:"varint,25,opt,name=gap_days,json=gapDays,proto3" json:"gap_days,omitempty"`
+	// Multi-scale temporal features keyed by column name, such as ndvi_derivative_15d
+	Features      map[string]float64 `protobuf:"bytes,26,rep,name=features,proto3" json:"features,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"fixed64,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
 func (x *FinalData_DeltaData) Reset() {
@@ -585,11 +620,53 @@ func (x *FinalData_DeltaData) GetLongitude() float64 {
 	return 0
 }
 
+func (x *FinalData_DeltaData) GetIndexes() map[string]float64 {
+	if x != nil {
+		return x.Indexes
+	}
+	return nil
+}
+
+func (x *FinalData_DeltaData) GetIndexDerivatives() map[string]float64 {
+	if x != nil {
+		return x.IndexDerivatives
+	}
+	return nil
+}
+
+func (x *FinalData_DeltaData) GetCoverage() float64 {
+	if x != nil {
+		return x.Coverage
+	}
+	return 0
+}
+
+func (x *FinalData_DeltaData) GetProvenance() string {
+	if x != nil {
+		return x.Provenance
+	}
+	return ""
+}
+
+func (x *FinalData_DeltaData) GetGapDays() int32 {
+	if x != nil {
+		return x.GapDays
+	}
+	return 0
+}
+
+func (x *FinalData_DeltaData) GetFeatures() map[string]float64 {
+	if x != nil {
+		return x.Features
+	}
+	return nil
+}
+
 var File_run_model_proto protoreflect.FileDescriptor
 
 const file_run_model_proto_rawDesc = "" +
 	"\n" +
-	"\x0frun_model.proto\"\xbd\a\n" +
+	"\x0frun_model.proto\"\xa8\v\n" +
 	"\tFinalData\x123\n" +
 	"\aweather\x18\x01 \x01(\v2\x19.FinalData.WeatherMetricsR\aweather\x12*\n" +
 	"\x05delta\x18\x02 \x01(\v2\x14.FinalData.DeltaDataR\x05delta\x12\x1d\n" +
@@ -602,7 +679,7 @@ const file_run_model_proto_rawDesc = "" +
 	"\favg_humidity\x18\x03 \x01(\x01R\vavgHumidity\x12(\n" +
 	"\x10humidity_std_dev\x18\x04 \x01(\x01R\x0ehumidityStdDev\x12/\n" +
 	"\x13total_precipitation\x18\x05 \x01(\x01R\x12totalPrecipitation\x120\n" +
-	"\x14dry_days_consecutive\x18\x06 \x01(\x05R\x12dryDaysConsecutive\x1a\xa1\x04\n" +
+	"\x14dry_days_consecutive\x18\x06 \x01(\x05R\x12dryDaysConsecutive\x1a\x8c\b\n" +
 	"\tDeltaData\x12\x16\n" +
 	"\x06forest\x18\x01 \x01(\tR\x06forest\x12\x12\n" +
 	"\x04plot\x18\x02 \x01(\tR\x04plot\x12\x1b\n" +
@@ -625,13 +702,35 @@ const file_run_model_proto_rawDesc = "" +
 	"\x0fndvi_derivative\x18\x11 \x01(\x01R\x0endviDerivative\x12\x14\n" +
 	"\x05label\x18\x12 \x01(\tR\x05label\x12\x1a\n" +
 	"\blatitude\x18\x13 \x01(\x01R\blatitude\x12\x1c\n" +
-	"\tlongitude\x18\x14 \x01(\x01R\tlongitude\"\x8e\x01\n" +
+	"\tlongitude\x18\x14 \x01(\x01R\tlongitude\x12;\n" +
+	"\aindexes\x18\x15 \x03(\v2!.FinalData.DeltaData.IndexesEntryR\aindexes\x12W\n" +
+	"\x11index_derivatives\x18\x16 \x03(\v2*.FinalData.DeltaData.IndexDerivativesEntryR\x10indexDerivatives\x12\x1a\n" +
+	"\bcoverage\x18\x17 \x01(\x01R\bcoverage\x12\x1e\n" +
+	"\n" +
+	"provenance\x18\x18 \x01(\tR\n" +
+	"provenance\x12\x19\n" +
+	"\bgap_days\x18\x19 \x01(\x05R\agapDays\x12>\n" +
+	"\bfeatures\x18\x1a \x03(\v2\".FinalData.DeltaData.FeaturesEntryR\bfeatures\x1a:\n" +
+	"\fIndexesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x01R\x05value:\x028\x01\x1aC\n" +
+	"\x15IndexDerivativesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x01R\x05value:\x028\x01\x1a;\n" +
+	"\rFeaturesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x01R\x05value:\x028\x01\"\xe5\x01\n" +
 	"\vPixelResult\x12\f\n" +
 	"\x01x\x18\x01 \x01(\x05R\x01x\x12\f\n" +
 	"\x01y\x18\x02 \x01(\x05R\x01y\x12\x1a\n" +
 	"\blatitude\x18\x03 \x01(\x01R\blatitude\x12\x1c\n" +
 	"\tlongitude\x18\x04 \x01(\x01R\tlongitude\x12)\n" +
-	"\x06result\x18\x05 \x03(\v2\x11.LabelProbabilityR\x06result\"J\n" +
+	"\x06result\x18\x05 \x03(\v2\x11.LabelProbabilityR\x06result\x12\x1a\n" +
+	"\bcoverage\x18\x06 \x01(\x01R\bcoverage\x12\x1e\n" +
+	"\n" +
+	"provenance\x18\a \x01(\tR\n" +
+	"provenance\x12\x19\n" +
+	"\bgap_days\x18\b \x01(\x05R\agapDays\"J\n" +
 	"\x10LabelProbability\x12\x14\n" +
 	"\x05label\x18\x01 \x01(\tR\x05label\x12 \n" +
 	"\vprobability\x18\x02 \x01(\x01R\vprobability\"G\n" +
@@ -656,7 +755,7 @@ func file_run_model_proto_rawDescGZIP() []byte {
 	return file_run_model_proto_rawDescData
 }
 
-var file_run_model_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
+var file_run_model_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
 var file_run_model_proto_goTypes = []any{
 	(*FinalData)(nil),                // 0: FinalData
 	(*PixelResult)(nil),              // 1: PixelResult
@@ -665,6 +764,9 @@ var file_run_model_proto_goTypes = []any{
 	(*RunModelResponse)(nil),         // 4: RunModelResponse
 	(*FinalData_WeatherMetrics)(nil), // 5: FinalData.WeatherMetrics
 	(*FinalData_DeltaData)(nil),      // 6: FinalData.DeltaData
+	nil,                              // 7: FinalData.DeltaData.IndexesEntry
+	nil,                              // 8: FinalData.DeltaData.IndexDerivativesEntry
+	nil,                              // 9: FinalData.DeltaData.FeaturesEntry
 }
 var file_run_model_proto_depIdxs = []int32{
 	5, // 0: FinalData.weather:type_name -> FinalData.WeatherMetrics
@@ -672,13 +774,16 @@ var file_run_model_proto_depIdxs = []int32{
 	2, // 2: PixelResult.result:type_name -> LabelProbability
 	0, // 3: RunModelRequest.data:type_name -> FinalData
 	1, // 4: RunModelResponse.results:type_name -> PixelResult
-	3, // 5: RunModelService.RunModel:input_type -> RunModelRequest
-	4, // 6: RunModelService.RunModel:output_type -> RunModelResponse
-	6, // [6:7] is the sub-list for method output_type
-	5, // [5:6] is the sub-list for method input_type
-	5, // [5:5] is the sub-list for extension type_name
-	5, // [5:5] is the sub-list for extension extendee
-	0, // [0:5] is the sub-list for field type_name
+	7, // 5: FinalData.DeltaData.indexes:type_name -> FinalData.DeltaData.IndexesEntry
+	8, // 6: FinalData.DeltaData.index_derivatives:type_name -> FinalData.DeltaData.IndexDerivativesEntry
+	9, // 7: FinalData.DeltaData.features:type_name -> FinalData.DeltaData.FeaturesEntry
+	3, // 8: RunModelService.RunModel:input_type -> RunModelRequest
+	4, // 9: RunModelService.RunModel:output_type -> RunModelResponse
+	9, // [9:10] is the sub-list for method output_type
+	8, // [8:9] is the sub-list for method input_type
+	8, // [8:8] is the sub-list for extension type_name
+	8, // [8:8] is the sub-list for extension extendee
+	0, // [0:8] is the sub-list for field type_name
 }
 
 func init() { file_run_model_proto_init() }
@@ -692,7 +797,7 @@ func file_run_model_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_run_model_proto_rawDesc), len(file_run_model_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   7,
+			NumMessages:   10,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -18,12 +18,20 @@ type LabelProbability struct {
 	Label       string
 	Probability float64
 }
+
+// PixelResult holds the label probabilities of a pixel. Coverage is the share of the pixel
+// inside the plot polygon, so edge pixels can be down-weighted. Provenance and GapDays tell
+// whether the pixel was observed on the predicted date or gap-filled, and how far its nearest
+// observation is, so predictions on estimates can be flagged.
 type PixelResult struct {
-	X         int32
-	Y         int32
-	Latitude  float64
-	Longitude float64
-	Result    []*LabelProbability
+	X          int32
+	Y          int32
+	Latitude   float64
+	Longitude  float64
+	Coverage   float64
+	Provenance string
+	GapDays    int32
+	Result     []*LabelProbability
 }
 
 func RunModel(model string, finalData []dataset.FinalData) ([]PixelResult, error) {
@@ -64,11 +72,14 @@ func convertToPixelResult(data []*protobufs.PixelResult) []PixelResult {
 			})
 		}
 		pixelResults = append(pixelResults, PixelResult{
-			X:         pixel.X,
-			Y:         pixel.Y,
-			Latitude:  pixel.Latitude,
-			Longitude: pixel.Longitude,
-			Result:    labelProbabilities,
+			X:          pixel.X,
+			Y:          pixel.Y,
+			Latitude:   pixel.Latitude,
+			Longitude:  pixel.Longitude,
+			Coverage:   pixel.Coverage,
+			Provenance: pixel.Provenance,
+			GapDays:    pixel.GapDays,
+			Result:     labelProbabilities,
 		})
 	}
 	return pixelResults
@@ -91,26 +102,32 @@ func convertToProtoFinalData(data []dataset.FinalData) []*protobufs.FinalData {
 				DryDaysConsecutive: int32(d.DryDaysConsecutive),
 			},
 			Delta: &protobufs.FinalData_DeltaData{
-				Forest:         d.Forest,
-				Plot:           d.Plot,
-				DeltaMin:       int32(d.DeltaMin),
-				DeltaMax:       int32(d.DeltaMax),
-				NdreDerivative: d.NDREDerivative,
-				NdmiDerivative: d.NDMIDerivative,
-				PsriDerivative: d.PSRIDerivative,
-				NdviDerivative: d.NDVIDerivative,
-				Ndre:           d.NDRE,
-				Ndmi:           d.NDMI,
-				Psri:           d.PSRI,
-				Ndvi:           d.NDVI,
-				Delta:          int32(d.Delta),
-				X:              int32(d.X),
-				Y:              int32(d.Y),
-				Latitude:       d.Latitude,
-				Longitude:      d.Longitude,
-				EndDate:        d.EndDate.Format(time.RFC3339),
-				Label:          label,
-				StartDate:      d.StartDate.Format(time.RFC3339),
+				Forest:           d.Forest,
+				Plot:             d.Plot,
+				DeltaMin:         int32(d.DeltaMin),
+				DeltaMax:         int32(d.DeltaMax),
+				NdreDerivative:   d.NDREDerivative,
+				NdmiDerivative:   d.NDMIDerivative,
+				PsriDerivative:   d.PSRIDerivative,
+				NdviDerivative:   d.NDVIDerivative,
+				Ndre:             d.NDRE,
+				Ndmi:             d.NDMI,
+				Psri:             d.PSRI,
+				Ndvi:             d.NDVI,
+				Delta:            int32(d.Delta),
+				X:                int32(d.X),
+				Y:                int32(d.Y),
+				Latitude:         d.Latitude,
+				Longitude:        d.Longitude,
+				EndDate:          d.EndDate.Format(time.RFC3339),
+				Label:            label,
+				StartDate:        d.StartDate.Format(time.RFC3339),
+				Indexes:          d.Indexes,
+				IndexDerivatives: d.IndexDerivatives,
+				Coverage:         d.Coverage,
+				Provenance:       d.Provenance.String(),
+				GapDays:          int32(d.GapDays),
+				Features:         d.Features,
 			},
 		})
 	}
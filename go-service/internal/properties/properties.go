@@ -1,6 +1,10 @@
 package properties
 
-import "os"
+import (
+	"os"
+	"runtime/debug"
+	"strconv"
+)
 
 func RootPath() string {
 	return os.Getenv("ROOT_PATH")
@@ -8,6 +12,41 @@ func RootPath() string {
 
 var GrpcPort int
 
+// version may be set at build time with
+// -ldflags "-X github.com/forest-guardian/forest-guardian-api-poc/internal/properties.version=v1.2.0"
+var version string
+
+// Version identifies the CLI build: the version set at build time, or else the VCS revision it
+// was built from, suffixed "-dirty" when the tree had uncommitted changes, or "unknown".
+func Version() string {
+	if version != "" {
+		return version
+	}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	revision, dirty := "", false
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			revision = setting.Value
+		case "vcs.modified":
+			dirty = setting.Value == "true"
+		}
+	}
+	if revision == "" {
+		if info.Main.Version != "" && info.Main.Version != "(devel)" {
+			return info.Main.Version
+		}
+		return "unknown"
+	}
+	if dirty {
+		revision += "-dirty"
+	}
+	return revision
+}
+
 type Color struct {
 	R, G, B uint8
 }
@@ -20,6 +59,40 @@ var ColorMap = map[string]Color{
 	"Saudavel": {0, 0, 255},     // blue
 }
 
+// ImageProvider selects where GetImages fetches imagery from: "sentinelhub" (default) or "local".
+func ImageProvider() string {
+	return os.Getenv("IMAGE_PROVIDER")
+}
+
+// LocalImagesPath is the directory tree served by the local image provider.
+func LocalImagesPath() string {
+	return os.Getenv("LOCAL_IMAGES_PATH")
+}
+
+// CopernicusRequestsPerMinute is the request budget of each Copernicus credential (default 60).
+func CopernicusRequestsPerMinute() int {
+	return intEnv("COPERNICUS_REQUESTS_PER_MINUTE", 60)
+}
+
+// ImageDownloadWorkers is the number of images downloaded in parallel (default 4).
+func ImageDownloadWorkers() int {
+	return intEnv("IMAGE_DOWNLOAD_WORKERS", 4)
+}
+
+// IndexExtractionWorkers is the number of images whose indices are computed in parallel when
+// building a pixel dataset (default 4). Each worker reads its own GDAL dataset.
+func IndexExtractionWorkers() int {
+	return intEnv("INDEX_EXTRACTION_WORKERS", 4)
+}
+
+func intEnv(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || value <= 0 {
+		return defaultValue
+	}
+	return value
+}
+
 func DiscordErrorNotificationUrl() string {
 	return os.Getenv("DISCORD_ERROR_NOTIFICATION_URL")
 }
@@ -0,0 +1,102 @@
+package sentinel
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/airbusgeo/godal"
+)
+
+// boaAddOffset is the BOA_ADD_OFFSET of Sentinel-2 L2A products processed with baseline 04.00
+// or later, -1000 DN at the 10000 quantification value, in reflectance units.
+const boaAddOffset = -0.1
+
+// boaOffsetStart is the day baseline 04.00 went operational. It is only used to guess the
+// baseline of scenes whose product name is unknown: reprocessed archives carry the offset
+// before this date as well.
+var boaOffsetStart = time.Date(2022, 1, 25, 0, 0, 0, 0, time.UTC)
+
+// baselinePattern extracts the processing baseline ("N0400") from a Sentinel-2 product name.
+var baselinePattern = regexp.MustCompile(`_N(\d{2})(\d{2})_`)
+
+// BaselineHarmonization records the reflectance offset removed from a Sentinel-2 image so
+// values are comparable across the processing baseline 04.00 boundary.
+type BaselineHarmonization struct {
+	ProcessingBaseline string `json:"processing_baseline"`
+	// Inferred is set when the baseline was guessed from the sensing date
+	Inferred bool `json:"inferred,omitempty"`
+	// Offset was added to every reflectance band; 0 when the baseline carries no offset
+	Offset float64 `json:"offset"`
+}
+
+// productBaseline returns the processing baseline of a product name as "04.00", or "" if
+// the name holds none.
+func productBaseline(productID string) string {
+	match := baselinePattern.FindStringSubmatch(productID)
+	if match == nil {
+		return ""
+	}
+	return match[1] + "." + match[2]
+}
+
+// baselineHarmonization returns the correction needed by a Sentinel-2 acquisition, or nil for
+// other sensors.
+func baselineHarmonization(acquisition Acquisition) *BaselineHarmonization {
+	if sensorOrDefault(acquisition.Sensor) != SensorSentinel2 {
+		return nil
+	}
+	harmonization := &BaselineHarmonization{ProcessingBaseline: acquisition.ProcessingBaseline}
+	if harmonization.ProcessingBaseline == "" {
+		harmonization.Inferred = true
+		harmonization.ProcessingBaseline = "03.01"
+		if !acquisition.SensingTime.Before(boaOffsetStart) {
+			harmonization.ProcessingBaseline = "04.00"
+		}
+	}
+	if baseline, err := strconv.ParseFloat(harmonization.ProcessingBaseline, 64); err == nil && baseline >= 4 {
+		harmonization.Offset = boaAddOffset
+	}
+	return harmonization
+}
+
+// applyHarmonization adds the offset to the reflectance bands of the image at path, clamping
+// at 0 as the L2A product specification does. Cloud, scene class and radar bands are left as is.
+func applyHarmonization(path string, harmonization BaselineHarmonization) error {
+	if harmonization.Offset == 0 {
+		return nil
+	}
+	godal.RegisterAll()
+	ds, err := godal.Open(path, godal.Update())
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %v", path, err)
+	}
+	defer ds.Close()
+
+	names, err := imageBandNames(ds)
+	if err != nil {
+		return err
+	}
+	for i, band := range ds.Bands() {
+		if !slices.Contains(sentinel2Bands, names[i]) {
+			continue
+		}
+		width, height := band.Structure().SizeX, band.Structure().SizeY
+		data := make([]float64, width*height)
+		if err := band.Read(0, 0, data, width, height); err != nil {
+			return fmt.Errorf("failed to read band %s of %s: %v", names[i], path, err)
+		}
+		for j, value := range data {
+			if !math.IsNaN(value) {
+				data[j] = math.Max(value+harmonization.Offset, 0)
+			}
+		}
+		if err := band.Write(0, 0, data, width, height); err != nil {
+			return fmt.Errorf("failed to write band %s of %s: %v", names[i], path, err)
+		}
+	}
+	return nil
+}
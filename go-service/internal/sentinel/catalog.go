@@ -0,0 +1,163 @@
+package sentinel
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Acquisition is a single scene returned by a provider catalog search.
+type Acquisition struct {
+	SensingTime time.Time
+	TileID      string
+	CloudCover  float64
+	Sensor      Sensor
+	// ProcessingBaseline is the Sentinel-2 processing baseline, such as "05.10", when known
+	ProcessingBaseline string
+}
+
+var tileIDPattern = regexp.MustCompile(`_T(\d{2}[A-Z]{3})_`)
+
+type catalogFeature struct {
+	ID         string `json:"id"`
+	Properties struct {
+		Datetime   string  `json:"datetime"`
+		CloudCover float64 `json:"eo:cloud_cover"`
+	} `json:"properties"`
+}
+
+type catalogResponse struct {
+	Features []catalogFeature `json:"features"`
+	Context  struct {
+		Next *int `json:"next"`
+	} `json:"context"`
+}
+
+// Search lists the Sentinel-2 L2A, or Landsat 8/9 L2, scenes intersecting the request geometry.
+func (p *SentinelHubProvider) Search(request ImageRequest) ([]Acquisition, error) {
+	if request.Sensor == SensorLandsat {
+		acquisitions, err := searchCatalog(request, "landsat-ot-l2")
+		for i := range acquisitions {
+			acquisitions[i].Sensor = SensorLandsat
+		}
+		return acquisitions, err
+	}
+	acquisitions, err := searchCatalog(request, "sentinel-2-l2a")
+	for i := range acquisitions {
+		acquisitions[i].Sensor = SensorSentinel2
+	}
+	return acquisitions, err
+}
+
+// searchCatalog lists the scenes of collection intersecting the request geometry using the
+// Copernicus Data Space STAC catalog, following the pagination cursor until exhausted.
+func searchCatalog(request ImageRequest, collection string) ([]Acquisition, error) {
+	geometryGeojson, err := request.Geometry.GeoJSON()
+	if err != nil {
+		return nil, fmt.Errorf("failed to export geometry to GeoJSON: %w", err)
+	}
+	var geojsonMap map[string]interface{}
+	if err := json.Unmarshal([]byte(geometryGeojson), &geojsonMap); err != nil {
+		return nil, fmt.Errorf("failed to parse GeoJSON: %w", err)
+	}
+
+	var acquisitions []Acquisition
+	next := 0
+	for {
+		requestPayload := map[string]interface{}{
+			"collections": []string{collection},
+			"datetime":    fmt.Sprintf("%s/%s", request.StartDate.UTC().Format(time.RFC3339), request.EndDate.UTC().Format(time.RFC3339)),
+			"intersects":  geojsonMap,
+			"limit":       100,
+			"fields": map[string]interface{}{
+				"include": []string{"id", "properties.datetime", "properties.eo:cloud_cover"},
+				"exclude": []string{},
+			},
+		}
+		if next > 0 {
+			requestPayload["next"] = next
+		}
+
+		requestBody, err := json.Marshal(requestPayload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal catalog payload: %v", err)
+		}
+
+		responseContent, err := postWithCredentials(catalogURL, requestBody)
+		if err != nil {
+			return nil, fmt.Errorf("failed to search catalog: %v", err)
+		}
+
+		var response catalogResponse
+		if err := json.Unmarshal(responseContent, &response); err != nil {
+			return nil, fmt.Errorf("invalid catalog response: %v", err)
+		}
+
+		for _, feature := range response.Features {
+			sensingTime, err := time.Parse(time.RFC3339, feature.Properties.Datetime)
+			if err != nil {
+				return nil, fmt.Errorf("invalid datetime %q for scene %s: %v", feature.Properties.Datetime, feature.ID, err)
+			}
+			tileID := ""
+			if match := tileIDPattern.FindStringSubmatch(feature.ID); match != nil {
+				tileID = match[1]
+			}
+			acquisitions = append(acquisitions, Acquisition{
+				SensingTime:        sensingTime,
+				TileID:             tileID,
+				CloudCover:         feature.Properties.CloudCover,
+				ProcessingBaseline: productBaseline(feature.ID),
+			})
+		}
+
+		if response.Context.Next == nil || len(response.Features) == 0 {
+			break
+		}
+		next = *response.Context.Next
+	}
+
+	return acquisitions, nil
+}
+
+// groupAcquisitionsByDay merges scenes sensed on the same UTC day (overlapping tiles or
+// orbits) into a single acquisition, keeping the latest sensing time and its processing
+// baseline as the process API "mostRecent" mosaic would, and the lowest scene cloud cover.
+// The result is sorted by date. Scenes of a single sensor are expected.
+func groupAcquisitionsByDay(acquisitions []Acquisition) []Acquisition {
+	byDay := make(map[string]*Acquisition)
+	tiles := make(map[string][]string)
+	for _, acquisition := range acquisitions {
+		day := acquisition.SensingTime.UTC().Format("2006-01-02")
+		if acquisition.TileID != "" && !contains(tiles[day], acquisition.TileID) {
+			tiles[day] = append(tiles[day], acquisition.TileID)
+		}
+
+		existing, ok := byDay[day]
+		if !ok {
+			a := acquisition
+			byDay[day] = &a
+			continue
+		}
+		if acquisition.SensingTime.After(existing.SensingTime) {
+			existing.SensingTime = acquisition.SensingTime
+			existing.ProcessingBaseline = acquisition.ProcessingBaseline
+		}
+		if acquisition.CloudCover < existing.CloudCover {
+			existing.CloudCover = acquisition.CloudCover
+		}
+	}
+
+	grouped := make([]Acquisition, 0, len(byDay))
+	for day, acquisition := range byDay {
+		sort.Strings(tiles[day])
+		acquisition.TileID = strings.Join(tiles[day], ",")
+		grouped = append(grouped, *acquisition)
+	}
+	sort.Slice(grouped, func(i, j int) bool {
+		return grouped[i].SensingTime.Before(grouped[j].SensingTime)
+	})
+	return grouped
+}
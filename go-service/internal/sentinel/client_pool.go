@@ -0,0 +1,220 @@
+package sentinel
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/forest-guardian/forest-guardian-api-poc/internal/properties"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+// tokenBucket is a minimal token-bucket limiter: it holds up to capacity tokens and
+// refills at rate tokens per second.
+type tokenBucket struct {
+	mu       sync.Mutex
+	capacity float64
+	tokens   float64
+	rate     float64
+	last     time.Time
+}
+
+func newTokenBucket(requestsPerMinute int) *tokenBucket {
+	capacity := float64(requestsPerMinute)
+	return &tokenBucket{
+		capacity: capacity,
+		tokens:   capacity,
+		rate:     capacity / 60,
+		last:     time.Now(),
+	}
+}
+
+// wait blocks until a token is available and consumes it.
+func (b *tokenBucket) wait() {
+	for {
+		b.mu.Lock()
+		now := time.Now()
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.capacity {
+			b.tokens = b.capacity
+		}
+		b.last = now
+		if b.tokens >= 1 {
+			b.tokens--
+			b.mu.Unlock()
+			return
+		}
+		delay := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+		b.mu.Unlock()
+		time.Sleep(delay)
+	}
+}
+
+type pooledClient struct {
+	id           int
+	httpClient   *http.Client
+	bucket       *tokenBucket
+	blockedUntil time.Time
+	disabled     bool
+}
+
+// clientPool holds one OAuth client per Copernicus credential. The HTTP clients keep
+// their tokens cached between requests, and every credential has its own request budget.
+// Requests are spread round-robin; a credential answering 429 is parked until its
+// Retry-After expires and a credential answering 401/403 is dropped from the pool.
+type clientPool struct {
+	mu      sync.Mutex
+	clients []*pooledClient
+	next    int
+}
+
+var (
+	defaultPool     *clientPool
+	defaultPoolErr  error
+	defaultPoolOnce sync.Once
+)
+
+// getClientPool returns the process-wide pool built from the COPERNICUS_* environment variables.
+func getClientPool() (*clientPool, error) {
+	defaultPoolOnce.Do(func() {
+		defaultPool, defaultPoolErr = newClientPool()
+	})
+	return defaultPool, defaultPoolErr
+}
+
+func newClientPool() (*clientPool, error) {
+	// OAuth2 configuration from environment variables
+	clientIDs := os.Getenv("COPERNICUS_CLIENT_ID")
+	clientSecrets := os.Getenv("COPERNICUS_CLIENT_SECRET")
+	tokenURL := os.Getenv("COPERNICUS_TOKEN_URL")
+
+	if clientIDs == "" || clientSecrets == "" || tokenURL == "" {
+		return nil, fmt.Errorf("missing required environment variables: COPERNICUS_CLIENT_ID, COPERNICUS_CLIENT_SECRET, or COPERNICUS_TOKEN_URL")
+	}
+
+	clientIDList := strings.Split(clientIDs, ",")
+	clientSecretList := strings.Split(clientSecrets, ",")
+	if len(clientIDList) != len(clientSecretList) {
+		return nil, fmt.Errorf("mismatched number of client IDs and secrets")
+	}
+
+	pool := &clientPool{}
+	for i, clientID := range clientIDList {
+		config := &clientcredentials.Config{
+			ClientID:     strings.TrimSpace(clientID),
+			ClientSecret: strings.TrimSpace(clientSecretList[i]),
+			TokenURL:     tokenURL,
+		}
+		pool.clients = append(pool.clients, &pooledClient{
+			id:         i + 1,
+			httpClient: config.Client(context.Background()),
+			bucket:     newTokenBucket(properties.CopernicusRequestsPerMinute()),
+		})
+	}
+	return pool, nil
+}
+
+// acquire picks the next usable client and waits for its request budget.
+func (p *clientPool) acquire() (*pooledClient, error) {
+	for {
+		p.mu.Lock()
+		now := time.Now()
+		var earliest time.Time
+		for i := 0; i < len(p.clients); i++ {
+			client := p.clients[(p.next+i)%len(p.clients)]
+			if client.disabled {
+				continue
+			}
+			if now.Before(client.blockedUntil) {
+				if earliest.IsZero() || client.blockedUntil.Before(earliest) {
+					earliest = client.blockedUntil
+				}
+				continue
+			}
+			p.next = (p.next + i + 1) % len(p.clients)
+			p.mu.Unlock()
+			client.bucket.wait()
+			return client, nil
+		}
+		p.mu.Unlock()
+
+		if earliest.IsZero() {
+			return nil, fmt.Errorf("unauthorized access for every client, check your client IDs and secrets")
+		}
+		time.Sleep(time.Until(earliest))
+	}
+}
+
+func (p *clientPool) block(client *pooledClient, until time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if until.After(client.blockedUntil) {
+		client.blockedUntil = until
+	}
+}
+
+func (p *clientPool) disable(client *pooledClient) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	client.disabled = true
+}
+
+// post sends requestBody to url through the pool, retrying up to three times per credential.
+func (p *clientPool) post(url string, requestBody []byte) ([]byte, error) {
+	retries := 3 * len(p.clients)
+	var err error
+	for attempt := 1; attempt <= retries; attempt++ {
+		client, acquireErr := p.acquire()
+		if acquireErr != nil {
+			return nil, acquireErr
+		}
+
+		response, postErr := client.httpClient.Post(url, "application/json", bytes.NewReader(requestBody))
+		if postErr != nil {
+			err = postErr
+			fmt.Printf("Client %d - Attempt %d failed: %v\n", client.id, attempt, err)
+			time.Sleep(2 * time.Second)
+			continue
+		}
+
+		body, readErr := io.ReadAll(response.Body)
+		response.Body.Close()
+
+		switch {
+		case response.StatusCode == http.StatusOK:
+			if readErr != nil {
+				err = fmt.Errorf("failed to read response body: %v", readErr)
+				continue
+			}
+			return body, nil
+		case response.StatusCode == http.StatusTooManyRequests:
+			// Rate limited: park this credential and move on to the next one
+			p.block(client, time.Now().Add(retryAfter(response)))
+			err = fmt.Errorf("rate limit exceeded for client %d", client.id)
+		case response.StatusCode == http.StatusUnauthorized || response.StatusCode == http.StatusForbidden:
+			p.disable(client)
+			err = fmt.Errorf("unauthorized access for client %d, check your client ID and secret", client.id)
+		default:
+			err = fmt.Errorf("status %d: %s", response.StatusCode, string(body))
+			fmt.Printf("Client %d - Attempt %d failed: %s\n", client.id, attempt, string(body))
+			time.Sleep(2 * time.Second)
+		}
+	}
+
+	return nil, fmt.Errorf("request failed after %d attempts: %v", retries, err)
+}
+
+// retryAfter reads the Retry-After header in seconds, defaulting to 10 seconds.
+func retryAfter(response *http.Response) time.Duration {
+	if seconds, err := strconv.Atoi(response.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return 10 * time.Second
+}
@@ -0,0 +1,168 @@
+package sentinel
+
+import (
+	"fmt"
+
+	"github.com/airbusgeo/godal"
+	"github.com/forest-guardian/forest-guardian-api-poc/internal/config"
+)
+
+// PixelCoverage classifies a pixel by its overlap with the plot polygon.
+type PixelCoverage string
+
+var (
+	PixelCoverageOutside  PixelCoverage = "outside"
+	PixelCoverageEdge     PixelCoverage = "edge"
+	PixelCoverageInterior PixelCoverage = "interior"
+)
+
+// coverageSupersampling is the number of subpixels per pixel side burned to estimate the
+// share of each pixel inside the polygon.
+const coverageSupersampling = 8
+
+// CoverageClass classifies a coverage fraction.
+func CoverageClass(fraction float64) PixelCoverage {
+	switch {
+	case fraction <= 0:
+		return PixelCoverageOutside
+	case fraction >= 1:
+		return PixelCoverageInterior
+	default:
+		return PixelCoverageEdge
+	}
+}
+
+// PlotCoverage returns, for every pixel of the image, the fraction of the pixel inside the
+// plot geometry, shrunk inward by the configured buffer. The geometry is in WGS84, as read
+// from the plot GeoJSON. The fraction is computed by rasterizing the polygon on a grid
+// coverageSupersampling times finer than the image and counting the burned subpixels.
+func PlotCoverage(image *godal.Dataset, geometry *godal.Geometry) ([][]float64, error) {
+	cfg, err := config.Get()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Coverage.BufferMeters < 0 {
+		return nil, fmt.Errorf("coverage buffer_meters must not be negative in %s", config.Path())
+	}
+
+	geoTransform, err := image.GeoTransform()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get GeoTransform: %w", err)
+	}
+	imageSR := image.SpatialRef()
+	defer imageSR.Close()
+
+	polygon, err := bufferedPolygon(geometry, cfg.Coverage.BufferMeters)
+	if err != nil {
+		return nil, err
+	}
+	defer polygon.Close()
+	if err := polygon.Reproject(imageSR); err != nil {
+		return nil, fmt.Errorf("failed to reproject plot geometry: %v", err)
+	}
+
+	width := image.Structure().SizeX
+	height := image.Structure().SizeY
+	k := coverageSupersampling
+	mask, err := godal.Create(godal.Memory, "", 1, godal.Byte, width*k, height*k)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create coverage mask: %v", err)
+	}
+	defer mask.Close()
+
+	maskTransform := geoTransform
+	for _, i := range []int{1, 2, 4, 5} {
+		maskTransform[i] /= float64(k)
+	}
+	if err := mask.SetGeoTransform(maskTransform); err != nil {
+		return nil, err
+	}
+	if err := mask.SetSpatialRef(imageSR); err != nil {
+		return nil, err
+	}
+	if polygon.Empty() {
+		// The buffer swallowed the whole plot, every pixel is outside
+		return make2D(height, width), nil
+	}
+	if err := mask.RasterizeGeometry(polygon, godal.Values(1)); err != nil {
+		return nil, fmt.Errorf("failed to rasterize plot geometry: %v", err)
+	}
+
+	burned := make([]float64, width*k*height*k)
+	if err := mask.Bands()[0].Read(0, 0, burned, width*k, height*k); err != nil {
+		return nil, err
+	}
+
+	coverage := make2D(height, width)
+	for i, value := range burned {
+		if value > 0 {
+			row, col := i/(width*k), i%(width*k)
+			coverage[row/k][col/k]++
+		}
+	}
+	for y := range coverage {
+		for x := range coverage[y] {
+			coverage[y][x] /= float64(k * k)
+		}
+	}
+	return coverage, nil
+}
+
+// bufferedPolygon returns a WGS84 copy of geometry shrunk inward by bufferMeters, buffered in
+// the UTM zone of the geometry so the distance is in meters.
+func bufferedPolygon(geometry *godal.Geometry, bufferMeters float64) (*godal.Geometry, error) {
+	wgs84, err := godal.NewSpatialRefFromEPSG(4326)
+	if err != nil {
+		return nil, err
+	}
+	defer wgs84.Close()
+
+	wkb, err := geometry.WKB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to export plot geometry: %v", err)
+	}
+	polygon, err := godal.NewGeometryFromWKB(wkb, wgs84)
+	if err != nil {
+		return nil, err
+	}
+	if bufferMeters == 0 {
+		return polygon, nil
+	}
+	defer polygon.Close()
+
+	bounds, err := polygon.Bounds()
+	if err != nil {
+		return nil, err
+	}
+	utm, err := utmSpatialRef((bounds[0]+bounds[2])/2, (bounds[1]+bounds[3])/2)
+	if err != nil {
+		return nil, err
+	}
+	defer utm.Close()
+	if err := polygon.Reproject(utm); err != nil {
+		return nil, fmt.Errorf("failed to project plot geometry to UTM: %v", err)
+	}
+	buffered, err := polygon.Buffer(-bufferMeters, 8)
+	if err != nil {
+		return nil, fmt.Errorf("failed to buffer plot geometry: %v", err)
+	}
+	buffered.SetSpatialRef(utm)
+	if err := buffered.Reproject(wgs84); err != nil {
+		buffered.Close()
+		return nil, err
+	}
+	return buffered, nil
+}
+
+// utmSpatialRef returns the WGS84 UTM zone containing the point.
+func utmSpatialRef(lon, lat float64) (*godal.SpatialRef, error) {
+	return godal.NewSpatialRefFromEPSG(utmEPSG(lon, lat))
+}
+
+func make2D(rows, cols int) [][]float64 {
+	plane := make([][]float64, rows)
+	for i := range plane {
+		plane[i] = make([]float64, cols)
+	}
+	return plane
+}
@@ -0,0 +1,250 @@
+package sentinel
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/airbusgeo/godal"
+	"github.com/forest-guardian/forest-guardian-api-poc/internal/properties"
+)
+
+// ForestProvider requests imagery once for the merged extent of a forest and clips every
+// plot from the forest-level raster, so adjacent plots no longer pay for the same scene.
+// Forest rasters are kept in data/images/forest_<forest>; searches are shared between the
+// plots of a forest as well.
+type ForestProvider struct {
+	upstream ImageProvider
+
+	mu         sync.Mutex
+	geometries map[string]*godal.Geometry
+	searches   map[string][]Acquisition
+	fileLocks  map[string]*sync.Mutex
+}
+
+func NewForestProvider(upstream ImageProvider) *ForestProvider {
+	return &ForestProvider{
+		upstream:   upstream,
+		geometries: make(map[string]*godal.Geometry),
+		searches:   make(map[string][]Acquisition),
+		fileLocks:  make(map[string]*sync.Mutex),
+	}
+}
+
+// ForestImagePath returns the folder of the forest-level rasters.
+func ForestImagePath(forest string) string {
+	return fmt.Sprintf("%s/data/images/forest_%s", properties.RootPath(), forest)
+}
+
+func (p *ForestProvider) Search(request ImageRequest) ([]Acquisition, error) {
+	return p.search(request, "optical", p.upstream.Search)
+}
+
+// unharmonizedKey marks the Sentinel-2 forest rasters requested without the process API's
+// harmonization, as GetImages corrects the processing baseline itself. Rasters cached before
+// the correction lack it and are requested again.
+const unharmonizedKey = "FOREST_GUARDIAN_HARMONIZE_VALUES"
+
+func (p *ForestProvider) FetchImage(request ImageRequest, outputPath string) error {
+	suffix := ""
+	if request.Sensor == SensorLandsat {
+		suffix = "_landsat"
+	}
+	return p.fetch(request, outputPath, suffix, sensorOrDefault(request.Sensor) == SensorSentinel2, p.upstream.FetchImage)
+}
+
+// SearchSAR lists the radar acquisitions of the forest extent. Upstream providers without
+// Sentinel-1 find none.
+func (p *ForestProvider) SearchSAR(request ImageRequest) ([]Acquisition, error) {
+	sarProvider, ok := p.upstream.(SARProvider)
+	if !ok {
+		fmt.Println("Sentinel-1 is enabled but the image provider does not serve it, radar features will be empty")
+		return nil, nil
+	}
+	return p.search(request, "sar", sarProvider.SearchSAR)
+}
+
+func (p *ForestProvider) FetchSAR(request ImageRequest, outputPath string) error {
+	sarProvider, ok := p.upstream.(SARProvider)
+	if !ok {
+		return ErrImageNotFound
+	}
+	return p.fetch(request, outputPath, "_s1", false, sarProvider.FetchSAR)
+}
+
+func (p *ForestProvider) forestGeometry(forest string) (*godal.Geometry, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if geometry, ok := p.geometries[forest]; ok {
+		return geometry, nil
+	}
+	geometry, err := GetForestGeometry(forest)
+	if err != nil {
+		return nil, err
+	}
+	p.geometries[forest] = geometry
+	return geometry, nil
+}
+
+// search runs the upstream search over the forest extent once per forest, sensor and range.
+func (p *ForestProvider) search(request ImageRequest, kind string, search func(ImageRequest) ([]Acquisition, error)) ([]Acquisition, error) {
+	key := fmt.Sprintf("%s|%s|%s|%s|%s", request.Forest, kind, sensorOrDefault(request.Sensor), request.StartDate.Format(time.RFC3339), request.EndDate.Format(time.RFC3339))
+	lock := p.lock(key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	p.mu.Lock()
+	acquisitions, ok := p.searches[key]
+	p.mu.Unlock()
+	if ok {
+		return acquisitions, nil
+	}
+
+	forestRequest, err := p.forestRequest(request)
+	if err != nil {
+		return nil, err
+	}
+	acquisitions, err = search(forestRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	p.searches[key] = acquisitions
+	p.mu.Unlock()
+	return acquisitions, nil
+}
+
+// fetch makes sure the forest raster of the requested day exists, then clips the plot from it.
+// unharmonized rasters are marked with unharmonizedKey, and only reused when they carry it.
+func (p *ForestProvider) fetch(request ImageRequest, outputPath, suffix string, unharmonized bool, fetch func(ImageRequest, string) error) error {
+	forestPath := ForestImagePath(request.Forest)
+	if err := os.MkdirAll(forestPath, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create directory %s: %v", forestPath, err)
+	}
+	rasterPath := filepath.Join(forestPath, fmt.Sprintf("%s%s.tif", startOfDay(request.StartDate.UTC()).Format("2006-01-02"), suffix))
+
+	// Concurrent plots of the same forest wait for the first one to download the raster
+	lock := p.lock(rasterPath)
+	lock.Lock()
+	if !forestRasterReady(rasterPath, request.Bands, unharmonized) {
+		forestRequest, err := p.forestRequest(request)
+		if err != nil {
+			lock.Unlock()
+			return err
+		}
+		tempPath := rasterPath + ".temp"
+		err = fetch(forestRequest, tempPath)
+		if err == nil && unharmonized {
+			err = markUnharmonized(tempPath)
+		}
+		if err == nil {
+			err = os.Rename(tempPath, rasterPath)
+		}
+		if err != nil {
+			os.Remove(tempPath)
+			lock.Unlock()
+			return err
+		}
+	}
+	lock.Unlock()
+
+	if err := clipToGeometry(rasterPath, outputPath, request.Geometry); err != nil {
+		return fmt.Errorf("failed to clip plot %s from %s: %v", request.Plot, rasterPath, err)
+	}
+	return setBandDescriptions(outputPath, request.Bands)
+}
+
+func (p *ForestProvider) forestRequest(request ImageRequest) (ImageRequest, error) {
+	geometry, err := p.forestGeometry(request.Forest)
+	if err != nil {
+		return ImageRequest{}, err
+	}
+	request.Geometry = geometry
+	return request, nil
+}
+
+func (p *ForestProvider) lock(key string) *sync.Mutex {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	lock, ok := p.fileLocks[key]
+	if !ok {
+		lock = &sync.Mutex{}
+		p.fileLocks[key] = lock
+	}
+	return lock
+}
+
+// forestRasterReady reports whether the forest raster exists and holds the requested bands,
+// and when unharmonized, whether it was requested without harmonization.
+func forestRasterReady(path string, bands []string, unharmonized bool) bool {
+	if _, err := os.Stat(path); err != nil {
+		return false
+	}
+	ds, err := godal.Open(path)
+	if err != nil {
+		return false
+	}
+	defer ds.Close()
+	if unharmonized && ds.Metadata(unharmonizedKey) != "false" {
+		return false
+	}
+	return hasBands(ds, bands)
+}
+
+// markUnharmonized records on the raster at path that it was requested without harmonization.
+func markUnharmonized(path string) error {
+	godal.RegisterAll()
+	ds, err := godal.Open(path, godal.Update())
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %v", path, err)
+	}
+	defer ds.Close()
+	if err := ds.SetMetadata(unharmonizedKey, "false"); err != nil {
+		return fmt.Errorf("failed to mark %s as unharmonized: %v", path, err)
+	}
+	return nil
+}
+
+// clipToGeometry cuts the pixels of geometry out of the raster at sourcePath into outputPath.
+// Pixels outside the geometry are 0, as in images requested for the plot alone.
+func clipToGeometry(sourcePath, outputPath string, geometry *godal.Geometry) error {
+	geometryGeojson, err := geometry.GeoJSON()
+	if err != nil {
+		return fmt.Errorf("failed to export geometry to GeoJSON: %w", err)
+	}
+	cutline, err := json.Marshal(map[string]interface{}{
+		"type": "FeatureCollection",
+		"features": []map[string]interface{}{
+			{
+				"type":       "Feature",
+				"properties": map[string]interface{}{},
+				"geometry":   json.RawMessage(geometryGeojson),
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	cutlinePath := outputPath + ".cutline.geojson"
+	if err := os.WriteFile(cutlinePath, cutline, 0644); err != nil {
+		return fmt.Errorf("failed to write cutline: %v", err)
+	}
+	defer os.Remove(cutlinePath)
+
+	godal.RegisterAll()
+	source, err := godal.Open(sourcePath)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %v", sourcePath, err)
+	}
+	defer source.Close()
+
+	clipped, err := source.Warp(outputPath, []string{"-cutline", cutlinePath, "-crop_to_cutline"}, godal.GTiff)
+	if err != nil {
+		return err
+	}
+	return clipped.Close()
+}
@@ -66,6 +66,55 @@ func GetGeometryFromGeoJSON(forest, plot string) (*godal.Geometry, error) {
 	return nil, fmt.Errorf("geometry not found for forest %s and plot %s", forest, plot)
 }
 
+// GetForestGeometry returns the union of every plot geometry of the forest.
+func GetForestGeometry(forest string) (*godal.Geometry, error) {
+	filePath := fmt.Sprintf("%s/data/geojsons/%s.geojson", properties.RootPath(), forest)
+
+	godal.RegisterInternalDrivers()
+	ds, err := godal.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer ds.Close()
+
+	var merged *godal.Geometry
+	layer := ds.Layers()[0]
+	for {
+		feat := layer.NextFeature()
+		if feat == nil {
+			break
+		}
+		defer feat.Close()
+
+		if _, ok := feat.Fields()["plot_id"]; !ok {
+			continue
+		}
+
+		geom := feat.Geometry()
+		wkb, _ := geom.WKB()
+		plotGeometry, err := godal.NewGeometryFromWKB(wkb, geom.SpatialRef())
+		if err != nil {
+			return nil, err
+		}
+		if merged == nil {
+			merged = plotGeometry
+			continue
+		}
+		union, err := merged.Union(plotGeometry)
+		merged.Close()
+		plotGeometry.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to merge plot geometries of forest %s: %v", forest, err)
+		}
+		merged = union
+	}
+
+	if merged == nil {
+		return nil, fmt.Errorf("no plot geometry found for forest %s", forest)
+	}
+	return merged, nil
+}
+
 func getAllPlotsAndGeometries(forest string) ([]map[string]interface{}, error) {
 	filePath := fmt.Sprintf("geojsons/%s.geojson", forest)
 	file, err := os.Open(filePath)
@@ -1,20 +1,27 @@
 package sentinel
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
-	"math"
 	"os"
 	"path/filepath"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/airbusgeo/godal"
 	"github.com/forest-guardian/forest-guardian-api-poc/internal/properties"
+	"github.com/gammazero/workerpool"
 	"github.com/schollz/progressbar/v3"
 )
 
 type Bands struct {
 	NDMI, CLD, SCL, NDRE, PSRI, B02, B04, NDVI float64
+	// QAPixel replaces CLD and SCL on Landsat images
+	QAPixel float64
+	Sensor  Sensor
+	// NearCloud is set on pixels within the quality policy's dilation radius of a cloud or shadow
+	NearCloud bool
 }
 type PixelStatus string
 
@@ -25,135 +32,85 @@ var (
 	PixelStatusTreatable PixelStatus = "treatable"
 )
 
-func reprojectAutoUTM(inputPath, outputPath string) error {
-	// Register drivers and open source GeoTIFF
-	godal.RegisterAll()
-	ds, err := godal.Open(inputPath, godal.ErrLogger(func(ec godal.ErrorCategory, code int, msg string) error {
-		if ec == godal.CE_Warning {
-			return nil
-		}
-		return fmt.Errorf("error opening dataset: %s", msg)
-	}))
-	if err != nil {
-		return err
-	}
-	defer ds.Close()
-
-	// Inspect current projection
-	sr := ds.SpatialRef()
-	defer sr.Close()
-
-	// Compute image center in source CRS
-	bounds, err := ds.Bounds()
-	if err != nil {
-		return err
-	}
-	centerX := (bounds[0] + bounds[2]) / 2.0
-	centerY := (bounds[1] + bounds[3]) / 2.0
-
-	// Transform to WGS84 lat/lon if needed
-	var lon, lat float64
-	if !sr.EPSGTreatsAsLatLong() {
-		dstSR, _ := godal.NewSpatialRefFromEPSG(4326)
-		defer dstSR.Close()
-		tr, _ := godal.NewTransform(sr, dstSR)
-		defer tr.Close()
-		xs := []float64{centerX}
-		ys := []float64{centerY}
-		err := tr.TransformEx(xs, ys, nil, nil)
-		if err != nil {
-			panic(err)
-		}
-		lon = xs[0]
-		lat = ys[0]
-	} else {
-		lon, lat = centerX, centerY
-	}
-
-	// Compute UTM zone/EPSG
-	zone := int(math.Floor((lon+180.0)/6.0)) + 1
-	var utmEPSG int
-	if lat >= 0 {
-		utmEPSG = 32600 + zone
-	} else {
-		utmEPSG = 32700 + zone
-	}
-	utmCode := fmt.Sprintf("EPSG:%d", utmEPSG)
-	// Reproject (warp) to UTM and save result
-	outDS, err := ds.Warp(outputPath,
-		[]string{"-t_srs", utmCode},
-		godal.CreationOption("TILED=YES", "COMPRESS=LZW"),
-		godal.GTiff, godal.ErrLogger(func(ec godal.ErrorCategory, code int, msg string) error {
-			if ec == godal.CE_Warning {
-				return nil
-			}
-			return fmt.Errorf("error warp dataset: %s", msg)
-		}))
-	if err != nil {
-		return err
-	}
-	defer outDS.Close()
-
-	return nil
-}
-
 func GetBands(indexes map[string][][]float64, x, y int) Bands {
 	ndmiValue := indexes["ndmi"][y][x]
-	cldValue := indexes["cloud"][y][x]
-	sclValue := indexes["scl"][y][x]
 	ndreValue := indexes["ndre"][y][x]
 	psriValue := indexes["psri"][y][x]
 	b02Value := indexes["b02"][y][x]
 	b04Value := indexes["b04"][y][x]
 	ndviValue := indexes["ndvi"][y][x]
+	nearCloud := false
+	if plane, ok := indexes["near_cloud"]; ok {
+		nearCloud = plane[y][x] > 0
+	}
+	if qaPixel, ok := indexes["qa_pixel"]; ok {
+		return Bands{
+			NDMI:      ndmiValue,
+			NDRE:      ndreValue,
+			PSRI:      psriValue,
+			B02:       b02Value,
+			B04:       b04Value,
+			NDVI:      ndviValue,
+			QAPixel:   qaPixel[y][x],
+			Sensor:    SensorLandsat,
+			NearCloud: nearCloud,
+		}
+	}
 	return Bands{
-		NDMI: ndmiValue,
-		CLD:  cldValue,
-		SCL:  sclValue,
-		NDRE: ndreValue,
-		PSRI: psriValue,
-		B02:  b02Value,
-		B04:  b04Value,
-		NDVI: ndviValue,
+		NDMI:      ndmiValue,
+		CLD:       indexes["cloud"][y][x],
+		SCL:       indexes["scl"][y][x],
+		NDRE:      ndreValue,
+		PSRI:      psriValue,
+		B02:       b02Value,
+		B04:       b04Value,
+		NDVI:      ndviValue,
+		Sensor:    SensorSentinel2,
+		NearCloud: nearCloud,
 	}
 }
 
+type validityCondition struct {
+	Condition   bool
+	PixelStatus PixelStatus
+}
+
+// Valid classifies the pixel under the quality policy in effect. Configuration errors surface
+// from GetIndexesFromImage, which reads the policy before any pixel is classified.
 func (bands Bands) Valid() PixelStatus {
-	invalidConditions := []struct {
-		Condition   bool
-		PixelStatus PixelStatus
-	}{
-		{bands.CLD > 0, PixelStatusUnknown},
-		{bands.SCL == 2 || bands.SCL == 3 || bands.SCL == 10, PixelStatusUnknown},
-		{bands.SCL == 8 || bands.SCL == 9, PixelStatusUnknown},
-		{(bands.B04+bands.B02)/2 > 0.9, PixelStatusInvalid},
-		{bands.PSRI == 0 && bands.NDVI == 0 && bands.NDMI == 0 && bands.NDRE == 0, PixelStatusInvalid},
-	}
+	policy, _ := ActiveQualityPolicy()
+	return policy.PixelStatus(bands)
+}
 
-	for _, condition := range invalidConditions {
-		if condition.Condition {
-			return condition.PixelStatus
-		}
-	}
-	return PixelStatusValid
+var imageProcessingMutex sync.Mutex
+
+type imageDownload struct {
+	sensingTime time.Time
+	imageName   string
+	tempPath    string
+	permPath    string
+	// sarPath holds the radar acquisition to merge into the image, when one was fetched
+	sarPath string
+	// cached is set for images already on disk that only need their manifest entry
+	// refreshed, or their radar bands merged
+	cached bool
+	entry  ManifestEntry
+	// regrid is set for cached images lying on another grid, such as those cached before
+	// the plot had a grid.json
+	regrid bool
 }
 
-// GetImages retrieves satellite images based on the given parameters
-func GetImages(geometry *godal.Geometry, forest, plot string, startDate, endDate time.Time, satelliteIntervalDays int) (map[time.Time]*godal.Dataset, error) {
+// GetImages retrieves satellite images from the given provider based on the given parameters.
+// The plot's manifest.json records every date and is consulted to skip rejected dates until
+// they may be retried.
+func GetImages(provider ImageProvider, geometry *godal.Geometry, forest, plot string, startDate, endDate time.Time, satelliteIntervalDays int) (_ map[time.Time]*godal.Dataset, err error) {
 	images := make(map[time.Time]*godal.Dataset)
-	imagesNotFoundFile := fmt.Sprintf("%s/data/images/invalid_images.json", properties.RootPath())
-
-	// Load images_not_found.json
-	var imagesNotFound []string
-	if _, err := os.Stat(imagesNotFoundFile); err == nil {
-		data, err := os.ReadFile(imagesNotFoundFile)
+	// The images opened so far are closed when any later step fails
+	defer func() {
 		if err != nil {
-			return nil, fmt.Errorf("failed to read %s: %v", imagesNotFoundFile, err)
+			closeImages(images)
 		}
-		if err := json.Unmarshal(data, &imagesNotFound); err != nil {
-			return nil, fmt.Errorf("invalid JSON in %s: %v", imagesNotFoundFile, err)
-		}
-	}
+	}()
 
 	// Ensure images directory exists
 	if _, err := os.Stat(fmt.Sprintf("%s/data/images", properties.RootPath())); os.IsNotExist(err) {
@@ -162,22 +119,119 @@ func GetImages(geometry *godal.Geometry, forest, plot string, startDate, endDate
 		}
 	}
 
-	// Iterate through dates
-	progressbar := progressbar.Default(int64(endDate.Sub(startDate).Hours()/24), "Getting images")
-	for currentDate := startDate; !currentDate.After(endDate); currentDate = currentDate.AddDate(0, 0, satelliteIntervalDays) {
-		startImageDate := currentDate
-		endImageDate := currentDate.Add(time.Hour*23 + time.Minute*59 + time.Second*59)
-		imageName := fmt.Sprintf("%s_%s_%s.tif", forest, plot, currentDate.Format("2006-01-02"))
-		fileName := fmt.Sprintf("%s/data/images/%s_%s/%s", properties.RootPath(), forest, plot, imageName)
+	enabledIndexes, err := EnabledIndexes()
+	if err != nil {
+		return nil, err
+	}
+	bands := RequiredBands(enabledIndexes)
+
+	sensors, err := EnabledSensors()
+	if err != nil {
+		return nil, err
+	}
+
+	// List the acquisitions that actually exist over the plot instead of probing every day
+	var sensorAcquisitions [][]Acquisition
+	for _, sensor := range sensors {
+		found, err := provider.Search(ImageRequest{
+			Forest:    forest,
+			Plot:      plot,
+			Geometry:  geometry,
+			StartDate: startDate,
+			EndDate:   endOfDay(endDate),
+			Sensor:    sensor,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error searching %s acquisitions: %v", sensor, err)
+		}
+		sensorAcquisitions = append(sensorAcquisitions, groupAcquisitionsByDay(found))
+	}
+	acquisitions := filterByInterval(mergeSensorAcquisitions(sensorAcquisitions), satelliteIntervalDays)
+
+	// Radar acquisitions within reach of the optical dates are merged into the images
+	sarProvider, sarAcquisitions, maxDaysApart, err := searchSAR(provider, geometry, forest, plot, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	imagePath := PlotImagePath(forest, plot)
+	// Verifica se o diretório existe e cria caso não
+	if _, err := os.Stat(imagePath); os.IsNotExist(err) {
+		if mkErr := os.MkdirAll(imagePath, os.ModePerm); mkErr != nil {
+			return nil, fmt.Errorf("failed to create directory %s: %v", imagePath, mkErr)
+		}
+	}
 
-		// Skip if image is in the not-found list
-		if contains(imagesNotFound, imageName) {
+	manifest, err := LoadManifest(forest, plot)
+	if err != nil {
+		return nil, err
+	}
+	grid, err := LoadPlotGrid(forest, plot, geometry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load grid of %s_%s: %v", forest, plot, err)
+	}
+	defer func() {
+		if err := manifest.Save(); err != nil {
+			fmt.Printf("failed to save image manifest of %s_%s: %v\n", forest, plot, err)
+		}
+	}()
+
+	// Open cached acquisitions and download the missing ones in parallel
+	progressbar := progressbar.Default(int64(len(acquisitions)), "Getting images")
+	var downloads []imageDownload
+	var downloadsMutex sync.Mutex
+	var downloadErrs []error
+	now := time.Now()
+	wp := workerpool.New(properties.ImageDownloadWorkers())
+	for _, acquisition := range acquisitions {
+		sensingTime := acquisition.SensingTime
+		startImageDate := startOfDay(sensingTime.UTC())
+		endImageDate := endOfDay(startImageDate)
+		imageName := imageFileName(forest, plot, sensingTime, acquisition.Sensor)
+		imageBands := sensorBands(acquisition.Sensor, bands)
+		fileName := filepath.Join(imagePath, imageName)
+
+		// Skip dates rejected until they may be retried
+		existing, recorded := manifest.Entry(imageName)
+		if recorded && !existing.Retryable(now) {
 			progressbar.Add(1)
 			continue
 		}
 
-		// Skip if file already exists
-		if _, err := os.Stat(fileName); err == nil {
+		request := ImageRequest{
+			Forest:    forest,
+			Plot:      plot,
+			Geometry:  geometry,
+			StartDate: startImageDate,
+			EndDate:   endImageDate,
+			Sensor:    acquisition.Sensor,
+			Bands:     imageBands,
+		}
+		entry := ManifestEntry{
+			File:        imageName,
+			Date:        startImageDate.Format("2006-01-02"),
+			Sensor:      sensorOrDefault(acquisition.Sensor),
+			SensingTime: sensingTime,
+			Request: ManifestRequest{
+				Provider:   providerName(provider),
+				StartDate:  startImageDate,
+				EndDate:    endImageDate,
+				Bands:      imageBands,
+				TileID:     acquisition.TileID,
+				CloudCover: acquisition.CloudCover,
+			},
+		}
+		// Sentinel-2 reflectance is harmonized across processing baselines before indices are computed
+		entry.Harmonization = baselineHarmonization(acquisition)
+
+		// Sentinel-2 images cached before their baseline correction was recorded were requested
+		// with the process API's own harmonization, or none for local files, so they are
+		// downloaded again rather than corrected a second time
+		harmonized := entry.Harmonization == nil || (recorded && existing.Harmonization != nil)
+
+		// Skip if file already exists, matches its recorded checksum and holds every band
+		// the enabled indices need
+		if _, err := os.Stat(fileName); err == nil && harmonized && cachedImageIntact(existing, recorded, fileName) {
 			data, err := godal.Open(fileName, godal.ErrLogger(func(ec godal.ErrorCategory, code int, msg string) error {
 				if ec == godal.CE_Warning {
 					return nil
@@ -188,41 +242,117 @@ func GetImages(geometry *godal.Geometry, forest, plot string, startDate, endDate
 			if err != nil {
 				return nil, fmt.Errorf("failed to open %s: %v", fileName, err)
 			}
-			images[currentDate] = data
-			progressbar.Add(1)
-			continue
-		}
+			if hasBands(data, imageBands) {
+				sarAcquisition := nearestAcquisition(sarAcquisitions, sensingTime, maxDaysApart)
+				needsSAR := sarProvider != nil && sarAcquisition != nil && !hasBands(data, sarBands)
+				onGrid := grid.matches(data)
+				if !needsSAR && onGrid && recorded && existing.Status == ManifestStatusAccepted {
+					images[sensingTime] = data
+					progressbar.Add(1)
+					continue
+				}
+				data.Close()
 
-		imageBytes, err := requestImage(startImageDate, endImageDate, geometry)
-		if err != nil {
-			if err.Error() == "Image not found" {
-				imagesNotFound = append(imagesNotFound, fileName)
-				saveImagesNotFound(imagesNotFoundFile, imagesNotFound)
+				// Images cached before the manifest or the grid, or missing the radar bands, are
+				// processed again without being downloaded
+				if recorded && existing.Status == ManifestStatusAccepted {
+					entry.Request = existing.Request
+				}
+				entry.Harmonization = existing.Harmonization
+				download := imageDownload{
+					sensingTime: sensingTime,
+					imageName:   imageName,
+					permPath:    fileName,
+					cached:      true,
+					entry:       entry,
+					regrid:      !onGrid,
+				}
+				wp.Submit(func() {
+					defer progressbar.Add(1)
+					if needsSAR {
+						download.sarPath = fetchSAR(sarProvider, forest, plot, geometry, *sarAcquisition, fileName)
+					}
+					downloadsMutex.Lock()
+					defer downloadsMutex.Unlock()
+					downloads = append(downloads, download)
+				})
 				continue
 			}
-			return nil, fmt.Errorf("error requesting image: %v", err)
+			data.Close()
 		}
 
-		imagePath := fmt.Sprintf("%s/data/images/%s_%s", properties.RootPath(), forest, plot)
-		// Verifica se o diretório existe e cria caso não
-		if _, err := os.Stat(imagePath); os.IsNotExist(err) {
-			if mkErr := os.MkdirAll(imagePath, os.ModePerm); mkErr != nil {
-				return nil, fmt.Errorf("failed to create directory %s: %v", imagePath, mkErr)
-			}
+		download := imageDownload{
+			sensingTime: sensingTime,
+			imageName:   imageName,
+			tempPath:    fileName + ".temp",
+			permPath:    fileName,
+			entry:       entry,
 		}
+		sarAcquisition := nearestAcquisition(sarAcquisitions, sensingTime, maxDaysApart)
+		wp.Submit(func() {
+			defer progressbar.Add(1)
+			err := provider.FetchImage(request, download.tempPath)
+			if err == nil && sarProvider != nil && sarAcquisition != nil {
+				download.sarPath = fetchSAR(sarProvider, forest, plot, geometry, *sarAcquisition, fileName)
+			}
+			downloadsMutex.Lock()
+			defer downloadsMutex.Unlock()
+			if err != nil {
+				if errors.Is(err, ErrImageNotFound) {
+					manifest.Reject(download.entry, RejectReasonNotFound, notFoundRetryAfter)
+				} else {
+					downloadErrs = append(downloadErrs, fmt.Errorf("error requesting image %s: %v", download.imageName, err))
+				}
+				return
+			}
+			downloads = append(downloads, download)
+		})
+	}
+	wp.StopWait()
 
-		permImageName := filepath.Join(imagePath, imageName)
-		tempImageName := imagePath + ".temp"
-		if err := os.WriteFile(tempImageName, imageBytes, 0644); err != nil {
-			return nil, fmt.Errorf("failed to write image file: %v", err)
+	for _, download := range downloads {
+		if download.tempPath != "" {
+			defer os.Remove(download.tempPath)
+		}
+		if download.sarPath != "" {
+			defer os.Remove(download.sarPath)
 		}
-		defer os.Remove(tempImageName)
+	}
+	if len(downloadErrs) > 0 {
+		return nil, downloadErrs[0]
+	}
 
-		if err = reprojectAutoUTM(tempImageName, permImageName); err != nil {
-			return nil, fmt.Errorf("failed to reproject image: %v", err)
+	// GDAL processing is shared between plots downloading concurrently, so the downloaded
+	// images are processed one at a time
+	imageProcessingMutex.Lock()
+	defer imageProcessingMutex.Unlock()
+	for _, download := range downloads {
+		if !download.cached {
+			if harmonization := download.entry.Harmonization; harmonization != nil {
+				if err := applyHarmonization(download.tempPath, *harmonization); err != nil {
+					return nil, fmt.Errorf("failed to harmonize %s: %v", download.imageName, err)
+				}
+			}
+			if err = warpToGrid(download.tempPath, download.permPath, grid); err != nil {
+				return nil, fmt.Errorf("failed to warp %s to the plot grid: %v", download.imageName, err)
+			}
+		} else if download.regrid {
+			gridPath := download.permPath + ".grid.temp"
+			if err := warpToGrid(download.permPath, gridPath, grid); err != nil {
+				os.Remove(gridPath)
+				return nil, fmt.Errorf("failed to warp %s to the plot grid: %v", download.imageName, err)
+			}
+			if err := os.Rename(gridPath, download.permPath); err != nil {
+				return nil, fmt.Errorf("failed to replace %s: %v", download.imageName, err)
+			}
+		}
+		if download.sarPath != "" {
+			if err := mergeSARBands(download.permPath, download.sarPath); err != nil {
+				return nil, fmt.Errorf("failed to merge radar bands into %s: %v", download.imageName, err)
+			}
 		}
 
-		ds, err := godal.Open(permImageName, godal.ErrLogger(func(ec godal.ErrorCategory, code int, msg string) error {
+		ds, err := godal.Open(download.permPath, godal.ErrLogger(func(ec godal.ErrorCategory, code int, msg string) error {
 			if ec == godal.CE_Warning {
 				return nil
 			}
@@ -235,68 +365,193 @@ func GetImages(geometry *godal.Geometry, forest, plot string, startDate, endDate
 
 		indexes, err := GetIndexesFromImage(ds)
 		if err != nil {
+			ds.Close()
 			return nil, err
 		}
 
-		height := len(indexes["ndmi"])
-		width := len(indexes["ndmi"][0])
-		totalPixels := height * width
-		count := 0
-		for y := 0; y < height; y++ {
-			for x := 0; x < width; x++ {
-				bands := GetBands(indexes, x, y)
-				pixelStatus := bands.Valid()
-				if pixelStatus == PixelStatusInvalid {
-					count++
-				}
+		ratio := imagePixelRatio(indexes)
+		entry := download.entry
+		entry.PixelRatio = &ratio
+		if ratio.Invalid == 1 {
+			ds.Close()
+			manifest.Reject(entry, RejectReasonAllInvalid, invalidImageRetryAfter)
+			if err := os.Remove(download.permPath); err != nil {
+				fmt.Printf("failed to delete image file %s: %v\n", download.permPath, err)
 			}
-		}
-		if count == totalPixels {
-			imagesNotFound = append(imagesNotFound, imageName)
-			saveImagesNotFound(imagesNotFoundFile, imagesNotFound)
-			if err := os.Remove(permImageName); err != nil {
-				fmt.Printf("failed to delete image file %s: %v\n", permImageName, err)
-			}
-			progressbar.Add(1)
 			continue
 		}
 
-		images[currentDate] = ds
-		progressbar.Add(1)
+		checksum, err := fileChecksum(download.permPath)
+		if err != nil {
+			ds.Close()
+			return nil, fmt.Errorf("failed to checksum %s: %v", download.permPath, err)
+		}
+		entry.Status = ManifestStatusAccepted
+		entry.Checksum = checksum
+		manifest.Put(entry)
+
+		images[download.sensingTime] = ds
 	}
 	return images, nil
 }
 
-func saveImagesNotFound(filePath string, imagesNotFound []string) {
-	var existingImagesNotFound []string
+// closeImages closes the datasets of images.
+func closeImages(images map[time.Time]*godal.Dataset) {
+	for _, image := range images {
+		image.Close()
+	}
+}
 
-	// Check if the file exists
-	if _, err := os.Stat(filePath); err == nil {
-		// File exists, read and unmarshal its content
-		data, err := os.ReadFile(filePath)
-		if err == nil {
-			_ = json.Unmarshal(data, &existingImagesNotFound)
+// imagePixelRatio returns the share of valid, unknown and invalid pixels of an image. Treatable
+// pixels only appear while estimating, so they are not counted here.
+func imagePixelRatio(indexes map[string][][]float64) PixelRatio {
+	height := len(indexes["ndmi"])
+	width := len(indexes["ndmi"][0])
+	var valid, unknown, invalid int
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			switch GetBands(indexes, x, y).Valid() {
+			case PixelStatusValid:
+				valid++
+			case PixelStatusUnknown:
+				unknown++
+			case PixelStatusInvalid:
+				invalid++
+			}
 		}
 	}
+	total := float64(height * width)
+	return PixelRatio{
+		Valid:   float64(valid) / total,
+		Unknown: float64(unknown) / total,
+		Invalid: float64(invalid) / total,
+	}
+}
 
-	// Append new images to the existing list
-	existingImagesNotFound = append(existingImagesNotFound, imagesNotFound...)
+// cachedImageIntact reports whether the cached file still matches the checksum its manifest
+// entry recorded. Files without a recorded checksum are trusted.
+func cachedImageIntact(entry ManifestEntry, recorded bool, path string) bool {
+	if !recorded || entry.Checksum == "" {
+		return true
+	}
+	checksum, err := fileChecksum(path)
+	if err != nil {
+		return false
+	}
+	if checksum != entry.Checksum {
+		fmt.Printf("cached image %s does not match its checksum, downloading it again\n", filepath.Base(path))
+		return false
+	}
+	return true
+}
+
+// providerName names the provider in the manifest.
+func providerName(provider ImageProvider) string {
+	switch p := provider.(type) {
+	case *SentinelHubProvider:
+		return "sentinelhub"
+	case *LocalProvider:
+		return "local"
+	case *ForestProvider:
+		return "forest/" + providerName(p.upstream)
+	default:
+		return fmt.Sprintf("%T", provider)
+	}
+}
+
+// searchSAR lists the radar acquisitions around the requested range when Sentinel-1 is
+// enabled and the provider serves it. A nil provider means no radar bands are merged.
+func searchSAR(provider ImageProvider, geometry *godal.Geometry, forest, plot string, startDate, endDate time.Time) (SARProvider, []Acquisition, int, error) {
+	enabled, maxDaysApart, err := sarEnabled()
+	if err != nil || !enabled {
+		return nil, nil, 0, err
+	}
+	sarProvider, ok := provider.(SARProvider)
+	if !ok {
+		fmt.Println("Sentinel-1 is enabled but the image provider does not serve it, radar features will be empty")
+		return nil, nil, 0, nil
+	}
+
+	acquisitions, err := sarProvider.SearchSAR(ImageRequest{
+		Forest:    forest,
+		Plot:      plot,
+		Geometry:  geometry,
+		StartDate: startDate.AddDate(0, 0, -maxDaysApart),
+		EndDate:   endOfDay(endDate.AddDate(0, 0, maxDaysApart)),
+	})
+	if err != nil {
+		return nil, nil, 0, fmt.Errorf("error searching radar acquisitions: %v", err)
+	}
+	return sarProvider, groupAcquisitionsByDay(acquisitions), maxDaysApart, nil
+}
 
-	// Remove duplicates
-	uniqueImages := make(map[string]struct{})
-	for _, image := range existingImagesNotFound {
-		uniqueImages[image] = struct{}{}
+// fetchSAR downloads the radar acquisition for the image at imagePath and returns the path
+// of the raw file. Failures only leave the image without radar bands, so they are reported
+// and an empty path is returned.
+func fetchSAR(provider SARProvider, forest, plot string, geometry *godal.Geometry, acquisition Acquisition, imagePath string) string {
+	day := startOfDay(acquisition.SensingTime.UTC())
+	sarPath := imagePath + ".s1.temp"
+	err := provider.FetchSAR(ImageRequest{
+		Forest:    forest,
+		Plot:      plot,
+		Geometry:  geometry,
+		StartDate: day,
+		EndDate:   endOfDay(day),
+		Bands:     sarBands,
+	}, sarPath)
+	if err != nil {
+		fmt.Printf("failed to fetch radar image for %s: %v\n", filepath.Base(imagePath), err)
+		os.Remove(sarPath)
+		return ""
+	}
+	return sarPath
+}
+
+// mergeSensorAcquisitions joins the per-sensor acquisitions, in order of preference, into a
+// single series sorted by date. A day covered by several sensors keeps the preferred one.
+func mergeSensorAcquisitions(sensorAcquisitions [][]Acquisition) []Acquisition {
+	var merged []Acquisition
+	days := make(map[string]bool)
+	for _, acquisitions := range sensorAcquisitions {
+		for _, acquisition := range acquisitions {
+			day := acquisition.SensingTime.UTC().Format("2006-01-02")
+			if days[day] {
+				continue
+			}
+			days[day] = true
+			merged = append(merged, acquisition)
+		}
 	}
+	sort.Slice(merged, func(i, j int) bool {
+		return merged[i].SensingTime.Before(merged[j].SensingTime)
+	})
+	return merged
+}
 
-	// Convert back to a slice
-	finalImagesNotFound := make([]string, 0, len(uniqueImages))
-	for image := range uniqueImages {
-		finalImagesNotFound = append(finalImagesNotFound, image)
+// filterByInterval drops acquisitions sensed less than intervalDays after the previously kept one.
+func filterByInterval(acquisitions []Acquisition, intervalDays int) []Acquisition {
+	if intervalDays <= 1 {
+		return acquisitions
 	}
+	var filtered []Acquisition
+	for _, acquisition := range acquisitions {
+		if len(filtered) > 0 {
+			last := startOfDay(filtered[len(filtered)-1].SensingTime.UTC())
+			if startOfDay(acquisition.SensingTime.UTC()).Before(last.AddDate(0, 0, intervalDays)) {
+				continue
+			}
+		}
+		filtered = append(filtered, acquisition)
+	}
+	return filtered
+}
+
+func startOfDay(date time.Time) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+}
 
-	// Marshal and write back to the file
-	data, _ := json.Marshal(finalImagesNotFound)
-	_ = os.WriteFile(filePath, data, 0644)
+func endOfDay(date time.Time) time.Time {
+	return startOfDay(date).Add(time.Hour*23 + time.Minute*59 + time.Second*59)
 }
 
 func contains(slice []string, item string) bool {
@@ -1,26 +1,36 @@
 package sentinel
 
 import (
+	"fmt"
 	"math"
+	"slices"
 
 	"github.com/airbusgeo/godal"
 )
 
+// validationPlanes are the planes GetIndexesFromImage returns for pixel validation only.
+var validationPlanes = []string{"b02", "b04", "cloud", "scl", "qa_pixel", "near_cloud"}
+
+// GetIndexesFromImage reads the bands of an image and computes every enabled index. The
+// result is keyed by index name, plus the raw "b02", "b04", "cloud" and "scl" planes used
+// for pixel validation ("qa_pixel" instead of "cloud" and "scl" on Landsat images), and
+// "near_cloud" when the quality policy dilates the cloud mask.
+// Indices needing bands Landsat lacks are NaN on Landsat images.
 func GetIndexesFromImage(dataset *godal.Dataset) (map[string][][]float64, error) {
-	// Read bands
-	bandsData := dataset.Bands()
-
-	// Map specific band names to their corresponding Band objects
-	bands := map[string]godal.Band{
-		"B05": bandsData[0], // Band 1
-		"B08": bandsData[1], // Band 2
-		"B11": bandsData[2], // Band 3
-		"B02": bandsData[3], // Band 4
-		"B04": bandsData[4], // Band 5
-		"B06": bandsData[5], // Band 6
-		"CLD": bandsData[6], // Band 7
-		"SCL": bandsData[7], // Band 8
+	enabledIndexes, err := EnabledIndexes()
+	if err != nil {
+		return nil, err
+	}
+	policy, err := ActiveQualityPolicy()
+	if err != nil {
+		return nil, err
+	}
+
+	bandNames, err := imageBandNames(dataset)
+	if err != nil {
+		return nil, err
 	}
+
 	// Read data from bands
 	readBand := func(band godal.Band) ([][]float64, error) {
 		xSize := band.Structure().SizeX
@@ -38,66 +48,154 @@ func GetIndexesFromImage(dataset *godal.Dataset) (map[string][][]float64, error)
 	}
 
 	bandData := make(map[string][][]float64)
-	for key, band := range bands {
-		var err error
-		bandData[key], err = readBand(band)
+	for i, band := range dataset.Bands() {
+		bandData[bandNames[i]], err = readBand(band)
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	ndre := calculateIndex(bandData["B08"], bandData["B05"])
-	ndmi := calculateIndex(bandData["B08"], bandData["B11"])
-	psri := calculateIndex(bandData["B04"], bandData["B06"])
-	ndvi := calculateIndex(bandData["B08"], bandData["B04"])
+	sensor := imageSensor(bandNames)
+	validationBands := []string{"B02", "B04", "CLD", "SCL"}
+	if sensor == SensorLandsat {
+		validationBands = []string{"B02", "B04", landsatQABand}
+	}
+	for _, band := range validationBands {
+		if _, ok := bandData[band]; !ok {
+			return nil, fmt.Errorf("image is missing band %s", band)
+		}
+	}
 
 	indexes := map[string][][]float64{
-		"ndre":  ndre,
-		"ndmi":  ndmi,
-		"psri":  psri,
-		"ndvi":  ndvi,
-		"b02":   bandData["B02"],
-		"b04":   bandData["B04"],
-		"cloud": bandData["CLD"],
-		"scl":   bandData["SCL"],
+		"b02": bandData["B02"],
+		"b04": bandData["B04"],
+	}
+	if sensor == SensorLandsat {
+		indexes["qa_pixel"] = bandData[landsatQABand]
+	} else {
+		indexes["cloud"] = bandData["CLD"]
+		indexes["scl"] = bandData["SCL"]
+	}
+	for _, index := range enabledIndexes {
+		// Images without a radar acquisition close enough carry no VV/VH bands, and
+		// Landsat images have no red-edge bands
+		if (IsSARIndex(index.Name) || sensor == SensorLandsat) && !hasAllBands(bandData, index.Bands) {
+			indexes[index.Name] = nanPlane(len(bandData["B02"]), len(bandData["B02"][0]))
+			continue
+		}
+		values, err := calculateSpectralIndex(index, bandData)
+		if err != nil {
+			return nil, err
+		}
+		indexes[index.Name] = values
+	}
+	if nearCloud := policy.nearCloudPlane(indexes); nearCloud != nil {
+		indexes["near_cloud"] = nearCloud
 	}
 
 	return indexes, nil
 }
 
-func calculateIndex(band1, band2 [][]float64) [][]float64 {
-	rows := len(band1)
-	cols := len(band1[0])
+// imageBandNames returns the band names stored in the band descriptions, falling back to the
+// legacy 8-band layout for images downloaded before bands were labelled.
+func imageBandNames(dataset *godal.Dataset) ([]string, error) {
+	bands := dataset.Bands()
+	names := make([]string, len(bands))
+	labelled := true
+	for i, band := range bands {
+		names[i] = band.Description()
+		if names[i] == "" {
+			labelled = false
+		}
+	}
+	if labelled {
+		return names, nil
+	}
+	if len(bands) == len(legacyBandLayout) {
+		return legacyBandLayout, nil
+	}
+	return nil, fmt.Errorf("image has %d unlabelled bands, expected the %d-band legacy layout", len(bands), len(legacyBandLayout))
+}
+
+// hasBands reports whether the image holds every band in required.
+func hasBands(dataset *godal.Dataset, required []string) bool {
+	names, err := imageBandNames(dataset)
+	if err != nil {
+		return false
+	}
+	for _, band := range required {
+		if !slices.Contains(names, band) {
+			return false
+		}
+	}
+	return true
+}
+
+func hasAllBands(bandData map[string][][]float64, bands []string) bool {
+	for _, band := range bands {
+		if _, ok := bandData[band]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func nanPlane(rows, cols int) [][]float64 {
+	plane := make([][]float64, rows)
+	for i := range plane {
+		plane[i] = make([]float64, cols)
+		for j := range plane[i] {
+			plane[i][j] = math.NaN()
+		}
+	}
+	return plane
+}
+
+func calculateSpectralIndex(index SpectralIndex, bandData map[string][][]float64) ([][]float64, error) {
+	inputs := make([][][]float64, len(index.Bands))
+	for i, band := range index.Bands {
+		data, ok := bandData[band]
+		if !ok {
+			return nil, fmt.Errorf("image is missing band %s required by index %s", band, index.Name)
+		}
+		inputs[i] = data
+	}
+
+	rows := len(inputs[0])
+	cols := len(inputs[0][0])
+	values := make([]float64, len(inputs))
 	result := make([][]float64, rows)
 	for i := range result {
 		result[i] = make([]float64, cols)
 		for j := range result[i] {
-			denominator := band1[i][j] + band2[i][j]
-			if denominator != 0 {
-				result[i][j] = (band1[i][j] - band2[i][j]) / denominator
-			} else {
-				result[i][j] = 0
+			for k := range inputs {
+				values[k] = inputs[k][i][j]
 			}
+			result[i][j] = index.Formula(values)
 		}
 	}
-	return result
+	return result, nil
 }
 
-func validateIndexes(psri, ndvi, ndmi, ndre, cld, scl, b02, b04 float64, weather map[string]interface{}) bool {
-	if math.IsNaN(psri) || math.IsNaN(ndvi) || math.IsNaN(ndmi) || math.IsNaN(ndre) {
-		return false
+// ImageSensor returns the sensor that acquired the image.
+func ImageSensor(dataset *godal.Dataset) Sensor {
+	bandNames, err := imageBandNames(dataset)
+	if err != nil {
+		return SensorSentinel2
 	}
-	if cld > 0 || scl == 3 || scl == 8 || scl == 9 || scl == 10 {
+	return imageSensor(bandNames)
+}
+
+func validateIndexes(policy QualityPolicy, psri, ndvi, ndmi, ndre, cld, scl, b02, b04 float64, weather map[string]interface{}) bool {
+	if math.IsNaN(psri) || math.IsNaN(ndvi) || math.IsNaN(ndmi) || math.IsNaN(ndre) {
 		return false
 	}
-	if (b04+b02)/2 > 0.9 {
+	bands := Bands{PSRI: psri, NDVI: ndvi, NDMI: ndmi, NDRE: ndre, CLD: cld, SCL: scl, B02: b02, B04: b04, Sensor: SensorSentinel2}
+	if policy.PixelStatus(bands) != PixelStatusValid {
 		return false
 	}
 	if weather["precipitation"] == nil || weather["temperature"] == nil {
 		return false
 	}
-	if psri == 0 && ndvi == 0 && ndmi == 0 && ndre == 0 {
-		return false
-	}
 	return true
 }
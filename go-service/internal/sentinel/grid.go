@@ -0,0 +1,183 @@
+package sentinel
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/airbusgeo/godal"
+)
+
+// gridPixelSize is the pixel side of plot grids in meters, the Sentinel-2 10 m resolution.
+const gridPixelSize = 10.0
+
+// PlotGrid is the canonical UTM grid every image of a plot is warped to, so a pixel (x, y)
+// is the same ground location on every date and in every run. It is defined from the plot
+// geometry the first time the plot is processed and kept in the plot image folder as grid.json.
+type PlotGrid struct {
+	EPSG int `json:"epsg"`
+	// OriginX and OriginY are the upper-left corner, snapped to the pixel size
+	OriginX   float64 `json:"origin_x"`
+	OriginY   float64 `json:"origin_y"`
+	PixelSize float64 `json:"pixel_size"`
+	Width     int     `json:"width"`
+	Height    int     `json:"height"`
+}
+
+// LoadPlotGrid returns the stored grid of the plot, defining and storing it from geometry
+// if the plot has none yet.
+func LoadPlotGrid(forest, plot string, geometry *godal.Geometry) (PlotGrid, error) {
+	path := filepath.Join(PlotImagePath(forest, plot), "grid.json")
+	data, err := os.ReadFile(path)
+	if err == nil {
+		var grid PlotGrid
+		if err := json.Unmarshal(data, &grid); err != nil {
+			return PlotGrid{}, fmt.Errorf("invalid JSON in %s: %v", path, err)
+		}
+		return grid, nil
+	}
+	if !os.IsNotExist(err) {
+		return PlotGrid{}, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+
+	grid, err := newPlotGrid(geometry)
+	if err != nil {
+		return PlotGrid{}, err
+	}
+	data, err = json.MarshalIndent(grid, "", "  ")
+	if err != nil {
+		return PlotGrid{}, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return PlotGrid{}, fmt.Errorf("failed to create directory %s: %v", filepath.Dir(path), err)
+	}
+	tempPath := path + ".temp"
+	if err := os.WriteFile(tempPath, data, 0644); err != nil {
+		return PlotGrid{}, fmt.Errorf("failed to write %s: %v", tempPath, err)
+	}
+	return grid, os.Rename(tempPath, path)
+}
+
+// newPlotGrid covers the bounds of the WGS84 geometry in the UTM zone of its center.
+func newPlotGrid(geometry *godal.Geometry) (PlotGrid, error) {
+	polygon, err := bufferedPolygon(geometry, 0)
+	if err != nil {
+		return PlotGrid{}, err
+	}
+	defer polygon.Close()
+
+	bounds, err := polygon.Bounds()
+	if err != nil {
+		return PlotGrid{}, err
+	}
+	epsg := utmEPSG((bounds[0]+bounds[2])/2, (bounds[1]+bounds[3])/2)
+	utm, err := godal.NewSpatialRefFromEPSG(epsg)
+	if err != nil {
+		return PlotGrid{}, err
+	}
+	defer utm.Close()
+	if err := polygon.Reproject(utm); err != nil {
+		return PlotGrid{}, fmt.Errorf("failed to project plot geometry to UTM: %v", err)
+	}
+	bounds, err = polygon.Bounds()
+	if err != nil {
+		return PlotGrid{}, err
+	}
+
+	originX := math.Floor(bounds[0]/gridPixelSize) * gridPixelSize
+	originY := math.Ceil(bounds[3]/gridPixelSize) * gridPixelSize
+	return PlotGrid{
+		EPSG:      epsg,
+		OriginX:   originX,
+		OriginY:   originY,
+		PixelSize: gridPixelSize,
+		Width:     max(int(math.Ceil((bounds[2]-originX)/gridPixelSize)), 1),
+		Height:    max(int(math.Ceil((originY-bounds[1])/gridPixelSize)), 1),
+	}, nil
+}
+
+// GeoTransform returns the GDAL geotransform of the grid.
+func (grid PlotGrid) GeoTransform() [6]float64 {
+	return [6]float64{grid.OriginX, grid.PixelSize, 0, grid.OriginY, 0, -grid.PixelSize}
+}
+
+// matches reports whether the image already lies on the grid.
+func (grid PlotGrid) matches(ds *godal.Dataset) bool {
+	structure := ds.Structure()
+	if structure.SizeX != grid.Width || structure.SizeY != grid.Height {
+		return false
+	}
+	geoTransform, err := ds.GeoTransform()
+	if err != nil {
+		return false
+	}
+	for i, value := range grid.GeoTransform() {
+		if math.Abs(geoTransform[i]-value) > 1e-6 {
+			return false
+		}
+	}
+	sr := ds.SpatialRef()
+	defer sr.Close()
+	return sr.AuthorityCode("") == strconv.Itoa(grid.EPSG)
+}
+
+// warpToGrid warps the image at inputPath onto the grid into outputPath, keeping the band
+// labels. Nearest neighbour resampling keeps the cloud and scene class values intact.
+func warpToGrid(inputPath, outputPath string, grid PlotGrid) error {
+	godal.RegisterAll()
+	ds, err := godal.Open(inputPath, godal.ErrLogger(func(ec godal.ErrorCategory, code int, msg string) error {
+		if ec == godal.CE_Warning {
+			return nil
+		}
+		return fmt.Errorf("error opening dataset: %s", msg)
+	}))
+	if err != nil {
+		return err
+	}
+	defer ds.Close()
+
+	minY := grid.OriginY - float64(grid.Height)*grid.PixelSize
+	maxX := grid.OriginX + float64(grid.Width)*grid.PixelSize
+	outDS, err := ds.Warp(outputPath,
+		[]string{
+			"-t_srs", fmt.Sprintf("EPSG:%d", grid.EPSG),
+			"-te", fmt.Sprint(grid.OriginX), fmt.Sprint(minY), fmt.Sprint(maxX), fmt.Sprint(grid.OriginY),
+			"-ts", fmt.Sprint(grid.Width), fmt.Sprint(grid.Height),
+			"-r", "near",
+		},
+		godal.CreationOption("TILED=YES", "COMPRESS=LZW"),
+		godal.GTiff, godal.ErrLogger(func(ec godal.ErrorCategory, code int, msg string) error {
+			if ec == godal.CE_Warning {
+				return nil
+			}
+			return fmt.Errorf("error warp dataset: %s", msg)
+		}))
+	if err != nil {
+		return err
+	}
+	defer outDS.Close()
+
+	// Keep the band labels so the indices can still find their bands
+	outBands := outDS.Bands()
+	for i, band := range ds.Bands() {
+		if description := band.Description(); description != "" {
+			if err := outBands[i].SetDescription(description); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// utmEPSG returns the EPSG code of the WGS84 UTM zone containing the point.
+func utmEPSG(lon, lat float64) int {
+	zone := int(math.Floor((lon+180)/6)) + 1
+	zone = min(max(zone, 1), 60)
+	if lat < 0 {
+		return 32700 + zone
+	}
+	return 32600 + zone
+}
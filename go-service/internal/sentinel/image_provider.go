@@ -0,0 +1,78 @@
+package sentinel
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/airbusgeo/godal"
+	"github.com/forest-guardian/forest-guardian-api-poc/internal/config"
+	"github.com/forest-guardian/forest-guardian-api-poc/internal/properties"
+)
+
+// ErrImageNotFound is returned by providers when no acquisition covers the requested range.
+var ErrImageNotFound = errors.New("Image not found")
+
+// ImageRequest describes the imagery wanted for a plot over a time range.
+type ImageRequest struct {
+	Forest    string
+	Plot      string
+	Geometry  *godal.Geometry
+	StartDate time.Time
+	EndDate   time.Time
+	// Sensor selects the optical mission; empty means Sentinel-2.
+	Sensor Sensor
+	// Bands lists the bands to fetch, in output order. Providers label each output band
+	// with its name so GetIndexesFromImage can find it.
+	Bands []string
+}
+
+// ImageProvider lists the acquisitions available for a request and fetches the raw GeoTIFF
+// with the requested bands covering a request into outputPath.
+type ImageProvider interface {
+	Search(request ImageRequest) ([]Acquisition, error)
+	FetchImage(request ImageRequest, outputPath string) error
+}
+
+// SARProvider is implemented by providers that also serve Sentinel-1 GRD backscatter.
+// FetchSAR writes the VV and VH gamma0 backscatter in dB over the request geometry, as
+// bands labelled "VV" and "VH".
+type SARProvider interface {
+	SearchSAR(request ImageRequest) ([]Acquisition, error)
+	FetchSAR(request ImageRequest, outputPath string) error
+}
+
+// NewImageProvider returns the provider selected by the IMAGE_PROVIDER environment variable,
+// wrapped in a ForestProvider when the configuration selects the forest acquisition mode. The
+// local provider serves the staged images of single plots, so it only supports the plot mode.
+func NewImageProvider() (ImageProvider, error) {
+	var provider ImageProvider
+	switch properties.ImageProvider() {
+	case "", "sentinelhub":
+		provider = NewSentinelHubProvider()
+	case "local":
+		localProvider, err := NewLocalProvider(properties.LocalImagesPath())
+		if err != nil {
+			return nil, err
+		}
+		provider = localProvider
+	default:
+		return nil, fmt.Errorf("unknown image provider: %s", properties.ImageProvider())
+	}
+
+	cfg, err := config.Get()
+	if err != nil {
+		return nil, err
+	}
+	switch cfg.Acquisition.Mode {
+	case "", "plot":
+		return provider, nil
+	case "forest":
+		if _, ok := provider.(*LocalProvider); ok {
+			return nil, fmt.Errorf("acquisition mode forest in %s is not supported by the local image provider, which serves plot images", config.Path())
+		}
+		return NewForestProvider(provider), nil
+	default:
+		return nil, fmt.Errorf("unknown acquisition mode %q in %s", cfg.Acquisition.Mode, config.Path())
+	}
+}
@@ -0,0 +1,232 @@
+package sentinel
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// sentinel2Bands are the Sentinel-2 L2A bands an index expression may reference.
+var sentinel2Bands = []string{"B01", "B02", "B03", "B04", "B05", "B06", "B07", "B08", "B8A", "B09", "B11", "B12"}
+
+var indexNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// CompileIndexExpression parses a user-defined index such as "(B08-B11)/(B08+B11)".
+// Expressions combine band names and numbers with + - * / ^, unary minus and
+// parentheses. Divisions by zero yield 0, as in the built-in indices.
+func CompileIndexExpression(name, expression string) (SpectralIndex, error) {
+	if !indexNamePattern.MatchString(name) || strings.HasSuffix(name, "_derivative") {
+		return SpectralIndex{}, fmt.Errorf("invalid index name %q: use lowercase letters, digits and underscores", name)
+	}
+	if _, exists := LookupIndex(name); exists || IsSARIndex(name) {
+		return SpectralIndex{}, fmt.Errorf("index %q is already a built-in index", name)
+	}
+
+	tokens, err := tokenizeExpression(expression)
+	if err != nil {
+		return SpectralIndex{}, fmt.Errorf("index %s: %v", name, err)
+	}
+	p := &expressionParser{tokens: tokens}
+	eval, err := p.parseExpression()
+	if err == nil && p.pos < len(p.tokens) {
+		err = fmt.Errorf("unexpected %q at position %d", p.tokens[p.pos].text, p.tokens[p.pos].pos)
+	}
+	if err != nil {
+		return SpectralIndex{}, fmt.Errorf("index %s: %v", name, err)
+	}
+	if len(p.bands) == 0 {
+		return SpectralIndex{}, fmt.Errorf("index %s: expression does not reference any band", name)
+	}
+
+	return SpectralIndex{Name: name, Bands: p.bands, Formula: eval}, nil
+}
+
+type tokenKind int
+
+const (
+	tokenNumber tokenKind = iota
+	tokenBand
+	tokenOperator
+	tokenLeftParen
+	tokenRightParen
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	value float64
+	pos   int
+}
+
+func tokenizeExpression(expression string) ([]token, error) {
+	var tokens []token
+	runes := []rune(expression)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case strings.ContainsRune("+-*/^", r):
+			tokens = append(tokens, token{kind: tokenOperator, text: string(r), pos: i})
+			i++
+		case r == '(':
+			tokens = append(tokens, token{kind: tokenLeftParen, text: "(", pos: i})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokenRightParen, text: ")", pos: i})
+			i++
+		case unicode.IsDigit(r) || r == '.':
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			text := string(runes[start:i])
+			value, err := strconv.ParseFloat(text, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q at position %d", text, start)
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: text, value: value, pos: start})
+		case unicode.IsLetter(r):
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i])) {
+				i++
+			}
+			text := strings.ToUpper(string(runes[start:i]))
+			if !slices.Contains(sentinel2Bands, text) {
+				return nil, fmt.Errorf("unknown band %q at position %d, available bands: %s", string(runes[start:i]), start, strings.Join(sentinel2Bands, ", "))
+			}
+			tokens = append(tokens, token{kind: tokenBand, text: text, pos: start})
+		default:
+			return nil, fmt.Errorf("unexpected character %q at position %d", r, i)
+		}
+	}
+	return tokens, nil
+}
+
+// expressionParser is a recursive descent parser that compiles the expression into closures
+// over the band values. Band values are passed in the order of bands.
+type expressionParser struct {
+	tokens []token
+	pos    int
+	bands  []string
+}
+
+type evaluator func(values []float64) float64
+
+func (p *expressionParser) peek() *token {
+	if p.pos < len(p.tokens) {
+		return &p.tokens[p.pos]
+	}
+	return nil
+}
+
+// expression := term (("+" | "-") term)*
+func (p *expressionParser) parseExpression() (evaluator, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for t := p.peek(); t != nil && t.kind == tokenOperator && (t.text == "+" || t.text == "-"); t = p.peek() {
+		p.pos++
+		right, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		l, r := left, right
+		if t.text == "+" {
+			left = func(v []float64) float64 { return l(v) + r(v) }
+		} else {
+			left = func(v []float64) float64 { return l(v) - r(v) }
+		}
+	}
+	return left, nil
+}
+
+// term := unary (("*" | "/") unary)*
+func (p *expressionParser) parseTerm() (evaluator, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for t := p.peek(); t != nil && t.kind == tokenOperator && (t.text == "*" || t.text == "/"); t = p.peek() {
+		p.pos++
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l, r := left, right
+		if t.text == "*" {
+			left = func(v []float64) float64 { return l(v) * r(v) }
+		} else {
+			left = func(v []float64) float64 { return safeDivide(l(v), r(v)) }
+		}
+	}
+	return left, nil
+}
+
+// unary := "-" unary | power
+func (p *expressionParser) parseUnary() (evaluator, error) {
+	if t := p.peek(); t != nil && t.kind == tokenOperator && t.text == "-" {
+		p.pos++
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return func(v []float64) float64 { return -operand(v) }, nil
+	}
+	return p.parsePower()
+}
+
+// power := primary ("^" unary)?
+func (p *expressionParser) parsePower() (evaluator, error) {
+	base, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t != nil && t.kind == tokenOperator && t.text == "^" {
+		p.pos++
+		exponent, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return func(v []float64) float64 { return math.Pow(base(v), exponent(v)) }, nil
+	}
+	return base, nil
+}
+
+// primary := number | band | "(" expression ")"
+func (p *expressionParser) parsePrimary() (evaluator, error) {
+	t := p.peek()
+	if t == nil {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	p.pos++
+	switch t.kind {
+	case tokenNumber:
+		value := t.value
+		return func([]float64) float64 { return value }, nil
+	case tokenBand:
+		idx := slices.Index(p.bands, t.text)
+		if idx < 0 {
+			p.bands = append(p.bands, t.text)
+			idx = len(p.bands) - 1
+		}
+		return func(v []float64) float64 { return v[idx] }, nil
+	case tokenLeftParen:
+		inner, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.peek(); closing == nil || closing.kind != tokenRightParen {
+			return nil, fmt.Errorf("missing closing parenthesis for position %d", t.pos)
+		}
+		p.pos++
+		return inner, nil
+	default:
+		return nil, fmt.Errorf("unexpected %q at position %d", t.text, t.pos)
+	}
+}
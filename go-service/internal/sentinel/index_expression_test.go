@@ -0,0 +1,90 @@
+package sentinel
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestCompileIndexExpression(t *testing.T) {
+	bands := map[string]float64{"B02": 2, "B03": 3, "B04": 4, "B08": 8, "B11": 5}
+	tests := []struct {
+		name       string
+		expression string
+		want       float64
+		wantBands  []string
+	}{
+		{"normalized difference", "(B08-B11)/(B08+B11)", 3.0 / 13.0, []string{"B08", "B11"}},
+		{"multiplication before addition", "B02+B03*B04", 14, []string{"B02", "B03", "B04"}},
+		{"division before subtraction", "B08-B04/B02", 6, []string{"B08", "B04", "B02"}},
+		{"left associative subtraction", "B08-B04-B02", 2, []string{"B08", "B04", "B02"}},
+		{"left associative division", "B08/B04/B02", 1, []string{"B08", "B04", "B02"}},
+		{"power before multiplication", "2*B02^3", 16, []string{"B02"}},
+		{"right associative power", "B02^B02^B03", 256, []string{"B02", "B03"}},
+		{"parentheses", "(B02+B03)*B04", 20, []string{"B02", "B03", "B04"}},
+		{"unary minus", "-B02+B03", 1, []string{"B02", "B03"}},
+		{"double unary minus", "--B02", 2, []string{"B02"}},
+		{"unary minus of power", "-B02^2", -4, []string{"B02"}},
+		{"unary minus in exponent", "B02^-1", 0.5, []string{"B02"}},
+		{"unary minus after operator", "B08*-B02", -16, []string{"B08", "B02"}},
+		{"division by zero", "B08/(B02-B02)", 0, []string{"B08", "B02"}},
+		{"lowercase bands", "b08 - b04", 4, []string{"B08", "B04"}},
+		{"repeated band", "B08*B08", 64, []string{"B08"}},
+		{"decimal numbers", "2.5*B02", 5, []string{"B02"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			index, err := CompileIndexExpression("custom", test.expression)
+			if err != nil {
+				t.Fatalf("CompileIndexExpression(%q) failed: %v", test.expression, err)
+			}
+			if strings.Join(index.Bands, ",") != strings.Join(test.wantBands, ",") {
+				t.Fatalf("bands = %v, want %v", index.Bands, test.wantBands)
+			}
+			values := make([]float64, len(index.Bands))
+			for i, band := range index.Bands {
+				values[i] = bands[band]
+			}
+			if got := index.Formula(values); math.Abs(got-test.want) > 1e-12 {
+				t.Errorf("%s = %v, want %v", test.expression, got, test.want)
+			}
+		})
+	}
+}
+
+func TestCompileIndexExpressionErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		index      string
+		expression string
+		wantErr    string
+	}{
+		{"unknown band", "custom", "B08-B13", `unknown band "B13"`},
+		{"unknown identifier", "custom", "nir-B04", `unknown band "nir"`},
+		{"unexpected character", "custom", "B08%B04", `unexpected character '%'`},
+		{"invalid number", "custom", "1.2.3*B08", `invalid number "1.2.3"`},
+		{"missing closing parenthesis", "custom", "(B08-B04", "missing closing parenthesis"},
+		{"unmatched closing parenthesis", "custom", "B08-B04)", `unexpected ")"`},
+		{"trailing operator", "custom", "B08-", "unexpected end of expression"},
+		{"leading binary operator", "custom", "*B08", `unexpected "*"`},
+		{"consecutive operators", "custom", "B08*/B04", `unexpected "/"`},
+		{"missing operator", "custom", "B08 B04", `unexpected "B04"`},
+		{"empty parentheses", "custom", "()", `unexpected ")"`},
+		{"empty expression", "custom", "", "unexpected end of expression"},
+		{"no band", "custom", "1+2", "does not reference any band"},
+		{"invalid name", "Custom", "B08", "invalid index name"},
+		{"derivative name", "custom_derivative", "B08", "invalid index name"},
+		{"built-in name", "ndvi", "B08", "already a built-in index"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := CompileIndexExpression(test.index, test.expression)
+			if err == nil {
+				t.Fatalf("CompileIndexExpression(%q, %q) succeeded, want error containing %q", test.index, test.expression, test.wantErr)
+			}
+			if !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("error = %q, want it to contain %q", err, test.wantErr)
+			}
+		})
+	}
+}
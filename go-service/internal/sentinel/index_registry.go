@@ -0,0 +1,138 @@
+package sentinel
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/forest-guardian/forest-guardian-api-poc/internal/config"
+)
+
+// SpectralIndex defines an index by the bands it reads and a per-pixel formula that
+// receives the band values in the same order as Bands.
+type SpectralIndex struct {
+	Name    string
+	Bands   []string
+	Formula func(values []float64) float64
+}
+
+// CoreIndexes are always computed: pixel validation, the spread analysis and existing
+// models depend on them.
+var CoreIndexes = []string{"ndre", "ndmi", "psri", "ndvi"}
+
+// legacyBandLayout is the band order of images downloaded before bands were labelled.
+var legacyBandLayout = []string{"B05", "B08", "B11", "B02", "B04", "B06", "CLD", "SCL"}
+
+var indexRegistry = []SpectralIndex{
+	{Name: "ndre", Bands: []string{"B08", "B05"}, Formula: normalizedDifference},
+	{Name: "ndmi", Bands: []string{"B08", "B11"}, Formula: normalizedDifference},
+	// psri has always been computed as a normalized difference of B04 and B06
+	{Name: "psri", Bands: []string{"B04", "B06"}, Formula: normalizedDifference},
+	{Name: "ndvi", Bands: []string{"B08", "B04"}, Formula: normalizedDifference},
+	{Name: "evi", Bands: []string{"B08", "B04", "B02"}, Formula: func(v []float64) float64 {
+		return safeDivide(2.5*(v[0]-v[1]), v[0]+6*v[1]-7.5*v[2]+1)
+	}},
+	{Name: "savi", Bands: []string{"B08", "B04"}, Formula: func(v []float64) float64 {
+		return safeDivide(1.5*(v[0]-v[1]), v[0]+v[1]+0.5)
+	}},
+	{Name: "gndvi", Bands: []string{"B08", "B03"}, Formula: normalizedDifference},
+	{Name: "nbr", Bands: []string{"B08", "B12"}, Formula: normalizedDifference},
+	{Name: "msi", Bands: []string{"B11", "B08"}, Formula: func(v []float64) float64 {
+		return safeDivide(v[0], v[1])
+	}},
+	{Name: "cire", Bands: []string{"B08", "B05"}, Formula: func(v []float64) float64 {
+		if v[1] == 0 {
+			return 0
+		}
+		return v[0]/v[1] - 1
+	}},
+}
+
+func normalizedDifference(v []float64) float64 {
+	return safeDivide(v[0]-v[1], v[0]+v[1])
+}
+
+func safeDivide(numerator, denominator float64) float64 {
+	if denominator == 0 {
+		return 0
+	}
+	return numerator / denominator
+}
+
+// LookupIndex returns the registered index with the given name.
+func LookupIndex(name string) (SpectralIndex, bool) {
+	for _, index := range indexRegistry {
+		if index.Name == name {
+			return index, true
+		}
+	}
+	return SpectralIndex{}, false
+}
+
+var (
+	enabledIndexes     []SpectralIndex
+	enabledIndexesErr  error
+	enabledIndexesOnce sync.Once
+)
+
+// EnabledIndexes returns the core indices, then the registry indices enabled in the
+// configuration, then the custom expressions and, when Sentinel-1 is enabled, the radar
+// features. The list is resolved once per process.
+func EnabledIndexes() ([]SpectralIndex, error) {
+	enabledIndexesOnce.Do(func() {
+		enabledIndexes, enabledIndexesErr = resolveEnabledIndexes()
+	})
+	return enabledIndexes, enabledIndexesErr
+}
+
+func resolveEnabledIndexes() ([]SpectralIndex, error) {
+	cfg, err := config.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	var indexes []SpectralIndex
+	for _, name := range CoreIndexes {
+		index, _ := LookupIndex(name)
+		indexes = append(indexes, index)
+	}
+	for _, name := range cfg.Indexes.Enabled {
+		if slices.Contains(CoreIndexes, name) {
+			continue
+		}
+		index, ok := LookupIndex(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown index %q enabled in %s", name, config.Path())
+		}
+		indexes = append(indexes, index)
+	}
+	for _, custom := range cfg.Indexes.Custom {
+		if slices.ContainsFunc(indexes, func(index SpectralIndex) bool { return index.Name == custom.Name }) {
+			return nil, fmt.Errorf("index %q is defined twice in %s", custom.Name, config.Path())
+		}
+		index, err := CompileIndexExpression(custom.Name, custom.Expression)
+		if err != nil {
+			return nil, fmt.Errorf("invalid custom index in %s: %v", config.Path(), err)
+		}
+		indexes = append(indexes, index)
+	}
+	if cfg.Sentinel1.Enabled {
+		indexes = append(indexes, sarIndexes...)
+	}
+	return indexes, nil
+}
+
+// RequiredBands lists the optical bands to download for the given indices. The legacy layout
+// comes first so the default configuration keeps requesting the original eight bands. Radar
+// bands are fetched separately and left out.
+func RequiredBands(indexes []SpectralIndex) []string {
+	bands := slices.Clone(legacyBandLayout)
+	for _, index := range indexes {
+		for _, band := range index.Bands {
+			if !slices.Contains(bands, band) && !slices.Contains(sarBands, band) {
+				bands = append(bands, band)
+			}
+		}
+	}
+	return bands
+}
@@ -0,0 +1,168 @@
+package sentinel
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/forest-guardian/forest-guardian-api-poc/internal/config"
+)
+
+// Sensor identifies the optical mission an image was acquired by.
+type Sensor string
+
+var (
+	SensorSentinel2 Sensor = "sentinel2"
+	SensorLandsat   Sensor = "landsat"
+)
+
+// landsatBandMap maps Sentinel-2 band names to the Landsat 8/9 OLI band of the same
+// spectral region. Red-edge bands (B05-B07) and water vapour (B09) have no equivalent.
+var landsatBandMap = map[string]string{
+	"B01": "B01",
+	"B02": "B02",
+	"B03": "B03",
+	"B04": "B04",
+	"B08": "B05",
+	"B8A": "B05",
+	"B11": "B06",
+	"B12": "B07",
+}
+
+// landsatHarmonization holds the HLS bandpass adjustment (Claverie et al., 2018) used to bring
+// OLI reflectance to its Sentinel-2 MSI equivalent: msi = (oli - offset) / slope.
+var landsatHarmonization = map[string][2]float64{
+	"B02": {0.9778, -0.004},
+	"B03": {1.0053, -0.0009},
+	"B04": {0.9765, 0.0009},
+	"B08": {0.9983, -0.0001},
+	"B8A": {0.9983, -0.0001},
+	"B11": {0.9987, -0.0011},
+	"B12": {1.003, -0.0012},
+}
+
+// landsatQABand labels the Collection 2 QA_PIXEL mask, used in place of CLD and SCL.
+const landsatQABand = "QA_PIXEL"
+
+// QA_PIXEL bit flags
+const (
+	qaFill         = 1 << 0
+	qaDilatedCloud = 1 << 1
+	qaCirrus       = 1 << 2
+	qaCloud        = 1 << 3
+	qaCloudShadow  = 1 << 4
+	qaSnow         = 1 << 5
+)
+
+// sensorOrDefault treats an unset sensor as Sentinel-2.
+func sensorOrDefault(sensor Sensor) Sensor {
+	if sensor == "" {
+		return SensorSentinel2
+	}
+	return sensor
+}
+
+// EnabledSensors returns the optical sensors configured for acquisition, Sentinel-2 only by default.
+func EnabledSensors() ([]Sensor, error) {
+	cfg, err := config.Get()
+	if err != nil {
+		return nil, err
+	}
+	if len(cfg.Acquisition.Sensors) == 0 {
+		return []Sensor{SensorSentinel2}, nil
+	}
+	var sensors []Sensor
+	for _, name := range cfg.Acquisition.Sensors {
+		sensor := Sensor(name)
+		if sensor != SensorSentinel2 && sensor != SensorLandsat {
+			return nil, fmt.Errorf("unknown sensor %q in %s", name, config.Path())
+		}
+		if !slices.Contains(sensors, sensor) {
+			sensors = append(sensors, sensor)
+		}
+	}
+	return sensors, nil
+}
+
+// sensorBands returns the bands an image of sensor holds to compute the indices needing
+// the given Sentinel-2 bands. Landsat images are labelled with the Sentinel-2 names of the
+// equivalent bands, and carry QA_PIXEL instead of CLD and SCL.
+func sensorBands(sensor Sensor, bands []string) []string {
+	if sensor != SensorLandsat {
+		return bands
+	}
+	var landsatBands []string
+	for _, band := range bands {
+		if _, ok := landsatBandMap[band]; ok && !slices.Contains(landsatBands, band) {
+			landsatBands = append(landsatBands, band)
+		}
+	}
+	return append(landsatBands, landsatQABand)
+}
+
+// imageSensor tells the sensor of an image from its bands.
+func imageSensor(bandNames []string) Sensor {
+	if slices.Contains(bandNames, landsatQABand) {
+		return SensorLandsat
+	}
+	return SensorSentinel2
+}
+
+// landsatSource renders Landsat 8/9 Collection 2 L2 surface reflectance harmonized to
+// Sentinel-2. bands are Sentinel-2 names, as returned by sensorBands.
+func landsatSource(bands []string) imageSource {
+	var inputs, units []string
+	samples := make([]string, len(bands))
+	for i, band := range bands {
+		landsatBand, unit := landsatBandMap[band], "REFLECTANCE"
+		if band == landsatQABand {
+			landsatBand, unit = "BQA", "DN"
+		}
+		if !slices.Contains(inputs, fmt.Sprintf("%q", landsatBand)) {
+			inputs = append(inputs, fmt.Sprintf("%q", landsatBand))
+			units = append(units, fmt.Sprintf("%q", unit))
+		}
+		samples[i] = "sample." + landsatBand
+		if adjustment, ok := landsatHarmonization[band]; ok {
+			samples[i] = fmt.Sprintf("(sample.%s - %g) / %g", landsatBand, adjustment[1], adjustment[0])
+		}
+	}
+
+	evalscript := fmt.Sprintf(`
+    //VERSION=3
+    function setup() {
+      return {
+        input: [{
+          bands: [%s],
+          units: [%s],
+        }],
+        output: {
+          id: "default",
+          bands: %d,
+          sampleType: SampleType.FLOAT32,
+        },
+      }
+    }
+
+    function evaluatePixel(sample) {
+      return [%s];
+    }
+  `, strings.Join(inputs, ", "), strings.Join(units, ", "), len(bands), strings.Join(samples, ", "))
+
+	return imageSource{
+		collection: "landsat-ot-l2",
+		evalscript: evalscript,
+		bands:      bands,
+	}
+}
+
+// imageFileName returns the cache file name of an image of sensor over the day of sensingTime.
+// Sentinel-2 keeps the original naming.
+func imageFileName(forest, plot string, sensingTime time.Time, sensor Sensor) string {
+	date := startOfDay(sensingTime.UTC()).Format("2006-01-02")
+	if sensor == SensorLandsat {
+		return fmt.Sprintf("%s_%s_%s_landsat.tif", forest, plot, date)
+	}
+	return fmt.Sprintf("%s_%s_%s.tif", forest, plot, date)
+}
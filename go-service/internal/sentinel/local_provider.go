@@ -0,0 +1,186 @@
+package sentinel
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/airbusgeo/godal"
+)
+
+// LocalProvider serves pre-staged GeoTIFFs from a directory tree. Bands are identified by
+// their descriptions, or by the legacy 8-band layout when unlabelled. Files are
+// matched by name using the same "<forest>_<plot>_<YYYY-MM-DD>.tif" convention as the image cache
+// ("<forest>_<plot>_<YYYY-MM-DD>_landsat.tif" for Landsat), at any depth below the root.
+// An optional index.json at the root lists the acquisitions with their real sensing time,
+// sensor, tile, scene cloud cover and processing baseline; without it, acquisitions are
+// discovered from the file names. Staged Sentinel-2 reflectance is expected as distributed,
+// with the baseline 04.00 offset still applied.
+type LocalProvider struct {
+	root  string
+	files map[string]string
+	index []localIndexEntry
+}
+
+type localIndexEntry struct {
+	File        string    `json:"file"`
+	Forest      string    `json:"forest"`
+	Plot        string    `json:"plot"`
+	SensingTime time.Time `json:"sensing_time"`
+	TileID      string    `json:"tile_id"`
+	CloudCover  float64   `json:"cloud_cover"`
+	Sensor      Sensor    `json:"sensor"`
+	// ProcessingBaseline is the Sentinel-2 baseline ("05.10") of the staged product
+	ProcessingBaseline string `json:"processing_baseline"`
+}
+
+func NewLocalProvider(root string) (*LocalProvider, error) {
+	if root == "" {
+		return nil, fmt.Errorf("missing required environment variable: LOCAL_IMAGES_PATH")
+	}
+
+	files := make(map[string]string)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(d.Name(), ".tif") {
+			return nil
+		}
+		files[d.Name()] = path
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to index local images at %s: %v", root, err)
+	}
+
+	var index []localIndexEntry
+	indexPath := filepath.Join(root, "index.json")
+	if _, err := os.Stat(indexPath); err == nil {
+		data, err := os.ReadFile(indexPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", indexPath, err)
+		}
+		if err := json.Unmarshal(data, &index); err != nil {
+			return nil, fmt.Errorf("invalid JSON in %s: %v", indexPath, err)
+		}
+	}
+
+	return &LocalProvider{root: root, files: files, index: index}, nil
+}
+
+func (p *LocalProvider) Search(request ImageRequest) ([]Acquisition, error) {
+	var acquisitions []Acquisition
+	if p.index != nil {
+		for _, entry := range p.indexEntries(request) {
+			acquisitions = append(acquisitions, Acquisition{
+				SensingTime:        entry.SensingTime,
+				TileID:             entry.TileID,
+				CloudCover:         entry.CloudCover,
+				Sensor:             sensorOrDefault(entry.Sensor),
+				ProcessingBaseline: entry.ProcessingBaseline,
+			})
+		}
+		return acquisitions, nil
+	}
+
+	sensor := sensorOrDefault(request.Sensor)
+	suffix := ".tif"
+	if sensor == SensorLandsat {
+		suffix = "_landsat.tif"
+	}
+	for name := range p.files {
+		prefix := fmt.Sprintf("%s_%s_", request.Forest, request.Plot)
+		if !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, suffix) {
+			continue
+		}
+		date, err := time.Parse("2006-01-02", strings.TrimSuffix(strings.TrimPrefix(name, prefix), suffix))
+		if err != nil {
+			continue
+		}
+		if date.Before(request.StartDate) || date.After(request.EndDate) {
+			continue
+		}
+		acquisitions = append(acquisitions, Acquisition{SensingTime: date, Sensor: sensor})
+	}
+	return acquisitions, nil
+}
+
+func (p *LocalProvider) indexEntries(request ImageRequest) []localIndexEntry {
+	var entries []localIndexEntry
+	for _, entry := range p.index {
+		if entry.Forest != request.Forest || entry.Plot != request.Plot || sensorOrDefault(entry.Sensor) != sensorOrDefault(request.Sensor) {
+			continue
+		}
+		if entry.SensingTime.Before(request.StartDate) || entry.SensingTime.After(request.EndDate) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func (p *LocalProvider) FetchImage(request ImageRequest, outputPath string) error {
+	if p.index != nil {
+		for _, entry := range p.indexEntries(request) {
+			sourcePath := filepath.Join(p.root, entry.File)
+			if err := checkBands(sourcePath, request.Bands); err != nil {
+				return err
+			}
+			return copyFile(sourcePath, outputPath)
+		}
+		return ErrImageNotFound
+	}
+
+	for date := request.StartDate; !date.After(request.EndDate); date = date.AddDate(0, 0, 1) {
+		imageName := imageFileName(request.Forest, request.Plot, date, sensorOrDefault(request.Sensor))
+		sourcePath, ok := p.files[imageName]
+		if !ok {
+			continue
+		}
+
+		if err := checkBands(sourcePath, request.Bands); err != nil {
+			return err
+		}
+		return copyFile(sourcePath, outputPath)
+	}
+
+	return ErrImageNotFound
+}
+
+func checkBands(path string, required []string) error {
+	ds, err := godal.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %v", path, err)
+	}
+	defer ds.Close()
+
+	if !hasBands(ds, required) {
+		return fmt.Errorf("image %s does not hold the required bands %v", path, required)
+	}
+	return nil
+}
+
+func copyFile(sourcePath, outputPath string) error {
+	source, err := os.Open(sourcePath)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %v", sourcePath, err)
+	}
+	defer source.Close()
+
+	output, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %v", outputPath, err)
+	}
+	defer output.Close()
+
+	if _, err := io.Copy(output, source); err != nil {
+		return fmt.Errorf("failed to copy %s: %v", sourcePath, err)
+	}
+	return nil
+}
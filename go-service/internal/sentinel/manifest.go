@@ -0,0 +1,284 @@
+package sentinel
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/forest-guardian/forest-guardian-api-poc/internal/properties"
+)
+
+// ManifestStatus tells whether a cached date holds a usable image.
+type ManifestStatus string
+
+var (
+	ManifestStatusAccepted ManifestStatus = "accepted"
+	ManifestStatusRejected ManifestStatus = "rejected"
+)
+
+// Rejection reasons recorded in the manifest
+const (
+	RejectReasonAllInvalid = "all pixels invalid"
+	RejectReasonNotFound   = "image not found"
+	RejectReasonLegacy     = "listed in invalid_images.json"
+)
+
+const (
+	// invalidImageRetryAfter is how long an image with no usable pixel is skipped. Images
+	// are occasionally reprocessed upstream, so rejections expire instead of lasting forever.
+	invalidImageRetryAfter = 90 * 24 * time.Hour
+	// notFoundRetryAfter is how long a date the catalog listed but the provider could not
+	// render is skipped.
+	notFoundRetryAfter = 7 * 24 * time.Hour
+)
+
+// ManifestRequest records the parameters an image was requested with.
+type ManifestRequest struct {
+	Provider   string    `json:"provider"`
+	StartDate  time.Time `json:"start_date"`
+	EndDate    time.Time `json:"end_date"`
+	Bands      []string  `json:"bands"`
+	TileID     string    `json:"tile_id,omitempty"`
+	CloudCover float64   `json:"cloud_cover"`
+}
+
+// PixelRatio is the share of valid, unknown and invalid pixels of an image.
+type PixelRatio struct {
+	Valid   float64 `json:"valid"`
+	Unknown float64 `json:"unknown"`
+	Invalid float64 `json:"invalid"`
+}
+
+// ManifestEntry describes one date of a plot image folder.
+type ManifestEntry struct {
+	File         string          `json:"file"`
+	Date         string          `json:"date"`
+	Sensor       Sensor          `json:"sensor"`
+	SensingTime  time.Time       `json:"sensing_time"`
+	Request      ManifestRequest `json:"request"`
+	Status       ManifestStatus  `json:"status"`
+	PixelRatio   *PixelRatio     `json:"pixel_ratio,omitempty"`
+	Checksum     string          `json:"checksum,omitempty"`
+	RejectReason string          `json:"reject_reason,omitempty"`
+	RetryAfter   *time.Time      `json:"retry_after,omitempty"`
+	UpdatedAt    time.Time       `json:"updated_at"`
+	// Harmonization is the processing baseline correction applied to a Sentinel-2 image
+	Harmonization *BaselineHarmonization `json:"harmonization,omitempty"`
+}
+
+// Retryable reports whether a rejected entry may be requested again at now.
+func (entry ManifestEntry) Retryable(now time.Time) bool {
+	return entry.Status != ManifestStatusRejected || entry.RetryAfter == nil || !now.Before(*entry.RetryAfter)
+}
+
+// Manifest indexes the images of a plot folder in its manifest.json. It replaces the
+// global invalid_images.json, whose entries are migrated into the plot manifests on load.
+type Manifest struct {
+	mu      sync.Mutex
+	dir     string
+	entries map[string]ManifestEntry
+}
+
+// legacyInvalidImagesMutex guards invalid_images.json while plots migrate their entries.
+var legacyInvalidImagesMutex sync.Mutex
+
+// PlotImagePath returns the image folder of a plot.
+func PlotImagePath(forest, plot string) string {
+	return fmt.Sprintf("%s/data/images/%s_%s", properties.RootPath(), forest, plot)
+}
+
+// LoadManifest reads the manifest of the plot image folder, creating an empty one if missing.
+func LoadManifest(forest, plot string) (*Manifest, error) {
+	manifest := &Manifest{dir: PlotImagePath(forest, plot), entries: make(map[string]ManifestEntry)}
+	data, err := os.ReadFile(manifest.path())
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read %s: %v", manifest.path(), err)
+	}
+	if err == nil {
+		var entries []ManifestEntry
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return nil, fmt.Errorf("invalid JSON in %s: %v", manifest.path(), err)
+		}
+		for _, entry := range entries {
+			manifest.entries[entry.File] = entry
+		}
+	}
+
+	if err := manifest.migrateLegacyInvalidImages(forest, plot); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+func (m *Manifest) path() string {
+	return filepath.Join(m.dir, "manifest.json")
+}
+
+// Entry returns the entry of the image file name.
+func (m *Manifest) Entry(file string) (ManifestEntry, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry, ok := m.entries[file]
+	return entry, ok
+}
+
+// Entries returns every entry sorted by date.
+func (m *Manifest) Entries() []ManifestEntry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entries := make([]ManifestEntry, 0, len(m.entries))
+	for _, entry := range m.entries {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Date == entries[j].Date {
+			return entries[i].File < entries[j].File
+		}
+		return entries[i].Date < entries[j].Date
+	})
+	return entries
+}
+
+// Put records entry, replacing any previous entry of the same file.
+func (m *Manifest) Put(entry ManifestEntry) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry.UpdatedAt = time.Now().UTC()
+	m.entries[entry.File] = entry
+}
+
+// Reject records the file as rejected for reason, to be retried after retryAfter.
+func (m *Manifest) Reject(entry ManifestEntry, reason string, retryAfter time.Duration) {
+	retry := time.Now().UTC().Add(retryAfter)
+	entry.Status = ManifestStatusRejected
+	entry.RejectReason = reason
+	entry.RetryAfter = &retry
+	entry.Checksum = ""
+	m.Put(entry)
+}
+
+// Purge removes the entries matching filter and, if deleteFiles is set, their image files.
+// It returns the number of entries removed.
+func (m *Manifest) Purge(filter func(ManifestEntry) bool, deleteFiles bool) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	purged := 0
+	for file, entry := range m.entries {
+		if !filter(entry) {
+			continue
+		}
+		if deleteFiles {
+			if err := os.Remove(filepath.Join(m.dir, file)); err != nil && !os.IsNotExist(err) {
+				return purged, fmt.Errorf("failed to delete %s: %v", file, err)
+			}
+		}
+		delete(m.entries, file)
+		purged++
+	}
+	return purged, nil
+}
+
+// Save writes the manifest atomically.
+func (m *Manifest) Save() error {
+	entries := m.Entries()
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %v", err)
+	}
+	if err := os.MkdirAll(m.dir, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create directory %s: %v", m.dir, err)
+	}
+	tempPath := m.path() + ".temp"
+	if err := os.WriteFile(tempPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %v", tempPath, err)
+	}
+	return os.Rename(tempPath, m.path())
+}
+
+// migrateLegacyInvalidImages moves the plot's names out of data/images/invalid_images.json,
+// which held bare file names and full paths alike, into rejected manifest entries.
+func (m *Manifest) migrateLegacyInvalidImages(forest, plot string) error {
+	legacyInvalidImagesMutex.Lock()
+	defer legacyInvalidImagesMutex.Unlock()
+
+	legacyPath := fmt.Sprintf("%s/data/images/invalid_images.json", properties.RootPath())
+	data, err := os.ReadFile(legacyPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %v", legacyPath, err)
+	}
+	var legacy []string
+	if err := json.Unmarshal(data, &legacy); err != nil {
+		return fmt.Errorf("invalid JSON in %s: %v", legacyPath, err)
+	}
+
+	prefix := fmt.Sprintf("%s_%s_", forest, plot)
+	var remaining []string
+	migrated := false
+	for _, name := range legacy {
+		file := filepath.Base(name)
+		date, err := time.Parse("2006-01-02", trimImageName(file, prefix))
+		if err != nil {
+			remaining = append(remaining, name)
+			continue
+		}
+		if _, exists := m.entries[file]; !exists {
+			m.Reject(ManifestEntry{
+				File:        file,
+				Date:        date.Format("2006-01-02"),
+				Sensor:      SensorSentinel2,
+				SensingTime: date,
+			}, RejectReasonLegacy, invalidImageRetryAfter)
+		}
+		migrated = true
+	}
+	if !migrated {
+		return nil
+	}
+
+	if err := m.Save(); err != nil {
+		return err
+	}
+	if len(remaining) == 0 {
+		return os.Remove(legacyPath)
+	}
+	data, err = json.Marshal(remaining)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(legacyPath, data, 0644)
+}
+
+// trimImageName returns the date part of a Sentinel-2 cache file name of the plot, or an
+// unparseable string for names of other plots.
+func trimImageName(file, prefix string) string {
+	if !strings.HasPrefix(file, prefix) {
+		return ""
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(file, prefix), ".tif")
+}
+
+// fileChecksum returns the hex SHA-256 of the file at path.
+func fileChecksum(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,166 @@
+package sentinel
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/forest-guardian/forest-guardian-api-poc/internal/config"
+)
+
+// QualityPolicy decides which pixels are masked as unknown (clouds, shadows) or invalid
+// (saturated, empty). Both pixel validation paths use the policy in effect.
+type QualityPolicy struct {
+	// CloudProbability is the CLD value above which a Sentinel-2 pixel is cloudy
+	CloudProbability float64
+	// MaskedSCLClasses are the Sentinel-2 scene classes treated as unknown
+	MaskedSCLClasses []int
+	// Brightness is the mean of B02 and B04 above which a pixel is invalid
+	Brightness float64
+	// DilationRadius extends the cloud and shadow mask by this many pixels
+	DilationRadius int
+}
+
+// DefaultQualityPolicy reproduces the original hard-coded masking rules.
+var DefaultQualityPolicy = QualityPolicy{
+	CloudProbability: 0,
+	MaskedSCLClasses: []int{2, 3, 8, 9, 10},
+	Brightness:       0.9,
+	DilationRadius:   0,
+}
+
+// sclCloudClasses are the scene classes of cloud shadows (3), clouds (8, 9) and cirrus (10),
+// the ones the dilation grows from when masked.
+var sclCloudClasses = []int{3, 8, 9, 10}
+
+var (
+	qualityPolicy     QualityPolicy
+	qualityPolicyErr  error
+	qualityPolicyOnce sync.Once
+)
+
+// ActiveQualityPolicy returns the quality policy of the configuration, resolved once per
+// process. On a configuration error it returns the default policy along with the error.
+func ActiveQualityPolicy() (QualityPolicy, error) {
+	qualityPolicyOnce.Do(func() {
+		qualityPolicy, qualityPolicyErr = resolveQualityPolicy()
+		if qualityPolicyErr != nil {
+			qualityPolicy = DefaultQualityPolicy
+		}
+	})
+	return qualityPolicy, qualityPolicyErr
+}
+
+func resolveQualityPolicy() (QualityPolicy, error) {
+	cfg, err := config.Get()
+	if err != nil {
+		return QualityPolicy{}, err
+	}
+	quality := cfg.Quality
+	if quality.CloudProbability < 0 || quality.Brightness < 0 || quality.DilationRadius < 0 {
+		return QualityPolicy{}, fmt.Errorf("quality settings must not be negative in %s", config.Path())
+	}
+	for _, class := range quality.MaskedSCLClasses {
+		if class < 0 || class > 11 {
+			return QualityPolicy{}, fmt.Errorf("unknown SCL class %d in %s", class, config.Path())
+		}
+	}
+
+	policy := QualityPolicy{
+		CloudProbability: quality.CloudProbability,
+		MaskedSCLClasses: DefaultQualityPolicy.MaskedSCLClasses,
+		Brightness:       DefaultQualityPolicy.Brightness,
+		DilationRadius:   quality.DilationRadius,
+	}
+	if quality.MaskedSCLClasses != nil {
+		policy.MaskedSCLClasses = quality.MaskedSCLClasses
+	}
+	if quality.Brightness != 0 {
+		policy.Brightness = quality.Brightness
+	}
+	return policy, nil
+}
+
+// PixelStatus classifies a pixel under the policy.
+func (policy QualityPolicy) PixelStatus(bands Bands) PixelStatus {
+	var invalidConditions []validityCondition
+	if bands.Sensor == SensorLandsat {
+		qa := int(bands.QAPixel)
+		invalidConditions = []validityCondition{
+			{qa&qaFill != 0, PixelStatusInvalid},
+			{qa&(qaCloud|qaDilatedCloud|qaCirrus) != 0, PixelStatusUnknown},
+			{qa&(qaCloudShadow|qaSnow) != 0, PixelStatusUnknown},
+		}
+	} else {
+		invalidConditions = []validityCondition{
+			{bands.CLD > policy.CloudProbability, PixelStatusUnknown},
+			{slices.Contains(policy.MaskedSCLClasses, int(bands.SCL)), PixelStatusUnknown},
+		}
+	}
+	// Landsat has no red edge, so its NDRE and PSRI are NaN and left out of the zero check
+	invalidConditions = append(invalidConditions,
+		validityCondition{bands.NearCloud, PixelStatusUnknown},
+		validityCondition{(bands.B04+bands.B02)/2 > policy.Brightness, PixelStatusInvalid},
+		validityCondition{(bands.PSRI == 0 || math.IsNaN(bands.PSRI)) && bands.NDVI == 0 && bands.NDMI == 0 && (bands.NDRE == 0 || math.IsNaN(bands.NDRE)), PixelStatusInvalid},
+	)
+
+	for _, condition := range invalidConditions {
+		if condition.Condition {
+			return condition.PixelStatus
+		}
+	}
+	return PixelStatusValid
+}
+
+// obscured reports whether the pixel is a cloud or a cloud shadow the dilation grows from.
+func (policy QualityPolicy) obscured(bands Bands) bool {
+	if bands.Sensor == SensorLandsat {
+		return int(bands.QAPixel)&(qaCloud|qaDilatedCloud|qaCirrus|qaCloudShadow) != 0
+	}
+	scl := int(bands.SCL)
+	return bands.CLD > policy.CloudProbability || (slices.Contains(sclCloudClasses, scl) && slices.Contains(policy.MaskedSCLClasses, scl))
+}
+
+// nearCloudPlane marks with 1 the pixels within DilationRadius of an obscured pixel, the
+// obscured pixels included. It returns nil when the policy does not dilate.
+func (policy QualityPolicy) nearCloudPlane(indexes map[string][][]float64) [][]float64 {
+	if policy.DilationRadius == 0 {
+		return nil
+	}
+	height := len(indexes["b02"])
+	width := len(indexes["b02"][0])
+	radius := policy.DilationRadius
+	plane := make2D(height, width)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if !policy.obscured(GetBands(indexes, x, y)) {
+				continue
+			}
+			for dy := -radius; dy <= radius; dy++ {
+				for dx := -radius; dx <= radius; dx++ {
+					ny, nx := y+dy, x+dx
+					if dx*dx+dy*dy > radius*radius || ny < 0 || ny >= height || nx < 0 || nx >= width {
+						continue
+					}
+					plane[ny][nx] = 1
+				}
+			}
+		}
+	}
+	return plane
+}
+
+// String describes the policy for run reports.
+func (policy QualityPolicy) String() string {
+	classes := make([]string, len(policy.MaskedSCLClasses))
+	for i, class := range policy.MaskedSCLClasses {
+		classes[i] = fmt.Sprint(class)
+	}
+	if len(classes) == 0 {
+		classes = []string{"none"}
+	}
+	return fmt.Sprintf("cloud probability > %g, masked SCL classes %s, brightness > %g, cloud dilation %d px",
+		policy.CloudProbability, strings.Join(classes, ", "), policy.Brightness, policy.DilationRadius)
+}
@@ -1,18 +1,18 @@
 package sentinel
 
 import (
-	"bytes"
-	"context"
 	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
 	"os"
 	"strings"
 	"time"
 
 	"github.com/airbusgeo/godal"
-	"golang.org/x/oauth2/clientcredentials"
+)
+
+const (
+	processURL = "https://sh.dataspace.copernicus.eu/api/v1/process"
+	catalogURL = "https://sh.dataspace.copernicus.eu/api/v1/catalog/1.0.0/search"
 )
 
 func calculatePixels(distance float64, resolution float64) int {
@@ -23,43 +23,125 @@ func calculatePixels(distance float64, resolution float64) int {
 	return int(pixels)
 }
 
-func requestImage(startDate, endDate time.Time, geometry *godal.Geometry) ([]byte, error) {
-	// Format the dates to ensure they are in ISO-8601 format
-	startDateStr := startDate.Format(time.RFC3339)
-	endDateStr := endDate.Format(time.RFC3339)
+// SentinelHubProvider requests Sentinel-2 L2A and Landsat 8/9 L2 imagery, and Sentinel-1 GRD backscatter, from
+// the Copernicus Data Space process API using the OAuth credentials in COPERNICUS_CLIENT_ID/SECRET.
+type SentinelHubProvider struct{}
+
+func NewSentinelHubProvider() *SentinelHubProvider {
+	return &SentinelHubProvider{}
+}
+
+func (p *SentinelHubProvider) FetchImage(request ImageRequest, outputPath string) error {
+	if request.Sensor == SensorLandsat {
+		return fetchSource(request, landsatSource(request.Bands), outputPath)
+	}
+	return fetchSource(request, opticalSource(request.Bands), outputPath)
+}
+
+// imageSource is the process API collection rendered for a request and the evalscript
+// producing its bands.
+type imageSource struct {
+	collection string
+	// dataFilter and processing are merged into the request data entry
+	dataFilter map[string]interface{}
+	processing map[string]interface{}
+	evalscript string
+	bands      []string
+}
+
+// opticalSource renders Sentinel-2 L2A as distributed: the process API would otherwise remove
+// the baseline 04.00 offset silently, and GetImages removes it instead so the correction is
+// recorded with the image.
+func opticalSource(bands []string) imageSource {
+	return imageSource{
+		collection: "sentinel-2-l2a",
+		processing: map[string]interface{}{"harmonizeValues": false},
+		evalscript: buildEvalscript(bands),
+		bands:      bands,
+	}
+}
 
-	bbox, err := geometry.Bounds()
+// fetchSource renders source over the request geometry into a GeoTIFF at outputPath with
+// labelled bands.
+func fetchSource(request ImageRequest, source imageSource, outputPath string) error {
+	bbox, err := request.Geometry.Bounds()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get geometry bounds: %v", err)
+		return fmt.Errorf("failed to get geometry bounds: %v", err)
 	}
 
-	widthPixels := calculatePixels(bbox[2]-bbox[0], 10)
-	heightPixels := calculatePixels(bbox[3]-bbox[1], 10)
-	// Clamp to allowed range (1-2500)
-	if widthPixels > 2500 {
-		widthPixels = 2500
+	// Plots larger than the output limit are requested in native-resolution tiles
+	tiles := splitTiles(bbox, 10)
+	if len(tiles) > 1 {
+		if err := fetchTiledImage(request, source, tiles, outputPath); err != nil {
+			return err
+		}
+		return setBandDescriptions(outputPath, source.bands)
+	}
+
+	imageBytes, err := requestImage(request.StartDate, request.EndDate, request.Geometry, tiles[0], source)
+	if err != nil {
+		return err
 	}
-	if heightPixels > 2500 {
-		heightPixels = 2500
+	if err := os.WriteFile(outputPath, imageBytes, 0644); err != nil {
+		return fmt.Errorf("failed to write image file: %v", err)
+	}
+	return setBandDescriptions(outputPath, source.bands)
+}
+
+// setBandDescriptions labels the bands of the GeoTIFF at path with the given names.
+func setBandDescriptions(path string, names []string) error {
+	godal.RegisterAll()
+	ds, err := godal.Open(path, godal.Update())
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %v", path, err)
+	}
+	defer ds.Close()
+
+	bands := ds.Bands()
+	if len(bands) != len(names) {
+		return fmt.Errorf("image %s has %d bands, expected %d", path, len(bands), len(names))
+	}
+	for i, band := range bands {
+		if err := band.SetDescription(names[i]); err != nil {
+			return fmt.Errorf("failed to label band %d of %s: %v", i+1, path, err)
+		}
+	}
+	return nil
+}
+
+// buildEvalscript returns an evalscript that outputs the given bands as FLOAT32, in order.
+func buildEvalscript(bands []string) string {
+	quoted := make([]string, len(bands))
+	samples := make([]string, len(bands))
+	for i, band := range bands {
+		quoted[i] = fmt.Sprintf("%q", band)
+		samples[i] = "sample." + band
 	}
 
-	evalscript := `
+	return fmt.Sprintf(`
     //VERSION=3
     function setup() {
       return {
-        input: ["B05", "B08", "B11", "B02", "B04", "B06", "CLD", "SCL"],
+        input: [%s],
         output: {
           id: "default",
-          bands: 8,
+          bands: %d,
           sampleType: SampleType.FLOAT32,
         },
       }
     }
 
     function evaluatePixel(sample) {
-      return [sample.B05, sample.B08, sample.B11, sample.B02, sample.B04, sample.B06, sample.CLD, sample.SCL];
+      return [%s];
     }
-  `
+  `, strings.Join(quoted, ", "), len(bands), strings.Join(samples, ", "))
+}
+
+// requestImage renders the part of geometry inside tile through the process API.
+func requestImage(startDate, endDate time.Time, geometry *godal.Geometry, tile imageTile, source imageSource) ([]byte, error) {
+	// Format the dates to ensure they are in ISO-8601 format
+	startDateStr := startDate.Format(time.RFC3339)
+	endDateStr := endDate.Format(time.RFC3339)
 
 	geometryGeojson, err := geometry.GeoJSON()
 	if err != nil {
@@ -71,26 +153,34 @@ func requestImage(startDate, endDate time.Time, geometry *godal.Geometry) ([]byt
 		return nil, fmt.Errorf("failed to parse GeoJSON: %w", err)
 	}
 
+	dataFilter := map[string]interface{}{
+		"timeRange": map[string]string{
+			"from": startDateStr,
+			"to":   endDateStr,
+		},
+	}
+	for key, value := range source.dataFilter {
+		dataFilter[key] = value
+	}
+	data := map[string]interface{}{
+		"dataFilter": dataFilter,
+		"type":       source.collection,
+	}
+	if source.processing != nil {
+		data["processing"] = source.processing
+	}
+
 	requestPayload := map[string]interface{}{
 		"input": map[string]interface{}{
 			"bounds": map[string]interface{}{
+				"bbox":     tile.bbox,
 				"geometry": geojsonMap,
 			},
-			"data": []map[string]interface{}{
-				{
-					"dataFilter": map[string]interface{}{
-						"timeRange": map[string]string{
-							"from": startDateStr,
-							"to":   endDateStr,
-						},
-					},
-					"type": "sentinel-2-l2a",
-				},
-			},
+			"data": []map[string]interface{}{data},
 		},
 		"output": map[string]interface{}{
-			"width":  widthPixels,
-			"height": heightPixels,
+			"width":  tile.width,
+			"height": tile.height,
 			"responses": []map[string]interface{}{
 				{
 					"identifier": "default",
@@ -100,7 +190,7 @@ func requestImage(startDate, endDate time.Time, geometry *godal.Geometry) ([]byt
 				},
 			},
 		},
-		"evalscript": evalscript,
+		"evalscript": source.evalscript,
 		"mosaicking": "mostRecent",
 	}
 
@@ -110,85 +200,14 @@ func requestImage(startDate, endDate time.Time, geometry *godal.Geometry) ([]byt
 		return nil, fmt.Errorf("failed to marshal request payload: %v", err)
 	}
 
-	// OAuth2 configuration from environment variables
-	clientIDs := os.Getenv("COPERNICUS_CLIENT_ID")
-	clientSecrets := os.Getenv("COPERNICUS_CLIENT_SECRET")
-	tokenURL := os.Getenv("COPERNICUS_TOKEN_URL")
-
-	clientIDList := strings.Split(clientIDs, ",")
-
-	clientSecretList := strings.Split(clientSecrets, ",")
-
-	if clientIDs == "" || clientSecrets == "" || tokenURL == "" {
-		return nil, fmt.Errorf("missing required environment variables: COPERNICUS_CLIENT_ID, COPERNICUS_CLIENT_SECRET, or COPERNICUS_TOKEN_URL")
-	}
-
-	var responseContent []byte
-	for i, clientID := range clientIDList {
-		if i >= len(clientSecretList) {
-			return nil, fmt.Errorf("mismatched number of client IDs and secrets")
-		}
-		clientSecret := clientSecretList[i]
-		config := &clientcredentials.Config{
-			ClientID:     clientID,
-			ClientSecret: clientSecret,
-			TokenURL:     tokenURL,
-		}
-
-		// Create an HTTP client with OAuth2
-		httpClient := config.Client(context.Background())
-
-		url := "https://sh.dataspace.copernicus.eu/api/v1/process"
-
-		// Retry logic
-		retries := 3
-		var response *http.Response
-		for attempt := 1; attempt <= retries; attempt++ {
-			response, err = httpClient.Post(url, "application/json", bytes.NewBuffer(requestBody))
-			if err == nil && response.StatusCode == http.StatusOK {
-				break
-			}
-
-			if response != nil {
-				body, _ := io.ReadAll(response.Body)
-				bodyStr := string(body)
-				response.Body.Close()
-				
-				// Don't retry on authentication errors or rate limits
-				if response.StatusCode == http.StatusForbidden || strings.Contains(bodyStr, "403") {
-					err = fmt.Errorf("unauthorized access, check your client ID and secret")
-					break
-				}
-				if response.StatusCode == http.StatusTooManyRequests || strings.Contains(bodyStr, "429") {
-					err = fmt.Errorf("rate limit exceeded for this token")
-					break
-				}
-				fmt.Printf("Client %d - Attempt %d failed: %s\n", i+1, attempt, bodyStr)
-
-			} else {
-				fmt.Printf("Client %d - Attempt %d failed: %v\n", i+1, attempt, err)
-			}
-
-			if attempt < retries {
-				time.Sleep(2 * time.Second)
-			}
-		}
+	return postWithCredentials(processURL, requestBody)
+}
 
-		if err != nil {
-			err = fmt.Errorf("failed to request image after %d attempts: %v", retries, err)
-			continue
-		}
-		defer response.Body.Close()
-
-		// Read the response body
-		responseContent, err = io.ReadAll(response.Body)
-		response.Body.Close()
-		if err != nil {
-			err = fmt.Errorf("failed to read response body: %v", err)
-			continue
-		}
-		break
+// postWithCredentials sends requestBody to url through the shared Copernicus client pool.
+func postWithCredentials(url string, requestBody []byte) ([]byte, error) {
+	pool, err := getClientPool()
+	if err != nil {
+		return nil, err
 	}
-
-	return responseContent, err
+	return pool.post(url, requestBody)
 }
@@ -0,0 +1,208 @@
+package sentinel
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/airbusgeo/godal"
+	"github.com/forest-guardian/forest-guardian-api-poc/internal/config"
+)
+
+// sarBands are the Sentinel-1 polarizations merged into the optical images, as gamma0 in dB.
+var sarBands = []string{"VV", "VH"}
+
+// sarIndexes are the radar features computed when Sentinel-1 is enabled. Backscatter is
+// stored in dB; the ratios are computed on linear power.
+var sarIndexes = []SpectralIndex{
+	{Name: "vv_db", Bands: []string{"VV"}, Formula: func(v []float64) float64 { return v[0] }},
+	{Name: "vh_db", Bands: []string{"VH"}, Formula: func(v []float64) float64 { return v[0] }},
+	{Name: "vh_vv", Bands: []string{"VV", "VH"}, Formula: func(v []float64) float64 {
+		return safeDivide(fromDB(v[1]), fromDB(v[0]))
+	}},
+	// Radar Vegetation Index for dual-polarized data
+	{Name: "rvi", Bands: []string{"VV", "VH"}, Formula: func(v []float64) float64 {
+		vv, vh := fromDB(v[0]), fromDB(v[1])
+		return safeDivide(4*vh, vv+vh)
+	}},
+}
+
+const sarEvalscript = `
+    //VERSION=3
+    function setup() {
+      return {
+        input: ["VV", "VH", "dataMask"],
+        output: {
+          id: "default",
+          bands: 2,
+          sampleType: SampleType.FLOAT32,
+        },
+      }
+    }
+
+    function toDb(value) {
+      return 10 * Math.log(Math.max(value, 1e-6)) / Math.LN10;
+    }
+
+    function evaluatePixel(sample) {
+      if (sample.dataMask == 0) {
+        return [NaN, NaN];
+      }
+      return [toDb(sample.VV), toDb(sample.VH)];
+    }
+  `
+
+// sarSource renders dual-polarized IW scenes, terrain-flattened to gamma0 and orthorectified.
+func sarSource() imageSource {
+	return imageSource{
+		collection: "sentinel-1-grd",
+		dataFilter: map[string]interface{}{
+			"acquisitionMode": "IW",
+			"polarization":    "DV",
+			"resolution":      "HIGH",
+		},
+		processing: map[string]interface{}{
+			"backCoeff":    "GAMMA0_TERRAIN",
+			"orthorectify": true,
+			"demInstance":  "COPERNICUS",
+		},
+		evalscript: sarEvalscript,
+		bands:      sarBands,
+	}
+}
+
+func fromDB(value float64) float64 {
+	return math.Pow(10, value/10)
+}
+
+// IsSARIndex reports whether name is one of the Sentinel-1 features.
+func IsSARIndex(name string) bool {
+	return slices.ContainsFunc(sarIndexes, func(index SpectralIndex) bool { return index.Name == name })
+}
+
+// sarEnabled returns whether Sentinel-1 is merged into the images and the largest gap in
+// days allowed between an optical image and its radar acquisition.
+func sarEnabled() (bool, int, error) {
+	cfg, err := config.Get()
+	if err != nil {
+		return false, 0, err
+	}
+	maxDaysApart := cfg.Sentinel1.MaxDaysApart
+	if maxDaysApart <= 0 {
+		maxDaysApart = 6
+	}
+	return cfg.Sentinel1.Enabled, maxDaysApart, nil
+}
+
+// SearchSAR lists the Sentinel-1 GRD scenes intersecting the request geometry.
+func (p *SentinelHubProvider) SearchSAR(request ImageRequest) ([]Acquisition, error) {
+	return searchCatalog(request, "sentinel-1-grd")
+}
+
+// FetchSAR renders the VV and VH backscatter over the request geometry into outputPath.
+func (p *SentinelHubProvider) FetchSAR(request ImageRequest, outputPath string) error {
+	return fetchSource(request, sarSource(), outputPath)
+}
+
+// nearestAcquisition returns the acquisition sensed closest to date, if it is at most
+// maxDaysApart days away.
+func nearestAcquisition(acquisitions []Acquisition, date time.Time, maxDaysApart int) *Acquisition {
+	var nearest *Acquisition
+	var nearestGap time.Duration
+	for i, acquisition := range acquisitions {
+		gap := acquisition.SensingTime.Sub(date).Abs()
+		if gap > time.Duration(maxDaysApart)*24*time.Hour {
+			continue
+		}
+		if nearest == nil || gap < nearestGap {
+			nearest = &acquisitions[i]
+			nearestGap = gap
+		}
+	}
+	return nearest
+}
+
+// mergeSARBands resamples the radar image at sarPath onto the grid of the optical image at
+// opticalPath and rewrites the optical image with the VV and VH bands appended.
+func mergeSARBands(opticalPath, sarPath string) error {
+	godal.RegisterAll()
+	optical, err := godal.Open(opticalPath)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %v", opticalPath, err)
+	}
+	defer optical.Close()
+
+	opticalNames, err := imageBandNames(optical)
+	if err != nil {
+		return err
+	}
+	structure := optical.Structure()
+	bounds, err := optical.Bounds()
+	if err != nil {
+		return err
+	}
+	geoTransform, err := optical.GeoTransform()
+	if err != nil {
+		return err
+	}
+
+	sar, err := godal.Open(sarPath)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %v", sarPath, err)
+	}
+	defer sar.Close()
+
+	alignedPath := sarPath + ".aligned.tif"
+	aligned, err := sar.Warp(alignedPath, []string{
+		"-t_srs", optical.Projection(),
+		"-te", fmt.Sprint(bounds[0]), fmt.Sprint(bounds[1]), fmt.Sprint(bounds[2]), fmt.Sprint(bounds[3]),
+		"-ts", fmt.Sprint(structure.SizeX), fmt.Sprint(structure.SizeY),
+		"-r", "bilinear",
+		"-dstnodata", "nan",
+	}, godal.GTiff)
+	if err != nil {
+		return fmt.Errorf("failed to align radar image to %s: %v", opticalPath, err)
+	}
+	defer os.Remove(alignedPath)
+	defer aligned.Close()
+
+	mergedPath := opticalPath + ".merged.tif"
+	merged, err := godal.Create(godal.GTiff, mergedPath, len(opticalNames)+len(sarBands), godal.Float32, structure.SizeX, structure.SizeY,
+		godal.CreationOption("TILED=YES", "COMPRESS=LZW"))
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %v", mergedPath, err)
+	}
+	if err := merged.SetGeoTransform(geoTransform); err != nil {
+		merged.Close()
+		return err
+	}
+	if err := merged.SetProjection(optical.Projection()); err != nil {
+		merged.Close()
+		return err
+	}
+
+	sources := append(slices.Clone(optical.Bands()), aligned.Bands()...)
+	names := append(slices.Clone(opticalNames), sarBands...)
+	buffer := make([]float32, structure.SizeX*structure.SizeY)
+	for i, band := range merged.Bands() {
+		if err := sources[i].Read(0, 0, buffer, structure.SizeX, structure.SizeY); err != nil {
+			merged.Close()
+			return fmt.Errorf("failed to read band %s: %v", names[i], err)
+		}
+		if err := band.Write(0, 0, buffer, structure.SizeX, structure.SizeY); err != nil {
+			merged.Close()
+			return fmt.Errorf("failed to write band %s: %v", names[i], err)
+		}
+		if err := band.SetDescription(names[i]); err != nil {
+			merged.Close()
+			return err
+		}
+	}
+	if err := merged.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(mergedPath, opticalPath)
+}
@@ -0,0 +1,104 @@
+package sentinel
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sync"
+
+	"github.com/airbusgeo/godal"
+	"github.com/forest-guardian/forest-guardian-api-poc/internal/properties"
+	"github.com/gammazero/workerpool"
+)
+
+// maxOutputPixels is the largest width or height the process API renders in one request.
+const maxOutputPixels = 2500
+
+// imageTile is a piece of the request bounding box rendered in a single process request.
+type imageTile struct {
+	bbox   [4]float64
+	width  int
+	height int
+}
+
+// splitTiles divides bbox (minX, minY, maxX, maxY in degrees) into tiles of at most
+// maxOutputPixels per side at the given resolution in meters. All tiles share the same
+// pixel size, so they line up exactly when mosaicked back together.
+func splitTiles(bbox [4]float64, resolution float64) []imageTile {
+	widthPixels := calculatePixels(bbox[2]-bbox[0], resolution)
+	heightPixels := calculatePixels(bbox[3]-bbox[1], resolution)
+	pixelWidth := (bbox[2] - bbox[0]) / float64(widthPixels)
+	pixelHeight := (bbox[3] - bbox[1]) / float64(heightPixels)
+
+	columns := int(math.Ceil(float64(widthPixels) / maxOutputPixels))
+	rows := int(math.Ceil(float64(heightPixels) / maxOutputPixels))
+	tileWidth := int(math.Ceil(float64(widthPixels) / float64(columns)))
+	tileHeight := int(math.Ceil(float64(heightPixels) / float64(rows)))
+
+	var tiles []imageTile
+	for row := 0; row < rows; row++ {
+		height := min(tileHeight, heightPixels-row*tileHeight)
+		maxY := bbox[3] - float64(row*tileHeight)*pixelHeight
+		for column := 0; column < columns; column++ {
+			width := min(tileWidth, widthPixels-column*tileWidth)
+			minX := bbox[0] + float64(column*tileWidth)*pixelWidth
+			tiles = append(tiles, imageTile{
+				bbox:   [4]float64{minX, maxY - float64(height)*pixelHeight, minX + float64(width)*pixelWidth, maxY},
+				width:  width,
+				height: height,
+			})
+		}
+	}
+	return tiles
+}
+
+// fetchTiledImage requests every tile in parallel and mosaics them into a single GeoTIFF at outputPath.
+func fetchTiledImage(request ImageRequest, source imageSource, tiles []imageTile, outputPath string) error {
+	tilePaths := make([]string, len(tiles))
+	var errs []error
+	var mutex sync.Mutex
+	wp := workerpool.New(properties.ImageDownloadWorkers())
+	for i, tile := range tiles {
+		tilePaths[i] = fmt.Sprintf("%s.tile%d.tif", outputPath, i)
+		wp.Submit(func() {
+			imageBytes, err := requestImage(request.StartDate, request.EndDate, request.Geometry, tile, source)
+			if err == nil {
+				err = os.WriteFile(tilePaths[i], imageBytes, 0644)
+			}
+			if err != nil {
+				mutex.Lock()
+				errs = append(errs, fmt.Errorf("failed to fetch tile %d: %v", i, err))
+				mutex.Unlock()
+			}
+		})
+	}
+	wp.StopWait()
+	defer func() {
+		for _, tilePath := range tilePaths {
+			os.Remove(tilePath)
+		}
+	}()
+	if len(errs) > 0 {
+		return errs[0]
+	}
+
+	return mosaicTiles(tilePaths, outputPath)
+}
+
+// mosaicTiles merges adjacent GeoTIFF tiles into one GeoTIFF through an intermediate VRT.
+func mosaicTiles(tilePaths []string, outputPath string) error {
+	godal.RegisterAll()
+	vrtPath := outputPath + ".vrt"
+	vrt, err := godal.BuildVRT(vrtPath, tilePaths, nil)
+	if err != nil {
+		return fmt.Errorf("failed to build tile mosaic: %v", err)
+	}
+	defer os.Remove(vrtPath)
+	defer vrt.Close()
+
+	outDS, err := vrt.Translate(outputPath, nil, godal.GTiff)
+	if err != nil {
+		return fmt.Errorf("failed to write tile mosaic: %v", err)
+	}
+	return outDS.Close()
+}
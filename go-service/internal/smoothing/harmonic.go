@@ -0,0 +1,70 @@
+package smoothing
+
+import (
+	"fmt"
+	"math"
+)
+
+// harmonicRidge is the penalty on the trend and harmonic coefficients, small enough to leave
+// a well-sampled fit unchanged but keeping short or irregular series solvable.
+const harmonicRidge = 1e-6
+
+// HarmonicFit is a mean, a linear trend and the first Order harmonics of Period fitted to a
+// series by least squares.
+type HarmonicFit struct {
+	Period       float64
+	Order        int
+	Coefficients []float64
+}
+
+// HarmonicTerms is the number of coefficients of a fit of the given order.
+func HarmonicTerms(order int) int {
+	return 2 + 2*order
+}
+
+// FitHarmonic fits values observed at times, in the unit of period. It needs more values
+// than coefficients and no NaN.
+func FitHarmonic(times, values []float64, order int, period float64) (HarmonicFit, error) {
+	terms := HarmonicTerms(order)
+	if len(values) <= terms {
+		return HarmonicFit{}, fmt.Errorf("%d values cannot fit %d harmonic coefficients", len(values), terms)
+	}
+	fit := HarmonicFit{Period: period, Order: order}
+	a := make([][]float64, 0, len(values)+terms-1)
+	b := make([]float64, 0, len(values)+terms-1)
+	for i, value := range values {
+		if math.IsNaN(value) {
+			return HarmonicFit{}, ErrNaN
+		}
+		a = append(a, fit.terms(times[i]))
+		b = append(b, value)
+	}
+	// The mean is left unpenalized
+	for j := 1; j < terms; j++ {
+		row := make([]float64, terms)
+		row[j] = math.Sqrt(harmonicRidge)
+		a = append(a, row)
+		b = append(b, 0)
+	}
+	fit.Coefficients = leastSquares(a, b)
+	return fit, nil
+}
+
+// At evaluates the fit at a time.
+func (f HarmonicFit) At(t float64) float64 {
+	value := 0.0
+	for j, term := range f.terms(t) {
+		value += f.Coefficients[j] * term
+	}
+	return value
+}
+
+func (f HarmonicFit) terms(t float64) []float64 {
+	terms := make([]float64, 0, HarmonicTerms(f.Order))
+	terms = append(terms, 1, t/f.Period)
+	for k := 1; k <= f.Order; k++ {
+		angle := 2 * math.Pi * float64(k) * t / f.Period
+		terms = append(terms, math.Cos(angle), math.Sin(angle))
+	}
+	return terms
+}
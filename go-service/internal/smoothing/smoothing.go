@@ -0,0 +1,292 @@
+// Package smoothing clears the outliers of pixel time series and smooths them, reproducing
+// clear_and_smooth.py of the Python service: a rolling-window outlier replacement followed by
+// the fit of a penalized B-spline, pygam's LinearGAM(s(0, lam=lambda)).
+package smoothing
+
+import (
+	"errors"
+	"math"
+)
+
+// Parameters tune the outlier replacement and the smoothing.
+type Parameters struct {
+	// Lambda is the penalty of the smoothing spline
+	Lambda float64
+	// Window is the number of neighbors on each side a value is compared with
+	Window int
+	// Threshold is the share of the window standard deviation a value may deviate from the
+	// window mean before it is replaced by the mean
+	Threshold float64
+}
+
+// DefaultParameters are the parameters of the Python service.
+var DefaultParameters = Parameters{Lambda: 0.1, Window: 10, Threshold: 0.02}
+
+// nSplines and splineOrder are pygam's defaults for a spline term
+const (
+	nSplines    = 20
+	splineOrder = 3
+)
+
+// ParityTolerance is the largest difference from the Python service's smoothing accepted as
+// parity.
+const ParityTolerance = 1e-6
+
+// ErrNaN is returned for series holding NaN, which pygam refuses to fit.
+var ErrNaN = errors.New("series contains NaN")
+
+// ClearAndSmooth replaces the outliers of values and smooths the result.
+func ClearAndSmooth(values []float64, parameters Parameters) ([]float64, error) {
+	return Smooth(DetectOutliers(values, parameters.Window, parameters.Threshold), parameters.Lambda)
+}
+
+// DetectOutliers replaces every value further than threshold standard deviations from the
+// mean of the values within window positions of it by that mean.
+func DetectOutliers(values []float64, window int, threshold float64) []float64 {
+	cleaned := make([]float64, len(values))
+	for i, value := range values {
+		start, end := max(0, i-window), min(len(values), i+window+1)
+		mean, std := meanStd(values[start:end])
+		if math.Abs(value-mean) > threshold*std {
+			cleaned[i] = mean
+		} else {
+			cleaned[i] = value
+		}
+	}
+	return cleaned
+}
+
+// Smooth fits a cubic P-spline of nSplines bases and an intercept to values, taken at evenly
+// spaced positions, with a second-difference penalty weighted by lambda, and returns the
+// fitted values. Constant series are returned as is.
+func Smooth(values []float64, lambda float64) ([]float64, error) {
+	y := append([]float64(nil), values...)
+	allZero := true
+	for _, value := range y {
+		if math.IsNaN(value) {
+			return nil, ErrNaN
+		}
+		allZero = allZero && value == 0
+	}
+	if _, std := meanStd(y); allZero || std == 0 {
+		return y, nil
+	}
+
+	basis := modelMatrix(len(y))
+	coef := solvePenalized(basis, y, penalty(lambda))
+	fitted := make([]float64, len(y))
+	for i, row := range basis {
+		for j, b := range row {
+			fitted[i] += b * coef[j]
+		}
+	}
+	return fitted, nil
+}
+
+// meanStd returns the mean and the population standard deviation, summed as numpy does.
+func meanStd(values []float64) (float64, float64) {
+	n := float64(len(values))
+	mean := pairwiseSum(values) / n
+	squares := make([]float64, len(values))
+	for i, value := range values {
+		squares[i] = (value - mean) * (value - mean)
+	}
+	return mean, math.Sqrt(pairwiseSum(squares) / n)
+}
+
+// pairwiseSum is numpy's summation, whose rounding differs from a plain loop past 8 values.
+func pairwiseSum(values []float64) float64 {
+	n := len(values)
+	if n < 8 {
+		sum := 0.0
+		for _, value := range values {
+			sum += value
+		}
+		return sum
+	}
+	if n <= 128 {
+		var r [8]float64
+		copy(r[:], values[:8])
+		i := 8
+		for ; i < n-n%8; i += 8 {
+			for j := range r {
+				r[j] += values[i+j]
+			}
+		}
+		sum := ((r[0] + r[1]) + (r[2] + r[3])) + ((r[4] + r[5]) + (r[6] + r[7]))
+		for ; i < n; i++ {
+			sum += values[i]
+		}
+		return sum
+	}
+	half := n / 2
+	half -= half % 8
+	return pairwiseSum(values[:half]) + pairwiseSum(values[half:])
+}
+
+// modelMatrix returns the B-spline bases evaluated at n evenly spaced positions, followed by
+// the intercept column, as pygam builds them for positions 0..n-1.
+func modelMatrix(n int) [][]float64 {
+	scale := float64(n - 1)
+	if scale == 0 {
+		scale = 1
+	}
+
+	// Knots: evenly spaced on [0, 1], extended by splineOrder knots on each side
+	boundary := nSplines - splineOrder + 1
+	step := 1 / float64(boundary-1)
+	knots := make([]float64, 0, boundary+2*splineOrder)
+	for k := splineOrder; k >= 1; k-- {
+		knots = append(knots, -float64(k)*step)
+	}
+	for k := range boundary {
+		knots = append(knots, float64(k)*step)
+	}
+	knots[len(knots)-1] = 1
+	for k := 1; k <= splineOrder; k++ {
+		knots = append(knots, 1+float64(k)*step)
+	}
+	knots[len(knots)-1] += 1e-9
+
+	matrix := make([][]float64, n)
+	for i := range matrix {
+		x := float64(i) / scale
+		bases := make([]float64, len(knots)-1)
+		for j := range bases {
+			if x >= knots[j] && x < knots[j+1] {
+				bases[j] = 1
+			}
+		}
+		// Cox-de Boor recursion, as in Hastie et al.
+		for m := 2; m <= splineOrder+1; m++ {
+			next := make([]float64, len(bases)-1)
+			for j := range next {
+				left := (x - knots[j]) * bases[j] / (knots[j+m-1] - knots[j])
+				right := (knots[j+m] - x) * bases[j+1] / (knots[j+m] - knots[j+1])
+				next[j] = left + right
+			}
+			bases = next
+		}
+		matrix[i] = append(bases, 1)
+	}
+	return matrix
+}
+
+// penalty returns lambda times the second-difference penalty of the spline coefficients,
+// with the unpenalized intercept last, plus pygam's sqrt(eps) ridge improving conditioning.
+func penalty(lambda float64) [][]float64 {
+	m := nSplines + 1
+	p := make([][]float64, m)
+	for i := range p {
+		p[i] = make([]float64, m)
+	}
+	for k := 0; k+2 < nSplines; k++ {
+		d := [3]float64{1, -2, 1}
+		for a := range d {
+			for b := range d {
+				p[k+a][k+b] += lambda * d[a] * d[b]
+			}
+		}
+	}
+	ridge := math.Sqrt(math.Nextafter(1, 2) - 1)
+	for i := range p {
+		p[i][i] += ridge
+	}
+	return p
+}
+
+// solvePenalized returns the coefficients minimizing |basis*coef - y|^2 + coef'*p*coef. The
+// penalty is factored as E'E and the problem solved as the least squares of basis stacked on
+// E, as pygam does, which keeps the conditioning of the basis rather than squaring it.
+func solvePenalized(basis [][]float64, y []float64, p [][]float64) []float64 {
+	m := len(p)
+	e := choleskyUpper(p)
+	a := make([][]float64, 0, len(basis)+m)
+	b := make([]float64, 0, len(basis)+m)
+	for i, row := range basis {
+		a = append(a, append([]float64(nil), row...))
+		b = append(b, y[i])
+	}
+	for i := range e {
+		a = append(a, e[i])
+		b = append(b, 0)
+	}
+	return leastSquares(a, b)
+}
+
+// choleskyUpper returns the upper triangular U with U'U = p.
+func choleskyUpper(p [][]float64) [][]float64 {
+	m := len(p)
+	u := make([][]float64, m)
+	for i := range u {
+		u[i] = make([]float64, m)
+	}
+	for j := range m {
+		sum := p[j][j]
+		for k := range j {
+			sum -= u[k][j] * u[k][j]
+		}
+		u[j][j] = math.Sqrt(sum)
+		for i := j + 1; i < m; i++ {
+			sum := p[j][i]
+			for k := range j {
+				sum -= u[k][j] * u[k][i]
+			}
+			u[j][i] = sum / u[j][j]
+		}
+	}
+	return u
+}
+
+// leastSquares solves min |a*x - b| by Householder QR. a has at least as many rows as
+// columns and full column rank; it is overwritten.
+func leastSquares(a [][]float64, b []float64) []float64 {
+	rows, cols := len(a), len(a[0])
+	for k := range cols {
+		norm := 0.0
+		for i := k; i < rows; i++ {
+			norm = math.Hypot(norm, a[i][k])
+		}
+		if norm == 0 {
+			continue
+		}
+		if a[k][k] > 0 {
+			norm = -norm
+		}
+		// Householder vector v = a[k:, k] - norm*e1, stored in place
+		a[k][k] -= norm
+		vv := 0.0
+		for i := k; i < rows; i++ {
+			vv += a[i][k] * a[i][k]
+		}
+		for j := k + 1; j < cols; j++ {
+			dot := 0.0
+			for i := k; i < rows; i++ {
+				dot += a[i][k] * a[i][j]
+			}
+			factor := 2 * dot / vv
+			for i := k; i < rows; i++ {
+				a[i][j] -= factor * a[i][k]
+			}
+		}
+		dot := 0.0
+		for i := k; i < rows; i++ {
+			dot += a[i][k] * b[i]
+		}
+		factor := 2 * dot / vv
+		for i := k; i < rows; i++ {
+			b[i] -= factor * a[i][k]
+		}
+		a[k][k] = norm
+	}
+
+	x := make([]float64, cols)
+	for k := cols - 1; k >= 0; k-- {
+		sum := b[k]
+		for j := k + 1; j < cols; j++ {
+			sum -= a[k][j] * x[j]
+		}
+		x[k] = sum / a[k][k]
+	}
+	return x
+}
@@ -0,0 +1,104 @@
+package smoothing_test
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"testing"
+
+	"github.com/forest-guardian/forest-guardian-api-poc/internal/smoothing"
+)
+
+// goldenPath holds the outputs of the Python service's clear_and_smooth on fixed series,
+// written by python-service/generate_smoothing_golden.py.
+const goldenPath = "testdata/pygam_golden.json"
+
+type goldenFile struct {
+	Parameters struct {
+		Lambda    float64 `json:"lambda"`
+		Window    int     `json:"window"`
+		Threshold float64 `json:"threshold"`
+	} `json:"parameters"`
+	Cases []struct {
+		Name     string    `json:"name"`
+		Values   []float64 `json:"values"`
+		Smoothed []float64 `json:"smoothed"`
+	} `json:"cases"`
+}
+
+func TestClearAndSmoothMatchesPygam(t *testing.T) {
+	data, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatalf("%v; run python-service/generate_smoothing_golden.py to write it", err)
+	}
+	var golden goldenFile
+	if err := json.Unmarshal(data, &golden); err != nil {
+		t.Fatalf("invalid %s: %v", goldenPath, err)
+	}
+	if len(golden.Cases) == 0 {
+		t.Fatalf("%s holds no cases", goldenPath)
+	}
+
+	parameters := smoothing.Parameters{
+		Lambda:    golden.Parameters.Lambda,
+		Window:    golden.Parameters.Window,
+		Threshold: golden.Parameters.Threshold,
+	}
+	if parameters != smoothing.DefaultParameters {
+		t.Errorf("golden parameters %+v differ from the defaults %+v", parameters, smoothing.DefaultParameters)
+	}
+	for _, test := range golden.Cases {
+		t.Run(test.Name, func(t *testing.T) {
+			smoothed, err := smoothing.ClearAndSmooth(test.Values, parameters)
+			if err != nil {
+				t.Fatalf("ClearAndSmooth failed: %v", err)
+			}
+			if len(smoothed) != len(test.Smoothed) {
+				t.Fatalf("got %d values, pygam %d", len(smoothed), len(test.Smoothed))
+			}
+			for i := range smoothed {
+				if diff := math.Abs(smoothed[i] - test.Smoothed[i]); !(diff <= smoothing.ParityTolerance) {
+					t.Errorf("value %d = %v, pygam %v, off by %g", i, smoothed[i], test.Smoothed[i], diff)
+				}
+			}
+		})
+	}
+}
+
+// Linear series lie in the null space of the second-difference penalty and in the span of the
+// cubic B-splines, so LinearGAM returns them unchanged whatever the penalty.
+func TestSmoothKeepsLinearSeries(t *testing.T) {
+	for _, n := range []int{4, 12, 73} {
+		for _, lambda := range []float64{0.1, 1, 100} {
+			values := make([]float64, n)
+			for i := range values {
+				values[i] = 0.2 + 0.01*float64(i)
+			}
+			smoothed, err := smoothing.Smooth(values, lambda)
+			if err != nil {
+				t.Fatalf("Smooth(%d values, %g) failed: %v", n, lambda, err)
+			}
+			for i := range values {
+				if diff := math.Abs(smoothed[i] - values[i]); !(diff <= smoothing.ParityTolerance) {
+					t.Errorf("Smooth(%d values, %g): value %d = %v, want %v", n, lambda, i, smoothed[i], values[i])
+				}
+			}
+		}
+	}
+}
+
+func TestSmoothConstantAndNaN(t *testing.T) {
+	constant := []float64{0.4, 0.4, 0.4, 0.4, 0.4}
+	smoothed, err := smoothing.Smooth(constant, 0.1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range constant {
+		if smoothed[i] != constant[i] {
+			t.Errorf("constant series changed at %d: %v", i, smoothed[i])
+		}
+	}
+	if _, err := smoothing.Smooth([]float64{0.1, math.NaN(), 0.3}, 0.1); err != smoothing.ErrNaN {
+		t.Errorf("Smooth with NaN returned %v, want ErrNaN", err)
+	}
+}
@@ -6,9 +6,11 @@ import (
 	"strings"
 	"time"
 
+	"github.com/forest-guardian/forest-guardian-api-poc/internal/dataset"
 	"github.com/forest-guardian/forest-guardian-api-poc/internal/delivery"
 	"github.com/forest-guardian/forest-guardian-api-poc/internal/notification"
 	"github.com/forest-guardian/forest-guardian-api-poc/internal/properties"
+	"github.com/forest-guardian/forest-guardian-api-poc/internal/sentinel"
 )
 
 type AccuracyReport struct {
@@ -27,6 +29,9 @@ type AccuracyReport struct {
 	TrainingStatsFormatted string
 	ValidationStatsFormatted string
 	AccretionMissFormatted string
+	QualityPolicy         string
+	GapFilling            string
+	Lineage               string
 	Error                 string
 }
 
@@ -140,6 +145,9 @@ func generateAccuracyMarkdownReport(report *AccuracyReport) error {
 - **Test Type**: Accuracy Assessment
 - **Generated on**: %s
 - **Model Validation Pipeline**: v1.0
+- **Quality Policy**: %s
+- **Gap Filling**: %s
+- **Dataset Lineage**: %s
 
 ## Statistical Summary
 - **Sample Size**: %d test cases
@@ -151,6 +159,9 @@ func generateAccuracyMarkdownReport(report *AccuracyReport) error {
 *Report generated automatically by Forest Guardian ML Pipeline*
 `, report.TrainingRatio, 100-report.TrainingRatio, 
 		time.Now().Format("2006-01-02 15:04:05"), 
+		report.QualityPolicy,
+		report.GapFilling,
+		report.Lineage,
 		report.TotalTests, report.AccuracyPercentage, report.TotalTests,
 		func() string {
 			if report.AccuracyPercentage >= 90 { return "High" }
@@ -193,24 +204,51 @@ func AccuracyTest() {
 
 	// Create training model filename that preserves the original format
 	// Extract the base name without extension
-	baseName := strings.TrimSuffix(selectedModel, ".csv")
-	// Add training indicator and timestamp
-	trainingModelFileName := fmt.Sprintf("%s_training_%s_%d.csv",
+	baseName := dataset.TrimFinalDataExtension(selectedModel)
+	// Add training indicator and timestamp, keeping the file format of the source model
+	trainingModelFileName := fmt.Sprintf("%s_training_%s_%d%s",
 		baseName,
 		time.Now().Format("2006-01-02"),
-		trainingRatio)
+		trainingRatio,
+		strings.TrimPrefix(selectedModel, baseName))
 
 	fmt.Printf("\033[32mStarting accuracy test with:\033[0m\n")
 	fmt.Printf("\033[32m- Source model: %s\033[0m\n", selectedModel)
 	fmt.Printf("\033[32m- Training ratio: %d%%\033[0m\n", trainingRatio)
 	fmt.Printf("\033[32m- Training model will be: %s\033[0m\n", trainingModelFileName)
 
+	lineage, hasLineage, err := delivery.ReadDatasetLineage(selectedModel)
+	if err != nil {
+		fmt.Printf("\n\033[31m%s\033[0m\n", err.Error())
+		return
+	}
+	// The settings the dataset was built with, or for models without lineage the configured
+	// quality policy and the gap filling recorded in the rows
+	qualityPolicy, gapFilling, lineageSummary := lineage.QualityPolicy, lineage.GapFilling, lineage.String()
+	if !hasLineage {
+		activePolicy, err := sentinel.ActiveQualityPolicy()
+		if err != nil {
+			fmt.Printf("\n\033[31m%s\033[0m\n", err.Error())
+			return
+		}
+		qualityPolicy = activePolicy.String()
+		gapFilling, err = recordedGapFilling(selectedModel)
+		if err != nil {
+			fmt.Printf("\n\033[31m%s\033[0m\n", err.Error())
+			return
+		}
+		lineageSummary = "not recorded, parameters parsed from the model name"
+	}
+
 	// Initialize accuracy report
 	report := &AccuracyReport{
 		SourceModel:    selectedModel,
 		TrainingModel:  trainingModelFileName,
 		TrainingRatio:  trainingRatio,
 		TestStartTime:  time.Now(),
+		QualityPolicy:  qualityPolicy,
+		GapFilling:     gapFilling,
+		Lineage:        lineageSummary,
 	}
 
 	accuracy, totalTests, correctPredictions, trainingStats, validationStats, accretionMissStats, err := delivery.RunAccuracyTest(
@@ -292,3 +330,17 @@ func AccuracyTest() {
 		accuracyPercentage)
 	notification.SendDiscordSuccessNotification(conclusionMessage)
 }
+
+// recordedGapFilling describes the gap filling strategies the rows of a model were built with.
+func recordedGapFilling(model string) (string, error) {
+	rows, err := dataset.ReadFinalDataFile(fmt.Sprintf("%s/data/model/%s", properties.RootPath(), model))
+	if err != nil {
+		return "", fmt.Errorf("error reading model: %v", err)
+	}
+
+	strategies := dataset.GapFillingStrategies(rows)
+	if len(strategies) == 0 {
+		return "unrecorded", nil
+	}
+	return strings.Join(strategies, ", "), nil
+}
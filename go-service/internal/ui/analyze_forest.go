@@ -5,9 +5,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"slices"
 	"strings"
 	"time"
 
+	"github.com/forest-guardian/forest-guardian-api-poc/internal/dataset"
 	"github.com/forest-guardian/forest-guardian-api-poc/internal/delivery"
 	"github.com/forest-guardian/forest-guardian-api-poc/internal/notification"
 	"github.com/forest-guardian/forest-guardian-api-poc/internal/properties"
@@ -21,11 +23,9 @@ func AnalyzeForest() {
 	fmt.Println("\033[33m- The '.geojson' file should contain the desired plot in its features identified by plot_id.\n\033[0m")
 	reader := bufio.NewReader(os.Stdin)
 
-	modelFolderPath := fmt.Sprintf("%s/data/model/", properties.RootPath())
-
-	modelFiles, err := os.ReadDir(modelFolderPath)
+	modelFiles, err := listModels()
 	if err != nil {
-		fmt.Printf("\n\033[31mError reading model folder: %s\033[0m\n", err.Error())
+		fmt.Printf("\n\033[31m%s\033[0m\n", err.Error())
 		return
 	}
 
@@ -36,7 +36,7 @@ func AnalyzeForest() {
 
 	fmt.Println("\033[32m\nAvailable models:\033[0m")
 	for i, file := range modelFiles {
-		fmt.Printf("\033[32m%d. %s\033[0m\n", i+1, file.Name())
+		fmt.Printf("\033[32m%d. %s\033[0m\n", i+1, file)
 	}
 
 	fmt.Print("\033[34mEnter the number of the model you want to use: \033[0m")
@@ -47,7 +47,7 @@ func AnalyzeForest() {
 		return
 	}
 
-	selectedModel := modelFiles[modelChoice-1].Name()
+	selectedModel := modelFiles[modelChoice-1]
 	fmt.Printf("\033[32mYou selected the model: %s\033[0m\n", selectedModel)
 
 	fmt.Print("\033[34mEnter the forest name: \033[0m")
@@ -106,6 +106,12 @@ func AnalyzeForest() {
 	fmt.Printf("\033[33mForest %s has %d plots that will be analyzed\n\033[0m", forest, len(plotIDs))
 	errs := []error{}
 	startTime := time.Now()
+
+	fmt.Printf("\033[32m\nDownloading images for %d plots\033[0m\n", len(plotIDs))
+	for _, err := range delivery.PrefetchForestImages(selectedModel, forest, plotIDs, endDate) {
+		fmt.Printf("\n\033[33mError prefetching images, the plot will be retried during analysis: %s\033[0m\n", err.Error())
+	}
+
 	completed := 0
 	for _, plot := range plotIDs {
 		fmt.Printf("\033[32m\nStarting forest %s plot %s analysis\033[0m\n", forest, plot)
@@ -129,6 +135,8 @@ func AnalyzeForest() {
 			fmt.Printf("\n\033[31mError reading image folder: %s\033[0m\n", err.Error())
 			errs = append(errs, err)
 		}
+		// The folder also holds the plot's manifest.json and grid.json
+		files = slices.DeleteFunc(files, func(file os.DirEntry) bool { return !strings.HasSuffix(file.Name(), ".tif") })
 
 		if len(files) == 0 {
 			fmt.Printf("\n\033[31mNo tiff images found to create resultant image\033[0m\n")
@@ -147,7 +155,7 @@ func AnalyzeForest() {
 			return
 		}
 
-		outputFilePath := fmt.Sprintf("%s/%s_%s_%s_%s", resultPath, forest, plot, endDate.Format("2006-01-02"), strings.TrimSuffix(selectedModel, ".csv"))
+		outputFilePath := fmt.Sprintf("%s/%s_%s_%s_%s", resultPath, forest, plot, endDate.Format("2006-01-02"), dataset.TrimFinalDataExtension(selectedModel))
 
 		output.CreateFinalDataGeoJson(result, outputFilePath)
 
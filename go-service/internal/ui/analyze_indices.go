@@ -54,7 +54,13 @@ func AnalyzeIndices() {
 		return
 	}
 
-	images, err := sentinel.GetImages(geometry, forest, plot, startDate, endDate, 1)
+	provider, err := sentinel.NewImageProvider()
+	if err != nil {
+		fmt.Printf("\n\033[31mError creating image provider: %s\033[0m\n", err.Error())
+		return
+	}
+
+	images, err := sentinel.GetImages(provider, geometry, forest, plot, startDate, endDate, 1)
 	if err != nil {
 		fmt.Printf("\n\033[31mError retrieving images: %s\033[0m\n", err.Error())
 		return
@@ -72,15 +78,8 @@ func AnalyzeIndices() {
 		return
 	}
 
-	groupedCleanData := make(map[time.Time][]dataset.PixelData)
-	for _, sortedPixels := range cleanData {
-		for date, pixel := range sortedPixels {
-			groupedCleanData[date] = append(groupedCleanData[date], pixel)
-		}
-	}
-
-	for date, pixels := range groupedCleanData {
-		output.CreateCleanDataImage(pixels, forest, plot, date)
+	for i, date := range cleanData.Dates {
+		output.CreateCleanDataImage(cleanData.ValidPixels(i), forest, plot, date)
 	}
 
 	imagesPath := fmt.Sprintf("%s/data/result/%s/%s/clean", properties.RootPath(), forest, plot)
@@ -3,8 +3,10 @@ package ui
 import (
 	"fmt"
 	"os"
+	"slices"
 	"strings"
 
+	"github.com/forest-guardian/forest-guardian-api-poc/internal/dataset"
 	"github.com/forest-guardian/forest-guardian-api-poc/internal/delivery"
 	"github.com/forest-guardian/forest-guardian-api-poc/internal/properties"
 	"github.com/forest-guardian/forest-guardian-api-poc/output"
@@ -49,6 +51,8 @@ func AnalyzePlot() {
 		PrintError(fmt.Sprintf("Error reading image folder: %s", err.Error()))
 		return
 	}
+	// The folder also holds the plot's manifest.json and grid.json
+	files = slices.DeleteFunc(files, func(file os.DirEntry) bool { return !strings.HasSuffix(file.Name(), ".tif") })
 
 	if len(files) == 0 {
 		PrintError("No tiff images found to create resultant image")
@@ -65,7 +69,7 @@ func AnalyzePlot() {
 	// Create output files
 	firstFileName := files[0].Name()
 	firstFilePath := fmt.Sprintf("%s%s", imageFolderPath, firstFileName)
-	outputFilePath := fmt.Sprintf("%s/%s_%s_%s_%s", resultPath, forest, plot, endDate.Format("2006-01-02"), strings.TrimSuffix(selectedModel, ".csv"))
+	outputFilePath := fmt.Sprintf("%s/%s_%s_%s_%s", resultPath, forest, plot, endDate.Format("2006-01-02"), dataset.TrimFinalDataExtension(selectedModel))
 
 	output.CreateFinalDataGeoJson(result, outputFilePath)
 
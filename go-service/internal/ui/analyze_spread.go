@@ -55,7 +55,13 @@ func AnalyzeSpread() {
 		return
 	}
 
-	images, err := sentinel.GetImages(geometry, forest, plot, startDate, endDate, 1)
+	provider, err := sentinel.NewImageProvider()
+	if err != nil {
+		fmt.Printf("\n\033[31mError creating image provider: %s\033[0m\n", err.Error())
+		return
+	}
+
+	images, err := sentinel.GetImages(provider, geometry, forest, plot, startDate, endDate, 1)
 	if err != nil {
 		fmt.Printf("\n\033[31mError retrieving images: %s\033[0m\n", err.Error())
 		return
@@ -73,13 +79,6 @@ func AnalyzeSpread() {
 		return
 	}
 
-	groupedCleanData := make(map[time.Time][]dataset.PixelData)
-	for _, sortedPixels := range cleanData {
-		for date, pixel := range sortedPixels {
-			groupedCleanData[date] = append(groupedCleanData[date], pixel)
-		}
-	}
-
 	deltaData, err := dataset.CreateDeltaDataset(forest, plot, 1, 20, cleanData)
 	if err != nil {
 		fmt.Printf("\n\033[31mError creating delta dataset: %s\033[0m\n", err.Error())
@@ -0,0 +1,71 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/forest-guardian/forest-guardian-api-poc/internal/dataset"
+	"github.com/forest-guardian/forest-guardian-api-poc/internal/sentinel"
+	"github.com/forest-guardian/forest-guardian-api-poc/internal/smoothing"
+)
+
+// CompareSmoothers checks the Go smoother against the Python service on the series of a
+// forest plot. The Python service must be running.
+func CompareSmoothers() {
+	forest, plot, err := ReadForestAndPlot()
+	if err != nil {
+		PrintError(err.Error())
+		return
+	}
+	startDate, endDate, err := ReadDateRange()
+	if err != nil {
+		PrintError(err.Error())
+		return
+	}
+
+	geometry, err := sentinel.GetGeometryFromGeoJSON(forest, plot)
+	if err != nil {
+		PrintError(fmt.Sprintf("Error retrieving geometry from GeoJSON: %s", err.Error()))
+		return
+	}
+	provider, err := sentinel.NewImageProvider()
+	if err != nil {
+		PrintError(fmt.Sprintf("Error creating image provider: %s", err.Error()))
+		return
+	}
+	images, err := sentinel.GetImages(provider, geometry, forest, plot, startDate, endDate, 1)
+	if err != nil {
+		PrintError(fmt.Sprintf("Error retrieving images: %s", err.Error()))
+		return
+	}
+	data, err := dataset.CreatePixelDataset(forest, plot, images)
+	if err != nil {
+		PrintError(fmt.Sprintf("Error creating pixel dataset: %s", err.Error()))
+		return
+	}
+
+	parity, err := dataset.CompareSmoothers(data)
+	if err != nil {
+		PrintError(err.Error())
+		return
+	}
+
+	names := make([]string, 0, len(parity.MaxDiff))
+	for name := range parity.MaxDiff {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Printf("\nCompared %d pixels\n", parity.Pixels)
+	if parity.Failed > 0 || parity.Disagreements > 0 {
+		fmt.Printf("%d pixels failed with both smoothers, %d with only one\n", parity.Failed, parity.Disagreements)
+	}
+	fmt.Printf("%-16s %14s %14s\n", "Index", "Max diff", "Mean diff")
+	for _, name := range names {
+		fmt.Printf("%-16s %14.3e %14.3e\n", name, parity.MaxDiff[name], parity.MeanDiff[name])
+	}
+	if parity.Matches() {
+		PrintSuccess(fmt.Sprintf("The Go smoother matches the Python service within %g", smoothing.ParityTolerance))
+	} else {
+		PrintError(fmt.Sprintf("The Go smoother differs from the Python service by more than %g", smoothing.ParityTolerance))
+	}
+}
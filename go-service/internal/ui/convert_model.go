@@ -0,0 +1,62 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/forest-guardian/forest-guardian-api-poc/internal/dataset"
+	"github.com/forest-guardian/forest-guardian-api-poc/internal/properties"
+)
+
+// ConvertModel handles the UI for converting model datasets between CSV and Parquet. The
+// source file is kept.
+func ConvertModel() {
+	fmt.Printf("%s1. Convert a model between CSV and Parquet\n2. Convert every CSV model to Parquet%s\n", ColorBlue, ColorReset)
+	action, err := ReadInt("Enter your choice: ", 1, 2)
+	if err != nil {
+		PrintError(err.Error())
+		return
+	}
+
+	modelFolderPath := fmt.Sprintf("%s/data/model", properties.RootPath())
+	var models []string
+	switch action {
+	case 1:
+		model, err := SelectModel()
+		if err != nil {
+			PrintError(err.Error())
+			return
+		}
+		models = append(models, model)
+	case 2:
+		files, err := os.ReadDir(modelFolderPath)
+		if err != nil {
+			PrintError(fmt.Sprintf("error reading model folder: %v", err))
+			return
+		}
+		for _, file := range files {
+			if !file.IsDir() && filepath.Ext(file.Name()) == dataset.CSVExtension {
+				models = append(models, file.Name())
+			}
+		}
+		if len(models) == 0 {
+			PrintWarning("No CSV models found in the model folder")
+			return
+		}
+	}
+
+	for _, model := range models {
+		extension := dataset.ParquetExtension
+		if dataset.IsParquet(model) {
+			extension = dataset.CSVExtension
+		}
+		converted := dataset.TrimFinalDataExtension(model) + extension
+		rows, err := dataset.ConvertFinalDataFile(filepath.Join(modelFolderPath, model), filepath.Join(modelFolderPath, converted))
+		if err != nil {
+			PrintError(fmt.Sprintf("Error converting %s: %v", model, err))
+			continue
+		}
+		PrintSuccess(fmt.Sprintf("Converted %s to %s (%d rows)", model, converted, rows))
+	}
+}
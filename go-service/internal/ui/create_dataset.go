@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/forest-guardian/forest-guardian-api-poc/internal/dataset"
 	"github.com/forest-guardian/forest-guardian-api-poc/internal/delivery"
 	"github.com/forest-guardian/forest-guardian-api-poc/internal/notification"
 	"github.com/forest-guardian/forest-guardian-api-poc/internal/properties"
@@ -100,7 +101,15 @@ func CreateDataset() {
 	var daysBeforeEvidenceToAnalyze int
 	fmt.Scanln(&daysBeforeEvidenceToAnalyze)
 
-	outputDataFileName := fmt.Sprintf("%s_%s_%d_%d_%d.csv", strings.TrimSuffix(inputDataFileName, ".csv"), time.Now().Format("2006-01-02"), deltaDays, deltaDaysThreshold, daysBeforeEvidenceToAnalyze)
+	fmt.Print("\033[34mEnter the output format, csv or parquet (default csv): \033[0m")
+	var outputFormat string
+	fmt.Scanln(&outputFormat)
+	outputExtension := dataset.CSVExtension
+	if strings.EqualFold(outputFormat, "parquet") {
+		outputExtension = dataset.ParquetExtension
+	}
+
+	outputDataFileName := fmt.Sprintf("%s_%s_%d_%d_%d%s", strings.TrimSuffix(inputDataFileName, ".csv"), time.Now().Format("2006-01-02"), deltaDays, deltaDaysThreshold, daysBeforeEvidenceToAnalyze, outputExtension)
 	err = delivery.CreateDataset(inputDataFileName, outputDataFileName, deltaDays, deltaDaysThreshold, daysBeforeEvidenceToAnalyze)
 	if err != nil {
 		fmt.Printf("\n\033[31mError creating dataset: %s\033[0m\n", err.Error())
@@ -0,0 +1,121 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/forest-guardian/forest-guardian-api-poc/internal/sentinel"
+)
+
+// ImageManifest handles the UI for querying and purging a plot's image manifest
+func ImageManifest() {
+	forest, plot, err := ReadForestAndPlot()
+	if err != nil {
+		PrintError(err.Error())
+		return
+	}
+
+	manifest, err := sentinel.LoadManifest(forest, plot)
+	if err != nil {
+		PrintError(err.Error())
+		return
+	}
+
+	fmt.Printf("%s1. Query entries\n2. Purge entries%s\n", ColorBlue, ColorReset)
+	action, err := ReadInt("Enter your choice: ", 1, 2)
+	if err != nil {
+		PrintError(err.Error())
+		return
+	}
+
+	filter, err := readManifestFilter()
+	if err != nil {
+		PrintError(err.Error())
+		return
+	}
+
+	if action == 1 {
+		printManifestEntries(manifest, filter)
+		return
+	}
+
+	deleteFiles := strings.EqualFold(ReadString("Delete the image files too? (y/N): "), "y")
+	purged, err := manifest.Purge(filter, deleteFiles)
+	if err != nil {
+		PrintError(err.Error())
+	}
+	if err := manifest.Save(); err != nil {
+		PrintError(err.Error())
+		return
+	}
+	PrintSuccess(fmt.Sprintf("Purged %d entries from the manifest of %s_%s", purged, forest, plot))
+}
+
+func readManifestFilter() (func(sentinel.ManifestEntry) bool, error) {
+	fmt.Printf("%s1. All entries\n2. Accepted entries\n3. Rejected entries\n4. Rejected entries due for retry\n5. Entries in a date range%s\n", ColorBlue, ColorReset)
+	choice, err := ReadInt("Select the entries: ", 1, 5)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	switch choice {
+	case 2:
+		return func(entry sentinel.ManifestEntry) bool { return entry.Status == sentinel.ManifestStatusAccepted }, nil
+	case 3:
+		return func(entry sentinel.ManifestEntry) bool { return entry.Status == sentinel.ManifestStatusRejected }, nil
+	case 4:
+		return func(entry sentinel.ManifestEntry) bool {
+			return entry.Status == sentinel.ManifestStatusRejected && entry.Retryable(now)
+		}, nil
+	case 5:
+		startDate, err := ReadDate("Enter the start date (YYYY-MM-DD): ")
+		if err != nil {
+			return nil, err
+		}
+		endDate, err := ReadDate("Enter the end date (YYYY-MM-DD): ")
+		if err != nil {
+			return nil, err
+		}
+		start, end := startDate.Format("2006-01-02"), endDate.Format("2006-01-02")
+		return func(entry sentinel.ManifestEntry) bool { return entry.Date >= start && entry.Date <= end }, nil
+	default:
+		return func(sentinel.ManifestEntry) bool { return true }, nil
+	}
+}
+
+func printManifestEntries(manifest *sentinel.Manifest, filter func(sentinel.ManifestEntry) bool) {
+	count := 0
+	fmt.Printf("\n%s%-12s %-10s %-9s %8s %8s %8s %-9s  %-30s %s%s\n", ColorGreen, "Date", "Sensor", "Status", "Valid", "Unknown", "Invalid", "Baseline", "Reason", "Retry after", ColorReset)
+	for _, entry := range manifest.Entries() {
+		if !filter(entry) {
+			continue
+		}
+		count++
+		valid, unknown, invalid := "-", "-", "-"
+		if entry.PixelRatio != nil {
+			valid = fmt.Sprintf("%.1f%%", entry.PixelRatio.Valid*100)
+			unknown = fmt.Sprintf("%.1f%%", entry.PixelRatio.Unknown*100)
+			invalid = fmt.Sprintf("%.1f%%", entry.PixelRatio.Invalid*100)
+		}
+		retryAfter := "-"
+		if entry.RetryAfter != nil {
+			retryAfter = entry.RetryAfter.Format("2006-01-02")
+		}
+		// Inferred baselines are marked with a question mark
+		baseline := "-"
+		if entry.Harmonization != nil {
+			baseline = entry.Harmonization.ProcessingBaseline
+			if entry.Harmonization.Inferred {
+				baseline += "?"
+			}
+		}
+		reason := entry.RejectReason
+		if reason == "" {
+			reason = "-"
+		}
+		fmt.Printf("%-12s %-10s %-9s %8s %8s %8s %-9s  %-30s %s\n", entry.Date, entry.Sensor, entry.Status, valid, unknown, invalid, baseline, reason, retryAfter)
+	}
+	PrintSuccess(fmt.Sprintf("%d entries", count))
+}
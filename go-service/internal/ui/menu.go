@@ -22,6 +22,10 @@ func ShowMenu() {
 		{"View the list of available forest plots", func() { ListPlots("") }},
 		{"Analyze forest plot image deforestation spread over time", AnalyzeSpread},
 		{"Plot pixel values over time", PlotPixels},
+		{"Query or purge the image manifest of a forest plot", ImageManifest},
+		{"Compare the Go and Python smoothers on a forest plot", CompareSmoothers},
+		{"List, inspect or garbage-collect the stored pipeline stages", StageStore},
+		{"Convert model datasets between CSV and Parquet", ConvertModel},
 		{"Exit the application", func() { fmt.Println("Exiting..."); os.Exit(0) }},
 	}
 
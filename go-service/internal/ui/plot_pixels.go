@@ -54,7 +54,13 @@ func PlotPixels() {
 		return
 	}
 
-	images, err := sentinel.GetImages(geometry, forest, plot, startDate, endDate, 1)
+	provider, err := sentinel.NewImageProvider()
+	if err != nil {
+		fmt.Printf("\n\033[31mError creating image provider: %s\033[0m\n", err.Error())
+		return
+	}
+
+	images, err := sentinel.GetImages(provider, geometry, forest, plot, startDate, endDate, 1)
 	if err != nil {
 		fmt.Printf("\n\033[31mError retrieving images: %s\033[0m\n", err.Error())
 		return
@@ -76,8 +82,8 @@ func PlotPixels() {
 	ndre := make(map[string]float64)
 	ndmi := make(map[string]float64)
 
-	for _, pixelData := range cleanData {
-		for date, pixel := range pixelData {
+	for i, date := range cleanData.Dates {
+		for _, pixel := range cleanData.ValidPixels(i) {
 			ndvi[date.Format("2006-01-02")] = pixel.NDVI
 			ndre[date.Format("2006-01-02")] = pixel.NDRE
 			ndmi[date.Format("2006-01-02")] = pixel.NDMI
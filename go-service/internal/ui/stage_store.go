@@ -0,0 +1,88 @@
+package ui
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/forest-guardian/forest-guardian-api-poc/internal/dataset"
+)
+
+// StageStore handles the UI for listing, inspecting and garbage-collecting the stored
+// pipeline stages
+func StageStore() {
+	fmt.Printf("%s1. List stages\n2. Inspect a stage\n3. Garbage-collect stages%s\n", ColorBlue, ColorReset)
+	action, err := ReadInt("Enter your choice: ", 1, 3)
+	if err != nil {
+		PrintError(err.Error())
+		return
+	}
+
+	switch action {
+	case 1:
+		entries, err := dataset.ListStages()
+		if err != nil {
+			PrintError(err.Error())
+			return
+		}
+		printStages(entries)
+	case 2:
+		entry, err := dataset.FindStage(strings.TrimSpace(ReadString("Enter the stage key or its first characters: ")))
+		if err != nil {
+			PrintError(err.Error())
+			return
+		}
+		printStage(entry)
+	case 3:
+		days, err := ReadPositiveInt("Remove the stages unused for more than how many days? ")
+		if err != nil {
+			PrintError(err.Error())
+			return
+		}
+		collection, err := dataset.CollectStages(time.Duration(days) * 24 * time.Hour)
+		if err != nil {
+			PrintError(err.Error())
+			return
+		}
+		PrintSuccess(fmt.Sprintf("Removed %d stages, freeing %.1f MB", collection.Removed, float64(collection.Freed)/(1<<20)))
+	}
+}
+
+func printStages(entries []dataset.StageEntry) {
+	var total int64
+	fmt.Printf("\n%s%-6s %-12s %-20s %-12s %-12s %10s  %s%s\n", ColorGreen, "Stage", "Key", "Forest_Plot", "Parent", "Last used", "Size", "Summary", ColorReset)
+	for _, entry := range entries {
+		parent := "-"
+		if entry.Parent != "" {
+			parent = shortKey(entry.Parent)
+		}
+		fmt.Printf("%-6s %-12s %-20s %-12s %-12s %9.1fM  %s\n", entry.Stage, shortKey(entry.Key), entry.Forest+"_"+entry.Plot, parent, entry.LastUsedAt.Format("2006-01-02"), float64(entry.Size)/(1<<20), entry.Summary)
+		total += entry.Size
+	}
+	PrintSuccess(fmt.Sprintf("%d stages, %.1f MB", len(entries), float64(total)/(1<<20)))
+}
+
+func shortKey(key string) string {
+	return key[:min(len(key), 12)]
+}
+
+func printStage(entry dataset.StageEntry) {
+	fmt.Printf("\n%sKey:%s %s\n", ColorGreen, ColorReset, entry.Key)
+	fmt.Printf("%sStage:%s %s\n", ColorGreen, ColorReset, entry.Stage)
+	fmt.Printf("%sForest/Plot:%s %s/%s\n", ColorGreen, ColorReset, entry.Forest, entry.Plot)
+	if entry.Parent != "" {
+		fmt.Printf("%sParent:%s %s\n", ColorGreen, ColorReset, entry.Parent)
+	}
+	fmt.Printf("%sOutput:%s %s (%s, %d bytes)\n", ColorGreen, ColorReset, entry.File, entry.Summary, entry.Size)
+	fmt.Printf("%sCreated:%s %s\n", ColorGreen, ColorReset, entry.CreatedAt.Format(time.RFC3339))
+	fmt.Printf("%sLast used:%s %s\n", ColorGreen, ColorReset, entry.LastUsedAt.Format(time.RFC3339))
+
+	var parameters bytes.Buffer
+	if err := json.Indent(&parameters, entry.Parameters, "", "  "); err != nil {
+		parameters.Reset()
+		parameters.Write(entry.Parameters)
+	}
+	fmt.Printf("%sParameters:%s\n%s\n", ColorGreen, ColorReset, parameters.String())
+}
@@ -5,10 +5,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/forest-guardian/forest-guardian-api-poc/internal/dataset"
 	"github.com/forest-guardian/forest-guardian-api-poc/internal/properties"
 )
 
@@ -99,11 +101,9 @@ func ReadPositiveInt(prompt string) (int, error) {
 
 // SelectModel displays available models and returns the selected one
 func SelectModel() (string, error) {
-	modelFolderPath := fmt.Sprintf("%s/data/model/", properties.RootPath())
-
-	modelFiles, err := os.ReadDir(modelFolderPath)
+	modelFiles, err := listModels()
 	if err != nil {
-		return "", fmt.Errorf("error reading model folder: %s", err.Error())
+		return "", err
 	}
 
 	if len(modelFiles) == 0 {
@@ -112,7 +112,7 @@ func SelectModel() (string, error) {
 
 	fmt.Printf("%s\nAvailable models:%s\n", ColorGreen, ColorReset)
 	for i, file := range modelFiles {
-		fmt.Printf("%s%d. %s%s\n", ColorGreen, i+1, file.Name(), ColorReset)
+		fmt.Printf("%s%d. %s%s\n", ColorGreen, i+1, file, ColorReset)
 	}
 
 	choice, err := ReadInt("Enter the number of the model you want to use: ", 1, len(modelFiles))
@@ -120,12 +120,31 @@ func SelectModel() (string, error) {
 		return "", err
 	}
 
-	selectedModel := modelFiles[choice-1].Name()
+	selectedModel := modelFiles[choice-1]
 	fmt.Printf("%sYou selected the model: %s%s\n", ColorGreen, selectedModel, ColorReset)
 
 	return selectedModel, nil
 }
 
+// listModels returns the CSV and Parquet model datasets of the model folder, leaving out their
+// lineage sidecars.
+func listModels() ([]string, error) {
+	modelFolderPath := fmt.Sprintf("%s/data/model/", properties.RootPath())
+
+	files, err := os.ReadDir(modelFolderPath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading model folder: %s", err.Error())
+	}
+
+	var models []string
+	for _, file := range files {
+		if !file.IsDir() && (filepath.Ext(file.Name()) == dataset.CSVExtension || dataset.IsParquet(file.Name())) {
+			models = append(models, file.Name())
+		}
+	}
+	return models, nil
+}
+
 // ReadForestAndPlot reads forest and plot information
 func ReadForestAndPlot() (string, string, error) {
 	PrintInfo("Available forests: ")
@@ -55,6 +55,10 @@ func calculateMeanHumidity(hourlyData HourlyData) map[string]float64 {
 	return meanHumidity
 }
 
+// Source is the API FetchWeather requests historical weather from, as recorded in the lineage of
+// the datasets built with it.
+const Source = "https://archive-api.open-meteo.com/v1/archive"
+
 func FetchWeather(latitude, longitude float64, startDate, endDate time.Time, retries int) (HistoricalWeather, error) {
 	weatherCache := cache.NewFileCache[HistoricalWeather]("weather")
 	cacheKey := weatherCache.GenerateKey(latitude, longitude, startDate.Format("2006-01-02"), endDate.Format("2006-01-02"))
@@ -69,7 +73,7 @@ func FetchWeather(latitude, longitude float64, startDate, endDate time.Time, ret
 	fmt.Printf("Weather cache MISS for key: %s (lat: %.6f, lon: %.6f, %s to %s)\n", 
 		cacheKey, latitude, longitude, startDate.Format("2006-01-02"), endDate.Format("2006-01-02"))
 
-	url := "https://archive-api.open-meteo.com/v1/archive"
+	url := Source
 	params := fmt.Sprintf("?latitude=%f&longitude=%f&start_date=%s&end_date=%s&daily=temperature_2m_mean,precipitation_sum&hourly=relative_humidity_2m",
 		latitude, longitude, startDate.Format("2006-01-02"), endDate.Format("2006-01-02"))
 
@@ -31,7 +31,10 @@ func CreateFinalDataGeoJson(result []ml.PixelResult, outputGeojsonPath string) {
 				"coordinates": []float64{pixel.Longitude, pixel.Latitude},
 			},
 			"properties": map[string]interface{}{
-				"results": results,
+				"results":    results,
+				"coverage":   pixel.Coverage,
+				"provenance": pixel.Provenance,
+				"gap_days":   pixel.GapDays,
 			},
 		}
 		features = append(features, feature)
@@ -42,6 +42,10 @@ def detect_outliers(data, window_size=10, threshold=0.02):
 
     return cleaned_data
 
-def clear_and_smooth(data):
-    smoothed_data = gam_smoothing(detect_outliers(data), 0.1)
+DEFAULT_LAMBDA = 0.1
+DEFAULT_WINDOW = 10
+DEFAULT_THRESHOLD = 0.02
+
+def clear_and_smooth(data, lam=DEFAULT_LAMBDA, window_size=DEFAULT_WINDOW, threshold=DEFAULT_THRESHOLD):
+    smoothed_data = gam_smoothing(detect_outliers(data, window_size, threshold), lam)
     return smoothed_data
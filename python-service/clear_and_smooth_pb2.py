@@ -14,28 +14,46 @@ _sym_db = _symbol_database.Default()
 
 
 
-DESCRIPTOR = _descriptor_pool.Default().AddSerializedFile(b'\n\x16\x63lear_and_smooth.proto\"\x81\x01\n\x15\x43learAndSmoothRequest\x12.\n\x04\x64\x61ta\x18\x01 \x03(\x0b\x32 .ClearAndSmoothRequest.DataEntry\x1a\x38\n\tDataEntry\x12\x0b\n\x03key\x18\x01 \x01(\t\x12\x1a\n\x05value\x18\x02 \x01(\x0b\x32\x0b.DoubleList:\x02\x38\x01\"\x9c\x01\n\x16\x43learAndSmoothResponse\x12@\n\rsmoothed_data\x18\x01 \x03(\x0b\x32).ClearAndSmoothResponse.SmoothedDataEntry\x1a@\n\x11SmoothedDataEntry\x12\x0b\n\x03key\x18\x01 \x01(\t\x12\x1a\n\x05value\x18\x02 \x01(\x0b\x32\x0b.DoubleList:\x02\x38\x01\"\x1c\n\nDoubleList\x12\x0e\n\x06values\x18\x01 \x03(\x01\x32Z\n\x15\x43learAndSmoothService\x12\x41\n\x0e\x43learAndSmooth\x12\x16.ClearAndSmoothRequest\x1a\x17.ClearAndSmoothResponseB\x0cZ\n/protobufsb\x06proto3')
+DESCRIPTOR = _descriptor_pool.Default().AddSerializedFile(b'\n\x16\x63lear_and_smooth.proto\"\x8e\x01\n\x15\x43learAndSmoothRequest\x12.\n\x04\x64\x61ta\x18\x01 \x03(\x0b\x32 .ClearAndSmoothRequest.DataEntry\x12\x0b\n\x03lam\x18\x02 \x01(\x01\x1a\x38\n\tDataEntry\x12\x0b\n\x03key\x18\x01 \x01(\t\x12\x1a\n\x05value\x18\x02 \x01(\x0b\x32\x0b.DoubleList:\x02\x38\x01\"\x9c\x01\n\x16\x43learAndSmoothResponse\x12@\n\rsmoothed_data\x18\x01 \x03(\x0b\x32).ClearAndSmoothResponse.SmoothedDataEntry\x1a@\n\x11SmoothedDataEntry\x12\x0b\n\x03key\x18\x01 \x01(\t\x12\x1a\n\x05value\x18\x02 \x01(\x0b\x32\x0b.DoubleList:\x02\x38\x01\"\x1c\n\nDoubleList\x12\x0e\n\x06values\x18\x01 \x03(\x01\"E\n\x13SmoothingParameters\x12\x0b\n\x03lam\x18\x01 \x01(\x01\x12\x0e\n\x06window\x18\x02 \x01(\x05\x12\x11\n\tthreshold\x18\x03 \x01(\x01\"\x83\x01\n\x0bPixelSeries\x12\t\n\x01x\x18\x01 \x01(\x05\x12\t\n\x01y\x18\x02 \x01(\x05\x12$\n\x04\x64\x61ta\x18\x03 \x03(\x0b\x32\x16.PixelSeries.DataEntry\x1a\x38\n\tDataEntry\x12\x0b\n\x03key\x18\x01 \x01(\t\x12\x1a\n\x05value\x18\x02 \x01(\x0b\x32\x0b.DoubleList:\x02\x38\x01\"d\n\x1a\x43learAndSmoothBatchRequest\x12(\n\nparameters\x18\x01 \x01(\x0b\x32\x14.SmoothingParameters\x12\x1c\n\x06pixels\x18\x02 \x03(\x0b\x32\x0c.PixelSeries\"\xaf\x01\n\rSmoothedPixel\x12\t\n\x01x\x18\x01 \x01(\x05\x12\t\n\x01y\x18\x02 \x01(\x05\x12\x37\n\rsmoothed_data\x18\x03 \x03(\x0b\x32 .SmoothedPixel.SmoothedDataEntry\x12\r\n\x05\x65rror\x18\x04 \x01(\t\x1a@\n\x11SmoothedDataEntry\x12\x0b\n\x03key\x18\x01 \x01(\t\x12\x1a\n\x05value\x18\x02 \x01(\x0b\x32\x0b.DoubleList:\x02\x38\x01\"=\n\x1b\x43learAndSmoothBatchResponse\x12\x1e\n\x06pixels\x18\x01 \x03(\x0b\x32\x0e.SmoothedPixel2\xb0\x01\n\x15\x43learAndSmoothService\x12\x41\n\x0e\x43learAndSmooth\x12\x16.ClearAndSmoothRequest\x1a\x17.ClearAndSmoothResponse\x12T\n\x13\x43learAndSmoothBatch\x12\x1b.ClearAndSmoothBatchRequest\x1a\x1c.ClearAndSmoothBatchResponse(\x01\x30\x01\x42MZKgithub.com/forest-guardian/forest-guardian-api-poc/internal/delta/protobufsb\x06proto3')
 
 _globals = globals()
 _builder.BuildMessageAndEnumDescriptors(DESCRIPTOR, _globals)
 _builder.BuildTopDescriptorsAndMessages(DESCRIPTOR, 'clear_and_smooth_pb2', _globals)
 if _descriptor._USE_C_DESCRIPTORS == False:
   _globals['DESCRIPTOR']._options = None
-  _globals['DESCRIPTOR']._serialized_options = b'Z\n/protobufs'
+  _globals['DESCRIPTOR']._serialized_options = b'ZKgithub.com/forest-guardian/forest-guardian-api-poc/internal/delta/protobufs'
   _globals['_CLEARANDSMOOTHREQUEST_DATAENTRY']._options = None
   _globals['_CLEARANDSMOOTHREQUEST_DATAENTRY']._serialized_options = b'8\001'
   _globals['_CLEARANDSMOOTHRESPONSE_SMOOTHEDDATAENTRY']._options = None
   _globals['_CLEARANDSMOOTHRESPONSE_SMOOTHEDDATAENTRY']._serialized_options = b'8\001'
+  _globals['_PIXELSERIES_DATAENTRY']._options = None
+  _globals['_PIXELSERIES_DATAENTRY']._serialized_options = b'8\001'
+  _globals['_SMOOTHEDPIXEL_SMOOTHEDDATAENTRY']._options = None
+  _globals['_SMOOTHEDPIXEL_SMOOTHEDDATAENTRY']._serialized_options = b'8\001'
   _globals['_CLEARANDSMOOTHREQUEST']._serialized_start=27
-  _globals['_CLEARANDSMOOTHREQUEST']._serialized_end=156
-  _globals['_CLEARANDSMOOTHREQUEST_DATAENTRY']._serialized_start=100
-  _globals['_CLEARANDSMOOTHREQUEST_DATAENTRY']._serialized_end=156
-  _globals['_CLEARANDSMOOTHRESPONSE']._serialized_start=159
-  _globals['_CLEARANDSMOOTHRESPONSE']._serialized_end=315
-  _globals['_CLEARANDSMOOTHRESPONSE_SMOOTHEDDATAENTRY']._serialized_start=251
-  _globals['_CLEARANDSMOOTHRESPONSE_SMOOTHEDDATAENTRY']._serialized_end=315
-  _globals['_DOUBLELIST']._serialized_start=317
-  _globals['_DOUBLELIST']._serialized_end=345
-  _globals['_CLEARANDSMOOTHSERVICE']._serialized_start=347
-  _globals['_CLEARANDSMOOTHSERVICE']._serialized_end=437
+  _globals['_CLEARANDSMOOTHREQUEST']._serialized_end=169
+  _globals['_CLEARANDSMOOTHREQUEST_DATAENTRY']._serialized_start=113
+  _globals['_CLEARANDSMOOTHREQUEST_DATAENTRY']._serialized_end=169
+  _globals['_CLEARANDSMOOTHRESPONSE']._serialized_start=172
+  _globals['_CLEARANDSMOOTHRESPONSE']._serialized_end=328
+  _globals['_CLEARANDSMOOTHRESPONSE_SMOOTHEDDATAENTRY']._serialized_start=264
+  _globals['_CLEARANDSMOOTHRESPONSE_SMOOTHEDDATAENTRY']._serialized_end=328
+  _globals['_DOUBLELIST']._serialized_start=330
+  _globals['_DOUBLELIST']._serialized_end=358
+  _globals['_SMOOTHINGPARAMETERS']._serialized_start=360
+  _globals['_SMOOTHINGPARAMETERS']._serialized_end=429
+  _globals['_PIXELSERIES']._serialized_start=432
+  _globals['_PIXELSERIES']._serialized_end=563
+  _globals['_PIXELSERIES_DATAENTRY']._serialized_start=507
+  _globals['_PIXELSERIES_DATAENTRY']._serialized_end=563
+  _globals['_CLEARANDSMOOTHBATCHREQUEST']._serialized_start=565
+  _globals['_CLEARANDSMOOTHBATCHREQUEST']._serialized_end=665
+  _globals['_SMOOTHEDPIXEL']._serialized_start=668
+  _globals['_SMOOTHEDPIXEL']._serialized_end=843
+  _globals['_SMOOTHEDPIXEL_SMOOTHEDDATAENTRY']._serialized_start=779
+  _globals['_SMOOTHEDPIXEL_SMOOTHEDDATAENTRY']._serialized_end=843
+  _globals['_CLEARANDSMOOTHBATCHRESPONSE']._serialized_start=845
+  _globals['_CLEARANDSMOOTHBATCHRESPONSE']._serialized_end=906
+  _globals['_CLEARANDSMOOTHSERVICE']._serialized_start=909
+  _globals['_CLEARANDSMOOTHSERVICE']._serialized_end=1085
 # @@protoc_insertion_point(module_scope)
@@ -19,6 +19,11 @@ class ClearAndSmoothServiceStub(object):
                 request_serializer=clear__and__smooth__pb2.ClearAndSmoothRequest.SerializeToString,
                 response_deserializer=clear__and__smooth__pb2.ClearAndSmoothResponse.FromString,
                 )
+        self.ClearAndSmoothBatch = channel.stream_stream(
+                '/ClearAndSmoothService/ClearAndSmoothBatch',
+                request_serializer=clear__and__smooth__pb2.ClearAndSmoothBatchRequest.SerializeToString,
+                response_deserializer=clear__and__smooth__pb2.ClearAndSmoothBatchResponse.FromString,
+                )
 
 
 class ClearAndSmoothServiceServicer(object):
@@ -30,6 +35,14 @@ class ClearAndSmoothServiceServicer(object):
         context.set_details('Method not implemented!')
         raise NotImplementedError('Method not implemented!')
 
+    def ClearAndSmoothBatch(self, request_iterator, context):
+        """Smooths the pixels of a plot streamed in batches; every request batch is answered by one
+        response batch, and a pixel that fails carries its error instead of failing the stream
+        """
+        context.set_code(grpc.StatusCode.UNIMPLEMENTED)
+        context.set_details('Method not implemented!')
+        raise NotImplementedError('Method not implemented!')
+
 
 def add_ClearAndSmoothServiceServicer_to_server(servicer, server):
     rpc_method_handlers = {
@@ -38,6 +51,11 @@ def add_ClearAndSmoothServiceServicer_to_server(servicer, server):
                     request_deserializer=clear__and__smooth__pb2.ClearAndSmoothRequest.FromString,
                     response_serializer=clear__and__smooth__pb2.ClearAndSmoothResponse.SerializeToString,
             ),
+            'ClearAndSmoothBatch': grpc.stream_stream_rpc_method_handler(
+                    servicer.ClearAndSmoothBatch,
+                    request_deserializer=clear__and__smooth__pb2.ClearAndSmoothBatchRequest.FromString,
+                    response_serializer=clear__and__smooth__pb2.ClearAndSmoothBatchResponse.SerializeToString,
+            ),
     }
     generic_handler = grpc.method_handlers_generic_handler(
             'ClearAndSmoothService', rpc_method_handlers)
@@ -64,3 +82,20 @@ class ClearAndSmoothService(object):
             clear__and__smooth__pb2.ClearAndSmoothResponse.FromString,
             options, channel_credentials,
             insecure, call_credentials, compression, wait_for_ready, timeout, metadata)
+
+    @staticmethod
+    def ClearAndSmoothBatch(request_iterator,
+            target,
+            options=(),
+            channel_credentials=None,
+            call_credentials=None,
+            insecure=False,
+            compression=None,
+            wait_for_ready=None,
+            timeout=None,
+            metadata=None):
+        return grpc.experimental.stream_stream(request_iterator, target, '/ClearAndSmoothService/ClearAndSmoothBatch',
+            clear__and__smooth__pb2.ClearAndSmoothBatchRequest.SerializeToString,
+            clear__and__smooth__pb2.ClearAndSmoothBatchResponse.FromString,
+            options, channel_credentials,
+            insecure, call_credentials, compression, wait_for_ready, timeout, metadata)
@@ -0,0 +1,63 @@
+"""Writes the expected outputs of clear_and_smooth for fixed series, which the Go smoother is
+tested against in go-service/internal/smoothing/testdata/pygam_golden.json. Run it from the
+python-service folder again after changing the smoothing of either service."""
+import json
+import math
+import os
+
+from clear_and_smooth import DEFAULT_LAMBDA, DEFAULT_THRESHOLD, DEFAULT_WINDOW, clear_and_smooth
+
+OUTPUT = os.path.join('..', 'go-service', 'internal', 'smoothing', 'testdata', 'pygam_golden.json')
+
+
+def noise(n, seed):
+    """Deterministic noise in [-0.5, 0.5) from a linear congruential generator."""
+    values = []
+    state = seed
+    for _ in range(n):
+        state = (1103515245 * state + 12345) % 2**31
+        values.append(state / 2**31 - 0.5)
+    return values
+
+
+def seasonal(n, seed, period=23, amplitude=0.25, level=0.55, scale=0.04):
+    return [level + amplitude * math.sin(2 * math.pi * i / period) + scale * e for i, e in enumerate(noise(n, seed))]
+
+
+def cases():
+    ndvi = seasonal(73, 1)
+    # Cloud residues pull single dates down
+    clouded = list(ndvi)
+    for i in (9, 30, 31, 58):
+        clouded[i] -= 0.4
+    # An abrupt loss of vegetation halfway through the series
+    disturbance = seasonal(46, 2)
+    for i in range(23, 46):
+        disturbance[i] -= 0.3
+    return {
+        'seasonal_ndvi': ndvi,
+        'clouded_ndvi': clouded,
+        'disturbance_ndmi': disturbance,
+        'short_psri': seasonal(5, 3, amplitude=0.05, level=0.1),
+        'twelve_ndre': seasonal(12, 4, period=12, amplitude=0.1, level=0.3),
+        'negative_ndmi': seasonal(30, 5, level=-0.2, amplitude=0.15),
+        'linear_ndvi': [0.2 + 0.01 * i for i in range(25)],
+    }
+
+
+def main():
+    golden = {
+        'parameters': {'lambda': DEFAULT_LAMBDA, 'window': DEFAULT_WINDOW, 'threshold': DEFAULT_THRESHOLD},
+        'cases': [
+            {'name': name, 'values': values, 'smoothed': clear_and_smooth(values)}
+            for name, values in cases().items()
+        ],
+    }
+    os.makedirs(os.path.dirname(OUTPUT), exist_ok=True)
+    with open(OUTPUT, 'w') as f:
+        json.dump(golden, f, indent=1)
+    print(f"Wrote {len(golden['cases'])} cases to {OUTPUT}")
+
+
+if __name__ == '__main__':
+    main()
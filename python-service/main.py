@@ -9,7 +9,7 @@ import grpc
 import pandas as pd
 import run_model_pb2
 import run_model_pb2_grpc
-from clear_and_smooth import clear_and_smooth
+from clear_and_smooth import clear_and_smooth, DEFAULT_LAMBDA, DEFAULT_WINDOW, DEFAULT_THRESHOLD
 from dotenv import load_dotenv
 from run_model import run_model
 from pest_clustering_server import serve_pest_clustering
@@ -24,9 +24,10 @@ class ClearAndSmoothService(clear_and_smooth_pb2_grpc.ClearAndSmoothServiceServi
     def ClearAndSmooth(self, request, context):
         try:
             smoothed_data = {}
+            lam = request.lam or DEFAULT_LAMBDA
             for key, double_list in request.data.items():
                 data = list(double_list.values)
-                smoothed_values = clear_and_smooth(data)
+                smoothed_values = clear_and_smooth(data, lam)
                 smoothed_data[key] = clear_and_smooth_pb2.DoubleList(values=smoothed_values)
             return clear_and_smooth_pb2.ClearAndSmoothResponse(smoothed_data=smoothed_data)
         except Exception as e:
@@ -34,11 +35,31 @@ class ClearAndSmoothService(clear_and_smooth_pb2_grpc.ClearAndSmoothServiceServi
             context.set_details(str(e))
             context.set_code(grpc.StatusCode.INTERNAL)
             return clear_and_smooth_pb2.ClearAndSmoothResponse()
+
+    def ClearAndSmoothBatch(self, request_iterator, context):
+        # Each pixel is smoothed on its own so that one failing series does not fail the plot
+        for request in request_iterator:
+            lam = request.parameters.lam or DEFAULT_LAMBDA
+            window = request.parameters.window or DEFAULT_WINDOW
+            threshold = request.parameters.threshold or DEFAULT_THRESHOLD
+            response = clear_and_smooth_pb2.ClearAndSmoothBatchResponse()
+            for pixel in request.pixels:
+                result = response.pixels.add(x=pixel.x, y=pixel.y)
+                try:
+                    for key, double_list in pixel.data.items():
+                        smoothed_values = clear_and_smooth(list(double_list.values), lam, window, threshold)
+                        result.smoothed_data[key].values.extend(smoothed_values)
+                except Exception as e:
+                    result.smoothed_data.clear()
+                    result.error = str(e) or type(e).__name__
+            yield response
         
 class RunModelServiceServicer(run_model_pb2_grpc.RunModelServiceServicer):
     def RunModel(self, request, context):
         try: 
             rows = []
+            extra_indexes = set()
+            features = set()
             for item in request.data:
                 weather = item.weather
                 delta = item.delta
@@ -81,15 +102,27 @@ class RunModelServiceServicer(run_model_pb2_grpc.RunModelServiceServicer):
                     "psri_derivative": delta.psri_derivative,
                     "ndvi_derivative": delta.ndvi_derivative,
                     "label": getattr(delta, "label", None),
+                    "coverage": delta.coverage,
+                    "provenance": delta.provenance,
+                    "gap_days": delta.gap_days,
                     "created_at": datetime.now().isoformat(),
                 }
+                # Enabled indices beyond the core four travel in the indexes maps
+                for name, value in delta.indexes.items():
+                    row[name] = value
+                    row[f"{name}_derivative"] = delta.index_derivatives[name]
+                    extra_indexes.add(name)
+                # Temporal features configured in the Go service, named columns such as ndvi_derivative_15d
+                for name, value in delta.features.items():
+                    row[name] = value
+                    features.add(name)
                 rows.append(row)
 
             # Create a DataFrame
             df = pd.DataFrame(rows)
             # print(df)
             print(f"Running model: {request.model}")
-            result = run_model(request.model,df)
+            result = run_model(request.model, df, extra_indexes=sorted(extra_indexes), features=sorted(features))
             response = run_model_pb2.RunModelResponse()
             for item in result:
                 pixel_result = run_model_pb2.PixelResult(
@@ -97,6 +130,9 @@ class RunModelServiceServicer(run_model_pb2_grpc.RunModelServiceServicer):
                     y=item['y'],
                     latitude=item['latitude'],
                     longitude=item['longitude'],
+                    coverage=item['coverage'],
+                    provenance=item['provenance'],
+                    gap_days=item['gap_days'],
                     result=[
                         run_model_pb2.LabelProbability(
                             label=label_prob['label'],
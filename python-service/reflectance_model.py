@@ -14,9 +14,10 @@ def retrieve_sample_probabilities(index, sample, cluster_probabilities, cluster_
     return sample_probability
     
 
-def reflectance_model(df, n_components=2, reg_covar=1e-6):
+def reflectance_model(df, n_components=2, reg_covar=1e-6, extra_columns=()):
     # Extract relevant columns
     columns = ['delta', 'ndre', 'ndmi', 'psri', 'ndvi', 'ndre_derivative', 'ndmi_derivative', 'psri_derivative', 'ndvi_derivative']
+    columns += list(extra_columns)
     data = df[columns]
 
     # Preprocess the data
@@ -47,6 +48,14 @@ def reflectance_model(df, n_components=2, reg_covar=1e-6):
         sample_probabilities['y'] = int(sample['y'])
         sample_probabilities['latitude'] = float(sample['latitude'])
         sample_probabilities['longitude'] = float(sample['longitude'])
+        # Inputs without a coverage column predate it, treat their pixels as interior
+        coverage = sample.get('coverage')
+        sample_probabilities['coverage'] = 1.0 if coverage is None or coverage != coverage else float(coverage)
+        # Clients that predate provenance send none, report their pixels as observed
+        provenance = sample.get('provenance')
+        sample_probabilities['provenance'] = provenance if isinstance(provenance, str) and provenance else 'observed'
+        gap_days = sample.get('gap_days')
+        sample_probabilities['gap_days'] = int(gap_days) if gap_days == gap_days and gap_days is not None else 0
         results.append(sample_probabilities) 
 
     return results
@@ -5,19 +5,41 @@ from climate_group_model import climate_group_model
 from reflectance_model import reflectance_model
 
 
-def run_model(model, input, climate_group_clusters=2, reflectance_clusters=16):
+def read_model_dataset(root, model):
+    """Reads a model dataset, as Parquet or as CSV by its extension. A name without extension
+    is read from its Parquet file when there is one, and from its CSV otherwise."""
+    path = f'{root}/data/model/{model}'
+    if not model.endswith(('.csv', '.parquet')):
+        path = f'{path}.parquet' if os.path.exists(f'{path}.parquet') else f'{path}.csv'
+    if path.endswith('.parquet'):
+        return pd.read_parquet(path)
+    return pd.read_csv(path)
+
+
+def run_model(model, input, climate_group_clusters=2, reflectance_clusters=16, extra_indexes=(), features=()):
     root = os.getenv('ROOT_PATH', '')
-    
-    # Ensure model has .csv extension
-    if not model.endswith('.csv'):
-        model = f"{model}.csv"
-    
-    dataset = pd.read_csv(f'{root}/data/model/{model}')
+
+    dataset = read_model_dataset(root, model)
+
     dataset_concat = pd.concat([dataset, input], ignore_index=True)
 
+    # Only use the extra indices the model dataset was built with and no row lacks, as rows
+    # appended before an index was enabled, or inputs without it, leave it missing and the
+    # mixture model cannot fit missing values
+    extra_columns = []
+    for name in extra_indexes:
+        columns = [name, f"{name}_derivative"]
+        if all(column in dataset.columns for column in columns) and not dataset_concat[columns].isna().any().any():
+            extra_columns += columns
+
+    # Temporal features are used when the model dataset has them and no row lacks them
+    for name in features:
+        if name in dataset.columns and not dataset_concat[name].isna().any():
+            extra_columns.append(name)
+
     # result = climate_group_model(dataset_concat, climate_group_clusters)
     # if len(result['label'].unique()) == 1:
     #     print("Only one cluster was found, skipping reflectance model")
     #     return 
-    result = reflectance_model(dataset_concat, reflectance_clusters)
+    result = reflectance_model(dataset_concat, reflectance_clusters, extra_columns=extra_columns)
     return result
@@ -14,7 +14,7 @@ _sym_db = _symbol_database.Default()
 
 
 
-DESCRIPTOR = _descriptor_pool.Default().AddSerializedFile(b'\n\x0frun_model.proto\"\x8a\x05\n\tFinalData\x12*\n\x07weather\x18\x01 \x01(\x0b\x32\x19.FinalData.WeatherMetrics\x12#\n\x05\x64\x65lta\x18\x02 \x01(\x0b\x32\x14.FinalData.DeltaData\x12\x12\n\ncreated_at\x18\x03 \x01(\t\x1a\xaa\x01\n\x0eWeatherMetrics\x12\x17\n\x0f\x61vg_temperature\x18\x01 \x01(\x01\x12\x14\n\x0ctemp_std_dev\x18\x02 \x01(\x01\x12\x14\n\x0c\x61vg_humidity\x18\x03 \x01(\x01\x12\x18\n\x10humidity_std_dev\x18\x04 \x01(\x01\x12\x1b\n\x13total_precipitation\x18\x05 \x01(\x01\x12\x1c\n\x14\x64ry_days_consecutive\x18\x06 \x01(\x05\x1a\xea\x02\n\tDeltaData\x12\x0e\n\x06\x66orest\x18\x01 \x01(\t\x12\x0c\n\x04plot\x18\x02 \x01(\t\x12\x11\n\tdelta_min\x18\x03 \x01(\x05\x12\x11\n\tdelta_max\x18\x04 \x01(\x05\x12\r\n\x05\x64\x65lta\x18\x05 \x01(\x05\x12\x12\n\nstart_date\x18\x06 \x01(\t\x12\x10\n\x08\x65nd_date\x18\x07 \x01(\t\x12\t\n\x01x\x18\x08 \x01(\x05\x12\t\n\x01y\x18\t \x01(\x05\x12\x0c\n\x04ndre\x18\n \x01(\x01\x12\x0c\n\x04ndmi\x18\x0b \x01(\x01\x12\x0c\n\x04psri\x18\x0c \x01(\x01\x12\x0c\n\x04ndvi\x18\r \x01(\x01\x12\x17\n\x0fndre_derivative\x18\x0e \x01(\x01\x12\x17\n\x0fndmi_derivative\x18\x0f \x01(\x01\x12\x17\n\x0fpsri_derivative\x18\x10 \x01(\x01\x12\x17\n\x0fndvi_derivative\x18\x11 \x01(\x01\x12\r\n\x05label\x18\x12 \x01(\t\x12\x10\n\x08latitude\x18\x13 \x01(\x01\x12\x11\n\tlongitude\x18\x14 \x01(\x01\"k\n\x0bPixelResult\x12\t\n\x01x\x18\x01 \x01(\x05\x12\t\n\x01y\x18\x02 \x01(\x05\x12\x10\n\x08latitude\x18\x03 \x01(\x01\x12\x11\n\tlongitude\x18\x04 \x01(\x01\x12!\n\x06result\x18\x05 \x03(\x0b\x32\x11.LabelProbability\"6\n\x10LabelProbability\x12\r\n\x05label\x18\x01 \x01(\t\x12\x13\n\x0bprobability\x18\x02 \x01(\x01\":\n\x0fRunModelRequest\x12\x18\n\x04\x64\x61ta\x18\x01 \x03(\x0b\x32\n.FinalData\x12\r\n\x05model\x18\x02 \x01(\t\"1\n\x10RunModelResponse\x12\x1d\n\x07results\x18\x01 \x03(\x0b\x32\x0c.PixelResult2B\n\x0fRunModelService\x12/\n\x08RunModel\x12\x10.RunModelRequest\x1a\x11.RunModelResponseB\x0cZ\n/protobufsb\x06proto3')
+DESCRIPTOR = _descriptor_pool.Default().AddSerializedFile(b'\n\x0frun_model.proto\"\x8d\x08\n\tFinalData\x12*\n\x07weather\x18\x01 \x01(\x0b\x32\x19.FinalData.WeatherMetrics\x12#\n\x05\x64\x65lta\x18\x02 \x01(\x0b\x32\x14.FinalData.DeltaData\x12\x12\n\ncreated_at\x18\x03 \x01(\t\x1a\xaa\x01\n\x0eWeatherMetrics\x12\x17\n\x0f\x61vg_temperature\x18\x01 \x01(\x01\x12\x14\n\x0ctemp_std_dev\x18\x02 \x01(\x01\x12\x14\n\x0c\x61vg_humidity\x18\x03 \x01(\x01\x12\x18\n\x10humidity_std_dev\x18\x04 \x01(\x01\x12\x1b\n\x13total_precipitation\x18\x05 \x01(\x01\x12\x1c\n\x14\x64ry_days_consecutive\x18\x06 \x01(\x05\x1a\xed\x05\n\tDeltaData\x12\x0e\n\x06\x66orest\x18\x01 \x01(\t\x12\x0c\n\x04plot\x18\x02 \x01(\t\x12\x11\n\tdelta_min\x18\x03 \x01(\x05\x12\x11\n\tdelta_max\x18\x04 \x01(\x05\x12\r\n\x05\x64\x65lta\x18\x05 \x01(\x05\x12\x12\n\nstart_date\x18\x06 \x01(\t\x12\x10\n\x08\x65nd_date\x18\x07 \x01(\t\x12\t\n\x01x\x18\x08 \x01(\x05\x12\t\n\x01y\x18\t \x01(\x05\x12\x0c\n\x04ndre\x18\n \x01(\x01\x12\x0c\n\x04ndmi\x18\x0b \x01(\x01\x12\x0c\n\x04psri\x18\x0c \x01(\x01\x12\x0c\n\x04ndvi\x18\r \x01(\x01\x12\x17\n\x0fndre_derivative\x18\x0e \x01(\x01\x12\x17\n\x0fndmi_derivative\x18\x0f \x01(\x01\x12\x17\n\x0fpsri_derivative\x18\x10 \x01(\x01\x12\x17\n\x0fndvi_derivative\x18\x11 \x01(\x01\x12\r\n\x05label\x18\x12 \x01(\t\x12\x10\n\x08latitude\x18\x13 \x01(\x01\x12\x11\n\tlongitude\x18\x14 \x01(\x01\x12\x32\n\x07indexes\x18\x15 \x03(\x0b\x32!.FinalData.DeltaData.IndexesEntry\x12\x45\n\x11index_derivatives\x18\x16 \x03(\x0b\x32*.FinalData.DeltaData.IndexDerivativesEntry\x12\x10\n\x08\x63overage\x18\x17 \x01(\x01\x12\x12\n\nprovenance\x18\x18 \x01(\t\x12\x10\n\x08gap_days\x18\x19 \x01(\x05\x12\x34\n\x08\x66\x65\x61tures\x18\x1a \x03(\x0b\x32\".FinalData.DeltaData.FeaturesEntry\x1a.\n\x0cIndexesEntry\x12\x0b\n\x03key\x18\x01 \x01(\t\x12\r\n\x05value\x18\x02 \x01(\x01:\x02\x38\x01\x1a\x37\n\x15IndexDerivativesEntry\x12\x0b\n\x03key\x18\x01 \x01(\t\x12\r\n\x05value\x18\x02 \x01(\x01:\x02\x38\x01\x1a/\n\rFeaturesEntry\x12\x0b\n\x03key\x18\x01 \x01(\t\x12\r\n\x05value\x18\x02 \x01(\x01:\x02\x38\x01\"\xa3\x01\n\x0bPixelResult\x12\t\n\x01x\x18\x01 \x01(\x05\x12\t\n\x01y\x18\x02 \x01(\x05\x12\x10\n\x08latitude\x18\x03 \x01(\x01\x12\x11\n\tlongitude\x18\x04 \x01(\x01\x12!\n\x06result\x18\x05 \x03(\x0b\x32\x11.LabelProbability\x12\x10\n\x08\x63overage\x18\x06 \x01(\x01\x12\x12\n\nprovenance\x18\x07 \x01(\t\x12\x10\n\x08gap_days\x18\x08 \x01(\x05\"6\n\x10LabelProbability\x12\r\n\x05label\x18\x01 \x01(\t\x12\x13\n\x0bprobability\x18\x02 \x01(\x01\":\n\x0fRunModelRequest\x12\x18\n\x04\x64\x61ta\x18\x01 \x03(\x0b\x32\n.FinalData\x12\r\n\x05model\x18\x02 \x01(\t\"1\n\x10RunModelResponse\x12\x1d\n\x07results\x18\x01 \x03(\x0b\x32\x0c.PixelResult2B\n\x0fRunModelService\x12/\n\x08RunModel\x12\x10.RunModelRequest\x1a\x11.RunModelResponseB\x0cZ\n/protobufsb\x06proto3')
 
 _globals = globals()
 _builder.BuildMessageAndEnumDescriptors(DESCRIPTOR, _globals)
@@ -22,20 +22,32 @@ _builder.BuildTopDescriptorsAndMessages(DESCRIPTOR, 'run_model_pb2', _globals)
 if _descriptor._USE_C_DESCRIPTORS == False:
   _globals['DESCRIPTOR']._options = None
   _globals['DESCRIPTOR']._serialized_options = b'Z\n/protobufs'
+  _globals['_FINALDATA_DELTADATA_INDEXESENTRY']._options = None
+  _globals['_FINALDATA_DELTADATA_INDEXESENTRY']._serialized_options = b'8\001'
+  _globals['_FINALDATA_DELTADATA_INDEXDERIVATIVESENTRY']._options = None
+  _globals['_FINALDATA_DELTADATA_INDEXDERIVATIVESENTRY']._serialized_options = b'8\001'
+  _globals['_FINALDATA_DELTADATA_FEATURESENTRY']._options = None
+  _globals['_FINALDATA_DELTADATA_FEATURESENTRY']._serialized_options = b'8\001'
   _globals['_FINALDATA']._serialized_start=20
-  _globals['_FINALDATA']._serialized_end=670
+  _globals['_FINALDATA']._serialized_end=1057
   _globals['_FINALDATA_WEATHERMETRICS']._serialized_start=135
   _globals['_FINALDATA_WEATHERMETRICS']._serialized_end=305
   _globals['_FINALDATA_DELTADATA']._serialized_start=308
-  _globals['_FINALDATA_DELTADATA']._serialized_end=670
-  _globals['_PIXELRESULT']._serialized_start=672
-  _globals['_PIXELRESULT']._serialized_end=779
-  _globals['_LABELPROBABILITY']._serialized_start=781
-  _globals['_LABELPROBABILITY']._serialized_end=835
-  _globals['_RUNMODELREQUEST']._serialized_start=837
-  _globals['_RUNMODELREQUEST']._serialized_end=895
-  _globals['_RUNMODELRESPONSE']._serialized_start=897
-  _globals['_RUNMODELRESPONSE']._serialized_end=946
-  _globals['_RUNMODELSERVICE']._serialized_start=948
-  _globals['_RUNMODELSERVICE']._serialized_end=1014
+  _globals['_FINALDATA_DELTADATA']._serialized_end=1057
+  _globals['_FINALDATA_DELTADATA_INDEXESENTRY']._serialized_start=905
+  _globals['_FINALDATA_DELTADATA_INDEXESENTRY']._serialized_end=951
+  _globals['_FINALDATA_DELTADATA_INDEXDERIVATIVESENTRY']._serialized_start=953
+  _globals['_FINALDATA_DELTADATA_INDEXDERIVATIVESENTRY']._serialized_end=1008
+  _globals['_FINALDATA_DELTADATA_FEATURESENTRY']._serialized_start=1010
+  _globals['_FINALDATA_DELTADATA_FEATURESENTRY']._serialized_end=1057
+  _globals['_PIXELRESULT']._serialized_start=1060
+  _globals['_PIXELRESULT']._serialized_end=1223
+  _globals['_LABELPROBABILITY']._serialized_start=1225
+  _globals['_LABELPROBABILITY']._serialized_end=1279
+  _globals['_RUNMODELREQUEST']._serialized_start=1281
+  _globals['_RUNMODELREQUEST']._serialized_end=1339
+  _globals['_RUNMODELRESPONSE']._serialized_start=1341
+  _globals['_RUNMODELRESPONSE']._serialized_end=1390
+  _globals['_RUNMODELSERVICE']._serialized_start=1392
+  _globals['_RUNMODELSERVICE']._serialized_end=1458
 # @@protoc_insertion_point(module_scope)